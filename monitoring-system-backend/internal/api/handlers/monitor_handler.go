@@ -2,35 +2,46 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
-	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
+	"github.com/badgerv/monitoring-api/internal/auth"
 	"github.com/badgerv/monitoring-api/internal/monitor"
 )
 
 type API struct {
-	Monitor   *monitor.Service
-	scheduler *monitor.Scheduler
-	mu        sync.Mutex // Protect scheduler access
+	Monitor *monitor.Service
 }
 
 type Endpoint struct {
-	ID                  int      `json:"id,omitempty"`
-	ServiceName         string   `json:"service_name"`
-	URL                 string   `json:"url"`
-	ServerName          string   `json:"server_name"`
-	APIMethod           string   `json:"api_method"`
-	ExpectedCode        int      `json:"expected_status_code"`
-	GitlabURL           *string  `json:"gitlab_url,omitempty"`
-	DockerContainerName *string  `json:"docker_container_name,omitempty"`
-	KubernetesPodName   *string  `json:"kubernetes_pod_name,omitempty"`
-	Tags                []string `json:"tags,omitempty"`
-	Description         *string  `json:"description,omitempty"`
-	LastChangedBy       *string  `json:"last_changed_by,omitempty"`
+	ID                   int                 `json:"id,omitempty"`
+	ServiceName          string              `json:"service_name"`
+	URL                  string              `json:"url"`
+	ServerName           string              `json:"server_name"`
+	CheckType            string              `json:"check_type,omitempty"`
+	CheckConfig          monitor.CheckConfig `json:"check_config"`
+	APIMethod            string              `json:"api_method"`
+	ExpectedCode         int                 `json:"expected_status_code"`
+	GitlabURL            *string             `json:"gitlab_url,omitempty"`
+	DockerContainerName  *string             `json:"docker_container_name,omitempty"`
+	KubernetesPodName    *string             `json:"kubernetes_pod_name,omitempty"`
+	Tags                 []string            `json:"tags,omitempty"`
+	Description          *string             `json:"description,omitempty"`
+	LastChangedBy        *string             `json:"last_changed_by,omitempty"`
+	Headers              map[string]string   `json:"headers,omitempty"`
+	QueryParams          map[string]string   `json:"query_params,omitempty"`
+	BodyTemplate         *string             `json:"body_template,omitempty"`
+	Assertions           []monitor.Assertion `json:"assertions,omitempty"`
+	CheckIntervalSeconds *int                `json:"check_interval_seconds,omitempty"`
+	TimeoutSeconds       *int                `json:"timeout_seconds,omitempty"`
 }
 
 func NewMonitorHandle(m *monitor.Service) *API {
@@ -43,43 +54,50 @@ func (a *API) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "OK"})
 }
 
+// actorFromContext identifies the user performing an action from the auth context
+func actorFromContext(c *gin.Context) monitor.Actor {
+	authCtx, ok := auth.GetAuthContext(c)
+	if !ok || authCtx.User == nil {
+		return monitor.Actor{Username: "unknown"}
+	}
+	id := authCtx.User.ID
+	return monitor.Actor{UserID: &id, Username: authCtx.User.Username}
+}
+
 func (a *API) StartEndPointChecks(c *gin.Context) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	err := a.Monitor.StartChecks(c.Request.Context(), actorFromContext(c))
 
 	// Check if scheduler is already running
-	if a.scheduler != nil {
+	if errors.Is(err, monitor.ErrSchedulerRunning) {
 		log.Println("Endpoints check already running")
 		c.JSON(http.StatusConflict, gin.H{"message": "Endpoints check already running"})
 		return
 	}
 
-	ctx := context.Background()
-
-	scheduler, err := a.Monitor.StartScheduler(ctx)
 	if err != nil {
 		log.Println("Endpoints check failed to start:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Endpoints check failed"})
 		return
 	}
 
-	a.scheduler = scheduler
 	log.Println("Endpoints check started successfully")
 	c.JSON(http.StatusOK, gin.H{"message": "Endpoints check started successfully"})
 }
 
 func (a *API) StopEndPointChecks(c *gin.Context) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	err := a.Monitor.StopChecks(c.Request.Context(), actorFromContext(c))
 
-	if a.scheduler == nil {
+	if errors.Is(err, monitor.ErrSchedulerNotRunning) {
 		log.Println("No endpoints check running")
 		c.JSON(http.StatusConflict, gin.H{"message": "No endpoints check running"})
 		return
 	}
 
-	a.scheduler.Stop()
-	a.scheduler = nil
+	if err != nil {
+		log.Println("Endpoints check failed to stop:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Endpoints check failed to stop"})
+		return
+	}
 
 	log.Println("Endpoints check stopped successfully")
 	c.JSON(http.StatusOK, gin.H{"message": "Endpoints stopped successfully"})
@@ -87,12 +105,97 @@ func (a *API) StopEndPointChecks(c *gin.Context) {
 
 // GetSchedulerStatus returns whether the scheduler is running
 func (a *API) GetSchedulerStatus(c *gin.Context) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	scheduler := a.Monitor.CurrentScheduler()
+
+	isRunning := scheduler != nil
+	isLeader := false
+	endpointCount := 0
+	workers := 0
+	schedule := []monitor.EndpointSchedule{}
+	if isRunning {
+		isLeader = scheduler.IsLeader()
+		endpointCount = scheduler.EndpointCount()
+		workers = scheduler.Workers()
+		schedule = scheduler.Schedule()
+	}
+
+	state, err := a.Monitor.GetSchedulerState(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scheduler_running":   isRunning,
+		"replica_id":          monitor.ReplicaID(),
+		"is_leader":           isLeader,
+		"desired_state":       state,
+		"scheduled_endpoints": endpointCount,
+		"workers":             workers,
+		"endpoints":           schedule,
+	})
+}
+
+// GetSchedulerHistory returns who started and stopped the scheduler, newest first
+func (a *API) GetSchedulerHistory(c *gin.Context) {
+	limit := 50
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 && v <= 500 {
+		limit = v
+	}
+
+	actions, err := a.Monitor.ListSchedulerActions(c.Request.Context(), limit)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    actions,
+	})
+}
+
+// GetRetentionRuns returns recent check retention runs and what each one pruned
+func (a *API) GetRetentionRuns(c *gin.Context) {
+	limit := 50
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 && v <= 500 {
+		limit = v
+	}
+
+	runs, err := a.Monitor.ListRetentionRuns(c.Request.Context(), limit)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    runs,
+	})
+}
+
+// RunRetention rolls up and prunes check data now instead of waiting for the next run
+func (a *API) RunRetention(c *gin.Context) {
+	actor := actorFromContext(c)
+	run, err := a.Monitor.RunRetention(c.Request.Context(), "manual", &actor.Username)
+
+	if errors.Is(err, monitor.ErrRetentionRunning) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
 
-	isRunning := a.scheduler != nil
 	c.JSON(http.StatusOK, gin.H{
-		"scheduler_running": isRunning,
+		"message": "Success",
+		"data":    run,
 	})
 }
 
@@ -155,21 +258,38 @@ func (a *API) CreateEndpoint(c *gin.Context) {
 		return
 	}
 	monitorEp := &monitor.Endpoint{
-		ID:                  ep.ID,
-		ServiceName:         ep.ServiceName,
-		ServerName:          ep.ServerName,
-		URL:                 ep.URL,
-		APIMethod:           ep.APIMethod,
-		ExpectedCode:        ep.ExpectedCode,
-		GitlabURL:           ep.GitlabURL,
-		DockerContainerName: ep.DockerContainerName,
-		KubernetesPodName:   ep.KubernetesPodName,
-		Tags:                ep.Tags,
-		Description:         ep.Description,
-		LastChangedBy:       ep.LastChangedBy,
+		ID:                   ep.ID,
+		ServiceName:          ep.ServiceName,
+		ServerName:           ep.ServerName,
+		URL:                  ep.URL,
+		CheckType:            ep.CheckType,
+		CheckConfig:          ep.CheckConfig,
+		APIMethod:            ep.APIMethod,
+		ExpectedCode:         ep.ExpectedCode,
+		GitlabURL:            ep.GitlabURL,
+		DockerContainerName:  ep.DockerContainerName,
+		KubernetesPodName:    ep.KubernetesPodName,
+		Tags:                 ep.Tags,
+		Description:          ep.Description,
+		LastChangedBy:        ep.LastChangedBy,
+		Headers:              ep.Headers,
+		QueryParams:          ep.QueryParams,
+		BodyTemplate:         ep.BodyTemplate,
+		Assertions:           ep.Assertions,
+		CheckIntervalSeconds: ep.CheckIntervalSeconds,
+		TimeoutSeconds:       ep.TimeoutSeconds,
 	}
 
+	// The creator is always the authenticated user, not whatever the body claims
+	actor := actorFromContext(c)
+	monitorEp.LastChangedBy = &actor.Username
+
 	createdEp, err := a.Monitor.CreateEndpoint(c.Request.Context(), monitorEp)
+	var validationErr *monitor.EndpointValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
@@ -182,53 +302,1471 @@ func (a *API) CreateEndpoint(c *gin.Context) {
 	})
 }
 
-// CheckEndpointHandler handles requests to check an endpoint's status
-func (a *API) CheckEndpointHandler(c *gin.Context) {
-    // Parse endpoint ID from URL
-    idStr := c.Param("id")
-    endpointID, err := strconv.Atoi(idStr)
-    if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{
-            "message": "Invalid endpoint ID",
-        })
-        return
-    }
+// UpdateEndpointRequestConfig changes the method, headers, query params and body template used to check an endpoint
+func (a *API) UpdateEndpointRequestConfig(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
 
-    // Get endpoint details
-    endpoint, err := a.Monitor.GetEndpointByID(context.Background(), endpointID)
-    if err != nil {
-        c.JSON(http.StatusNotFound, gin.H{
-            "message": "Endpoint not found",
-            "error":   err.Error(),
-        })
-        return
-    }
+	var cfg monitor.RequestConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
 
-    // Call CheckEndpointStatus and get latency
-    latency, err := a.Monitor.CheckEndpointStatus(
-        context.Background(),
-        endpoint.URL,
-        endpoint.APIMethod,
-        endpoint.ExpectedCode,
-    )
+	if err := monitor.ValidateRequestConfig(cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
 
-    // Build response
-    status := "unreachable"
-    if err == nil {
-        status = "up"
-    }
+	updatedEp, err := a.Monitor.UpdateEndpointRequestConfig(c.Request.Context(), id, cfg)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
 
-    c.JSON(http.StatusOK, gin.H{
-        "endpoint_id": endpointID,
-        "service_name": endpoint.ServiceName,
-        "server_name": endpoint.ServerName,
-        "status": status,
-        "latency_ms": latency.Milliseconds(), // return latency in milliseconds
-        "error": func() string {
-            if err != nil {
-                return err.Error()
-            }
-            return ""
-        }(),
-    })
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updatedEp,
+	})
+}
+
+// UpdateEndpoint replaces an endpoint (PUT); every core field must be supplied
+func (a *API) UpdateEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var ep Endpoint
+	if err := c.ShouldBindJSON(&ep); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	monitorEp := &monitor.Endpoint{
+		ServiceName:          ep.ServiceName,
+		ServerName:           ep.ServerName,
+		URL:                  ep.URL,
+		CheckType:            ep.CheckType,
+		CheckConfig:          ep.CheckConfig,
+		APIMethod:            ep.APIMethod,
+		ExpectedCode:         ep.ExpectedCode,
+		GitlabURL:            ep.GitlabURL,
+		DockerContainerName:  ep.DockerContainerName,
+		KubernetesPodName:    ep.KubernetesPodName,
+		Tags:                 ep.Tags,
+		Description:          ep.Description,
+		Headers:              ep.Headers,
+		QueryParams:          ep.QueryParams,
+		BodyTemplate:         ep.BodyTemplate,
+		Assertions:           ep.Assertions,
+		CheckIntervalSeconds: ep.CheckIntervalSeconds,
+		TimeoutSeconds:       ep.TimeoutSeconds,
+	}
+
+	updatedEp, err := a.Monitor.UpdateEndpoint(c.Request.Context(), id, monitorEp, actorFromContext(c))
+	var validationErr *monitor.EndpointValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, monitor.ErrEndpointNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updatedEp,
+	})
+}
+
+// PatchEndpoint updates only the fields present in the request body (PATCH)
+func (a *API) PatchEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var patch monitor.EndpointPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	updatedEp, err := a.Monitor.PatchEndpoint(c.Request.Context(), id, patch, actorFromContext(c))
+	var validationErr *monitor.EndpointValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, monitor.ErrEndpointNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updatedEp,
+	})
+}
+
+// DeleteEndpoint removes an endpoint along with its checks and stats
+func (a *API) DeleteEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = a.Monitor.DeleteEndpoint(c.Request.Context(), id, actorFromContext(c))
+	if errors.Is(err, monitor.ErrEndpointNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// GetEndpointChanges returns the change log of an endpoint
+func (a *API) GetEndpointChanges(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	changes, err := a.Monitor.ListEndpointChanges(c.Request.Context(), id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    changes,
+	})
+}
+
+// parseTimeQuery reads an optional RFC3339 timestamp from the query string
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// GetCheckHistory returns raw check results of an endpoint, newest first.
+// Supports from, to, status (up/down), error, cursor and limit query params.
+func (a *API) GetCheckHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	q := monitor.CheckHistoryQuery{
+		Status:        c.Query("status"),
+		ErrorContains: c.Query("error"),
+		Cursor:        c.Query("cursor"),
+	}
+	if q.From, err = parseTimeQuery(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if q.To, err = parseTimeQuery(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if q.Limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "limit must be a number"})
+			return
+		}
+	}
+
+	if err := monitor.ValidateCheckHistoryQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	page, err := a.Monitor.GetCheckHistory(c.Request.Context(), id, q)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    page,
+	})
+}
+
+// GetCheckSeries returns an endpoint's checks downsampled into minute or hour buckets
+// for latency and availability charts
+func (a *API) GetCheckSeries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	bucket := c.DefaultQuery("bucket", "hour")
+	start, end, err := monitor.ResolveSeriesRange(bucket, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	series, err := a.Monitor.GetCheckSeries(c.Request.Context(), id, bucket, start, end)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data": gin.H{
+			"bucket":  bucket,
+			"from":    start,
+			"to":      end,
+			"buckets": series,
+		},
+	})
+}
+
+// SubscribeToEndpoint subscribes the current user to an endpoint's DOWN/RECOVERED alerts
+func (a *API) SubscribeToEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	actor := actorFromContext(c)
+	if actor.UserID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return
+	}
+
+	if err := a.Monitor.Subscribe(c.Request.Context(), id, *actor.UserID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// UnsubscribeFromEndpoint stops the current user's alerts for an endpoint
+func (a *API) UnsubscribeFromEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	actor := actorFromContext(c)
+	if actor.UserID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return
+	}
+
+	if err := a.Monitor.Unsubscribe(c.Request.Context(), id, *actor.UserID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// GetEndpointSubscriptions lists the users receiving an endpoint's alerts
+func (a *API) GetEndpointSubscriptions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	subs, err := a.Monitor.ListSubscriptions(c.Request.Context(), id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    subs,
+	})
+}
+
+// ListIncidents returns incidents newest first, filtered by status, endpoint_id and server_name
+func (a *API) ListIncidents(c *gin.Context) {
+	filter := monitor.IncidentFilter{
+		Status:     c.Query("status"),
+		ServerName: c.Query("server_name"),
+	}
+	if v := c.Query("endpoint_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid endpoint_id"})
+			return
+		}
+		filter.EndpointID = id
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	incidents, err := a.Monitor.ListIncidents(c.Request.Context(), filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    incidents,
+	})
+}
+
+// GetIncident returns an incident with the failed checks attached to it
+func (a *API) GetIncident(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("incidentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	incident, err := a.Monitor.GetIncident(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrIncidentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    incident,
+	})
+}
+
+// AcknowledgeIncident marks an incident as acknowledged by the current user
+func (a *API) AcknowledgeIncident(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("incidentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	incident, err := a.Monitor.AcknowledgeIncident(c.Request.Context(), id, actorFromContext(c))
+	if errors.Is(err, monitor.ErrIncidentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    incident,
+	})
+}
+
+// AssignIncident hands an incident to a user
+func (a *API) AssignIncident(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("incidentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		UserID uuid.UUID `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	incident, err := a.Monitor.AssignIncident(c.Request.Context(), id, req.UserID)
+	if errors.Is(err, monitor.ErrIncidentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    incident,
+	})
+}
+
+// GetIncidentMetrics returns MTTA/MTTR per endpoint and per server over from/to,
+// defaulting to the last 30 days
+func (a *API) GetIncidentMetrics(c *gin.Context) {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.AddDate(0, 0, -30)
+	if from != nil {
+		start = *from
+	}
+
+	report, err := a.Monitor.GetIncidentMetrics(c.Request.Context(), start, end)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    report,
+	})
+}
+
+// UpdateEndpointSchedule changes the check interval and timeout of an endpoint
+func (a *API) UpdateEndpointSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var cfg monitor.ScheduleConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	updatedEp, err := a.Monitor.UpdateEndpointSchedule(c.Request.Context(), id, cfg)
+	var validationErr *monitor.EndpointValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, monitor.ErrEndpointNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updatedEp,
+	})
+}
+
+// UpdateEndpointAssertions replaces the response assertions evaluated for an endpoint
+func (a *API) UpdateEndpointAssertions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		Assertions []monitor.Assertion `json:"assertions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	if err := monitor.ValidateAssertions(req.Assertions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	updatedEp, err := a.Monitor.UpdateEndpointAssertions(c.Request.Context(), id, req.Assertions)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updatedEp,
+	})
+}
+
+// CheckEndpointHandler handles requests to check an endpoint's status
+func (a *API) CheckEndpointHandler(c *gin.Context) {
+    // Parse endpoint ID from URL
+    idStr := c.Param("id")
+    endpointID, err := strconv.Atoi(idStr)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "message": "Invalid endpoint ID",
+        })
+        return
+    }
+
+    // Get endpoint details
+    endpoint, err := a.Monitor.GetEndpoint(context.Background(), endpointID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{
+            "message": "Endpoint not found",
+            "error":   err.Error(),
+        })
+        return
+    }
+
+    // Call CheckEndpointStatus and get latency
+    latency, err := a.Monitor.CheckEndpointStatus(context.Background(), endpoint)
+
+    // Build response
+    status := "unreachable"
+    var failedAssertion *monitor.AssertionError
+    if err == nil {
+        status = "up"
+    } else if errors.As(err, &failedAssertion) {
+        status = "assertion_failed"
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "failed_assertion": failedAssertion,
+        "endpoint_id": endpointID,
+        "service_name": endpoint.ServiceName,
+        "server_name": endpoint.ServerName,
+        "status": status,
+        "latency_ms": latency.Milliseconds(), // return latency in milliseconds
+        "error": func() string {
+            if err != nil {
+                return err.Error()
+            }
+            return ""
+        }(),
+    })
+}
+
+// ListMaintenanceWindows returns every maintenance window, flagging the active ones
+func (a *API) ListMaintenanceWindows(c *gin.Context) {
+	windows, err := a.Monitor.ListMaintenanceWindows(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    windows,
+	})
+}
+
+// CreateMaintenanceWindow schedules a one-off or recurring maintenance window
+func (a *API) CreateMaintenanceWindow(c *gin.Context) {
+	var window monitor.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	if err := monitor.ValidateMaintenanceWindow(&window); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	created, err := a.Monitor.CreateMaintenanceWindow(c.Request.Context(), window, actorFromContext(c))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    created,
+	})
+}
+
+func (a *API) UpdateMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("windowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var window monitor.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+	window.ID = id
+
+	if err := monitor.ValidateMaintenanceWindow(&window); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	updated, err := a.Monitor.UpdateMaintenanceWindow(c.Request.Context(), window)
+	if errors.Is(err, monitor.ErrMaintenanceWindowNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updated,
+	})
+}
+
+func (a *API) DeleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("windowId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = a.Monitor.DeleteMaintenanceWindow(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrMaintenanceWindowNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// ListSilences returns active silences; ?include_expired=true returns all of them
+func (a *API) ListSilences(c *gin.Context) {
+	includeExpired, _ := strconv.ParseBool(c.Query("include_expired"))
+
+	silences, err := a.Monitor.ListSilences(c.Request.Context(), includeExpired)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    silences,
+	})
+}
+
+// CreateSilence mutes alerts for an endpoint, tag or server until expires_at
+func (a *API) CreateSilence(c *gin.Context) {
+	var silence monitor.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	if err := monitor.ValidateSilence(&silence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	created, err := a.Monitor.CreateSilence(c.Request.Context(), silence, actorFromContext(c))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    created,
+	})
+}
+
+// ExpireSilence ends a silence early
+func (a *API) ExpireSilence(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("silenceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	silence, err := a.Monitor.ExpireSilence(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrSilenceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    silence,
+	})
+}
+
+// GetEndpointCertificate returns the last TLS certificate inspection of an endpoint
+func (a *API) GetEndpointCertificate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	cert, err := a.Monitor.GetCertificate(c.Request.Context(), id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	if cert == nil {
+		c.JSON(http.StatusNotFound, gin.H{"message": "no certificate recorded for this endpoint"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    cert,
+	})
+}
+
+// GetEndpointHeartbeat returns the ping path, state and recent pings of a heartbeat endpoint
+func (a *API) GetEndpointHeartbeat(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	heartbeat, err := a.Monitor.GetHeartbeat(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrHeartbeatNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "endpoint is not a heartbeat"})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    heartbeat,
+	})
+}
+
+// PingHeartbeat records a success ping from a job
+func (a *API) PingHeartbeat(c *gin.Context) {
+	a.pingHeartbeat(c, monitor.PingSuccess)
+}
+
+// PingHeartbeatStart records that a job started, so its duration can be measured
+func (a *API) PingHeartbeatStart(c *gin.Context) {
+	a.pingHeartbeat(c, monitor.PingStart)
+}
+
+// PingHeartbeatFail records that a job failed, taking its heartbeat down
+func (a *API) PingHeartbeatFail(c *gin.Context) {
+	a.pingHeartbeat(c, monitor.PingFail)
+}
+
+// pingHeartbeat is unauthenticated: the token in the path identifies the heartbeat.
+// A POST body is kept as the ping's message, e.g. the error a failed job exited with.
+func (a *API) pingHeartbeat(c *gin.Context, kind string) {
+	var message string
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64<<10))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+			return
+		}
+		message = string(body)
+	}
+
+	err := a.Monitor.PingHeartbeat(c.Request.Context(), c.Param("token"), kind, message)
+	if errors.Is(err, monitor.ErrHeartbeatNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// ListSLOs returns every SLO with its attainment, error budget and burn rates
+func (a *API) ListSLOs(c *gin.Context) {
+	statuses, err := a.Monitor.ListSLOStatuses(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    statuses,
+	})
+}
+
+func (a *API) GetSLO(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sloId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	status, err := a.Monitor.GetSLOStatus(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrSLONotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    status,
+	})
+}
+
+// CreateSLO defines an availability or latency objective for an endpoint or tag
+func (a *API) CreateSLO(c *gin.Context) {
+	var slo monitor.SLO
+	if err := c.ShouldBindJSON(&slo); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	if err := monitor.ValidateSLO(&slo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	created, err := a.Monitor.CreateSLO(c.Request.Context(), slo, actorFromContext(c))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    created,
+	})
+}
+
+func (a *API) UpdateSLO(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sloId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var slo monitor.SLO
+	if err := c.ShouldBindJSON(&slo); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+	slo.ID = id
+
+	if err := monitor.ValidateSLO(&slo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	updated, err := a.Monitor.UpdateSLO(c.Request.Context(), slo)
+	if errors.Is(err, monitor.ErrSLONotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updated,
+	})
+}
+
+func (a *API) DeleteSLO(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sloId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = a.Monitor.DeleteSLO(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrSLONotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// ListStatusComponents returns the components published on the status page
+func (a *API) ListStatusComponents(c *gin.Context) {
+	components, err := a.Monitor.ListStatusComponents(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    components,
+	})
+}
+
+// CreateStatusComponent publishes an endpoint, tag or server on the status page
+func (a *API) CreateStatusComponent(c *gin.Context) {
+	var component monitor.StatusComponent
+	if err := c.ShouldBindJSON(&component); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	if err := monitor.ValidateStatusComponent(&component); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	created, err := a.Monitor.CreateStatusComponent(c.Request.Context(), component, actorFromContext(c))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    created,
+	})
+}
+
+func (a *API) UpdateStatusComponent(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("componentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var component monitor.StatusComponent
+	if err := c.ShouldBindJSON(&component); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+	component.ID = id
+
+	if err := monitor.ValidateStatusComponent(&component); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	updated, err := a.Monitor.UpdateStatusComponent(c.Request.Context(), component)
+	if errors.Is(err, monitor.ErrStatusComponentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updated,
+	})
+}
+
+func (a *API) DeleteStatusComponent(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("componentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = a.Monitor.DeleteStatusComponent(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrStatusComponentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// GetPublicStatus serves the unauthenticated status page
+func (a *API) GetPublicStatus(c *gin.Context) {
+	status, err := a.Monitor.GetPublicStatus(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "status is unavailable"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    status,
+	})
+}
+
+// GetStatusBadge serves an SVG badge with a component's current status, for READMEs
+func (a *API) GetStatusBadge(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("componentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	name, status, err := a.Monitor.GetComponentStatus(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrStatusComponentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "status is unavailable"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=60")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", monitor.StatusBadgeSVG(name, status))
+}
+
+// maxImportSize caps the body of an endpoint import.
+const maxImportSize = 10 << 20
+
+// formatContentTypes are the content types endpoint imports and exports are sent with
+var formatContentTypes = map[string]string{
+	monitor.FormatJSON: "application/json",
+	monitor.FormatYAML: "application/yaml",
+	monitor.FormatCSV:  "text/csv",
+}
+
+// importFormat reads the format of an import from ?format=, falling back to the
+// Content-Type of the body
+func importFormat(c *gin.Context) (string, error) {
+	if format := c.Query("format"); format != "" {
+		return monitor.ParseFormat(format)
+	}
+	switch c.ContentType() {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return monitor.FormatYAML, nil
+	case "text/csv":
+		return monitor.FormatCSV, nil
+	default:
+		return monitor.FormatJSON, nil
+	}
+}
+
+// ExportEndpoints downloads every endpoint as JSON, YAML or CSV (?format=), in the
+// format ImportEndpoints reads. Header values are masked unless ?include_secrets=true.
+func (a *API) ExportEndpoints(c *gin.Context) {
+	format, err := monitor.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	includeSecrets, _ := strconv.ParseBool(c.Query("include_secrets"))
+
+	data, err := a.Monitor.ExportEndpoints(c.Request.Context(), format, includeSecrets)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="endpoints.%s"`, format))
+	c.Data(http.StatusOK, formatContentTypes[format]+"; charset=utf-8", data)
+}
+
+// ImportEndpoints creates and updates endpoints from a JSON, YAML or CSV body.
+// ?mode=sync also deletes endpoints missing from it; ?dry_run=true only returns the diff.
+// The import is applied all or nothing; a failed change returns 409 with the diff.
+func (a *API) ImportEndpoints(c *gin.Context) {
+	format, err := importFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxImportSize+1))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+	if len(body) > maxImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "import file is too large"})
+		return
+	}
+
+	opts := monitor.ImportOptions{Mode: c.Query("mode"), DryRun: dryRun}
+	result, err := a.Monitor.ImportEndpoints(c.Request.Context(), format, body, opts, actorFromContext(c))
+	var importErr *monitor.ImportError
+	if errors.As(err, &importErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid import", "errors": importErr.Problems})
+		return
+	}
+	if errors.Is(err, monitor.ErrImportNotApplied) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error(), "data": result})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    result,
+	})
+}
+
+// ListServers returns every server with the share of its endpoints up, its worst
+// latency and open incidents, filtered by ?environment= and ?tag=
+func (a *API) ListServers(c *gin.Context) {
+	filter := monitor.ServerFilter{
+		Environment: c.Query("environment"),
+		Tag:         c.Query("tag"),
+	}
+
+	servers, err := a.Monitor.ListServers(c.Request.Context(), filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    servers,
+	})
+}
+
+// GetServer returns a server's aggregated health and its endpoints
+func (a *API) GetServer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("serverId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	server, err := a.Monitor.GetServer(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrServerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    server,
+	})
+}
+
+func (a *API) CreateServer(c *gin.Context) {
+	var server monitor.Server
+	if err := c.ShouldBindJSON(&server); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	if err := monitor.ValidateServer(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	created, err := a.Monitor.CreateServer(c.Request.Context(), server)
+	if errors.Is(err, monitor.ErrServerExists) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    created,
+	})
+}
+
+// UpdateServer replaces a server's details. Renaming it renames it on its endpoints.
+func (a *API) UpdateServer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("serverId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var server monitor.Server
+	if err := c.ShouldBindJSON(&server); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+	server.ID = id
+
+	if err := monitor.ValidateServer(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	updated, err := a.Monitor.UpdateServer(c.Request.Context(), server)
+	if errors.Is(err, monitor.ErrServerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, monitor.ErrServerExists) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    updated,
+	})
+}
+
+// DeleteServer removes a server once none of its endpoints are left
+func (a *API) DeleteServer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("serverId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = a.Monitor.DeleteServer(c.Request.Context(), id)
+	if errors.Is(err, monitor.ErrServerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, monitor.ErrServerInUse) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// GetDependencyGraph returns every endpoint and the dependencies between them
+func (a *API) GetDependencyGraph(c *gin.Context) {
+	graph, err := a.Monitor.GetDependencyGraph(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    graph,
+	})
+}
+
+// AddEndpointDependency makes the endpoint depend on {"depends_on_id": ...}
+func (a *API) AddEndpointDependency(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var req struct {
+		DependsOnID int `json:"depends_on_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	dep, err := a.Monitor.AddDependency(c.Request.Context(), id, req.DependsOnID, actorFromContext(c))
+	if errors.Is(err, monitor.ErrEndpointNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, monitor.ErrDependencyCycle) {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    dep,
+	})
+}
+
+func (a *API) RemoveEndpointDependency(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+	dependsOnID, err := strconv.Atoi(c.Param("dependsOnId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = a.Monitor.RemoveDependency(c.Request.Context(), id, dependsOnID)
+	if errors.Is(err, monitor.ErrDependencyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// SeedDependencies adds the dependencies pipeline units imply between endpoints
+func (a *API) SeedDependencies(c *gin.Context) {
+	result, err := a.Monitor.SeedDependenciesFromPipelines(c.Request.Context(), actorFromContext(c))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    result,
+	})
 }
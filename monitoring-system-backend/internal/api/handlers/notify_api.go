@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/badgerv/monitoring-api/internal/notify"
+)
+
+type NotifyAPI struct {
+	Notify *notify.Service
+}
+
+type ChannelRequest struct {
+	Name    string          `json:"name" binding:"required"`
+	Type    string          `json:"type" binding:"required"`
+	Config  json.RawMessage `json:"config" binding:"required"`
+	Enabled *bool           `json:"enabled"`
+}
+
+type RouteRequest struct {
+	ScopeType  string   `json:"scope_type" binding:"required"`
+	ScopeValue string   `json:"scope_value"`
+	Events     []string `json:"events"`
+}
+
+func NewNotifyHandler(n *notify.Service) *NotifyAPI {
+	return &NotifyAPI{Notify: n}
+}
+
+func (req ChannelRequest) toConfig() notify.ChannelConfig {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return notify.ChannelConfig{
+		Name:    req.Name,
+		Type:    req.Type,
+		Config:  req.Config,
+		Enabled: enabled,
+	}
+}
+
+// ListChannels returns every notification channel, with secrets masked
+func (h *NotifyAPI) ListChannels(c *gin.Context) {
+	channels, err := h.Notify.ListChannels(c.Request.Context())
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    channels,
+	})
+}
+
+func (h *NotifyAPI) GetChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	channel, err := h.Notify.GetChannel(c.Request.Context(), id)
+	if errors.Is(err, notify.ErrChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    channel,
+	})
+}
+
+// CreateChannel stores a new email, Slack, Teams or webhook channel
+func (h *NotifyAPI) CreateChannel(c *gin.Context) {
+	var req ChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	cfg := req.toConfig()
+	if err := h.Notify.ValidateChannel(cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if actor := actorFromContext(c); actor.UserID != nil {
+		cfg.CreatedBy = actor.Username
+	}
+
+	channel, err := h.Notify.CreateChannel(c.Request.Context(), cfg)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    channel,
+	})
+}
+
+// UpdateChannel replaces a channel's settings; a masked secret keeps the stored one
+func (h *NotifyAPI) UpdateChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var req ChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	cfg := req.toConfig()
+	cfg.ID = id
+	if err := h.Notify.ValidateChannel(cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	channel, err := h.Notify.UpdateChannel(c.Request.Context(), cfg)
+	if errors.Is(err, notify.ErrChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    channel,
+	})
+}
+
+func (h *NotifyAPI) DeleteChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = h.Notify.DeleteChannel(c.Request.Context(), id)
+	if errors.Is(err, notify.ErrChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+// TestChannel sends a test message so the config can be checked end to end
+func (h *NotifyAPI) TestChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	err = h.Notify.TestChannel(c.Request.Context(), id)
+	if errors.Is(err, notify.ErrChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
+
+func (h *NotifyAPI) ListRoutes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	routes, err := h.Notify.ListRoutes(c.Request.Context(), id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Success",
+		"data":    routes,
+	})
+}
+
+// CreateRoute sends a channel the events of an endpoint, a tag or the pipelines
+func (h *NotifyAPI) CreateRoute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	var req RouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+
+	route := notify.Route{
+		ChannelID:  id,
+		ScopeType:  req.ScopeType,
+		ScopeValue: req.ScopeValue,
+		Events:     req.Events,
+	}
+	if err := notify.ValidateRoute(route); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	created, err := h.Notify.CreateRoute(c.Request.Context(), route)
+	if errors.Is(err, notify.ErrChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Success",
+		"data":    created,
+	})
+}
+
+func (h *NotifyAPI) DeleteRoute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("routeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+
+	if err := h.Notify.DeleteRoute(c.Request.Context(), id); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Success"})
+}
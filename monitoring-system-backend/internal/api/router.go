@@ -1,9 +1,13 @@
 package api
 
 import (
+	"log"
+	"os"
+
 	"github.com/badgerv/monitoring-api/internal/api/handlers"
 	"github.com/badgerv/monitoring-api/internal/auth"
 	"github.com/badgerv/monitoring-api/internal/gitlab"
+	"github.com/badgerv/monitoring-api/internal/metrics"
 	"github.com/badgerv/monitoring-api/internal/rbac"
 	"github.com/badgerv/monitoring-api/internal/websocket"
 	"github.com/gin-contrib/cors"
@@ -14,6 +18,7 @@ import (
 func ApiRouter(
 	mh *handlers.API,
 	ah *handlers.AuthAPI,
+	nh *handlers.NotifyAPI,
 	authMiddleware gin.HandlerFunc,
 	rbacService *rbac.Service,
 	gitlabService *gitlab.PipelineService,
@@ -27,13 +32,22 @@ func ApiRouter(
 	// Add CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://192.168.20.20:3000", "http://192.9.201.92:3000"}, // React frontend
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * 60 * 60,
 	}))
 
+	// Count and time every request by route, and expose them with the check metrics
+	r.Use(metrics.GinMiddleware())
+	// Without a token the metrics would be public, so they are not served at all
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		r.GET("/metrics", metrics.GinHandler(token))
+	} else {
+		log.Println("warning: METRICS_TOKEN is not set, /metrics is disabled")
+	}
+
 	// ================== Monitoring Endpoints ==================
 	monitor := r.Group("/api/v1/monitor")
 	{
@@ -41,23 +55,106 @@ func ApiRouter(
 		// Admin only (multiple roles allowed)
 		monitor.Use(authMiddleware, rbacService.RequireRole("admin", "super admin", "devops", "senior-developer", "developer", "qa-engineer"))
 		{
-			// Public (no auth needed)
+			// Read access, plus subscribing and acknowledging, for every signed-in role
 			monitor.GET("/get-overall-stats", mh.GetAggregateStats)
 			monitor.GET("/check-scheduler-status", mh.GetSchedulerStatus)
+			monitor.GET("/scheduler-history", mh.GetSchedulerHistory)
+			monitor.GET("/retention-runs", mh.GetRetentionRuns)
 			monitor.GET("/get-endpoint-by-id/:id", mh.GetEndpointDetailByID)
 			monitor.GET("/get-endpoint-essentials", mh.GetAllEndpointEssentials)
 			monitor.GET("/:id/check", mh.CheckEndpointHandler)
+			monitor.GET("/:id/changes", mh.GetEndpointChanges)
+			monitor.GET("/:id/checks", mh.GetCheckHistory)
+			monitor.GET("/:id/checks/series", mh.GetCheckSeries)
+			monitor.GET("/:id/certificate", mh.GetEndpointCertificate)
+			monitor.GET("/:id/heartbeat", mh.GetEndpointHeartbeat)
+			monitor.GET("/:id/subscriptions", mh.GetEndpointSubscriptions)
+			monitor.POST("/:id/subscription", mh.SubscribeToEndpoint)
+			monitor.DELETE("/:id/subscription", mh.UnsubscribeFromEndpoint)
+			monitor.GET("/incidents", mh.ListIncidents)
+			monitor.GET("/incidents/metrics", mh.GetIncidentMetrics)
+			monitor.GET("/incidents/:incidentId", mh.GetIncident)
+			monitor.POST("/incidents/:incidentId/acknowledge", mh.AcknowledgeIncident)
+			monitor.GET("/maintenance-windows", mh.ListMaintenanceWindows)
+			monitor.GET("/silences", mh.ListSilences)
+			monitor.GET("/slos", mh.ListSLOs)
+			monitor.GET("/slos/:sloId", mh.GetSLO)
+			monitor.GET("/status-page/components", mh.ListStatusComponents)
+			monitor.GET("/servers", mh.ListServers)
+			monitor.GET("/servers/:serverId", mh.GetServer)
+			monitor.GET("/dependency-graph", mh.GetDependencyGraph)
 		}
 
 		monitor.Use(authMiddleware, rbacService.RequireRole("admin", "super admin", "devops"))
 		{
 			monitor.POST("/start-checks", mh.StartEndPointChecks)
 			monitor.POST("/stop-checks", mh.StopEndPointChecks)
+			monitor.POST("/retention-runs", mh.RunRetention)
+			monitor.POST("/incidents/:incidentId/assign", mh.AssignIncident)
+			monitor.POST("/maintenance-windows", mh.CreateMaintenanceWindow)
+			monitor.PUT("/maintenance-windows/:windowId", mh.UpdateMaintenanceWindow)
+			monitor.DELETE("/maintenance-windows/:windowId", mh.DeleteMaintenanceWindow)
+			monitor.POST("/silences", mh.CreateSilence)
+			monitor.POST("/silences/:silenceId/expire", mh.ExpireSilence)
+			monitor.POST("/slos", mh.CreateSLO)
+			monitor.PUT("/slos/:sloId", mh.UpdateSLO)
+			monitor.DELETE("/slos/:sloId", mh.DeleteSLO)
+			monitor.POST("/status-page/components", mh.CreateStatusComponent)
+			monitor.PUT("/status-page/components/:componentId", mh.UpdateStatusComponent)
+			monitor.DELETE("/status-page/components/:componentId", mh.DeleteStatusComponent)
+			monitor.POST("/servers", mh.CreateServer)
+			monitor.PUT("/servers/:serverId", mh.UpdateServer)
+			monitor.DELETE("/servers/:serverId", mh.DeleteServer)
+			monitor.POST("/dependency-graph/seed", mh.SeedDependencies)
 			monitor.POST("/create-endpoint", mh.CreateEndpoint)
+			monitor.PUT("/update-endpoint/:id", mh.UpdateEndpoint)
+			monitor.PATCH("/update-endpoint/:id", mh.PatchEndpoint)
+			monitor.DELETE("/delete-endpoint/:id", mh.DeleteEndpoint)
+			monitor.GET("/export-endpoints", mh.ExportEndpoints)
+			monitor.POST("/import-endpoints", mh.ImportEndpoints)
+			monitor.PUT("/:id/request-config", mh.UpdateEndpointRequestConfig)
+			monitor.PUT("/:id/assertions", mh.UpdateEndpointAssertions)
+			monitor.PUT("/:id/schedule", mh.UpdateEndpointSchedule)
+			monitor.POST("/:id/dependencies", mh.AddEndpointDependency)
+			monitor.DELETE("/:id/dependencies/:dependsOnId", mh.RemoveEndpointDependency)
 		}
 
 	}
 
+	// ================== Heartbeat Pings ==================
+	// Public: jobs authenticate with the token in their ping URL
+	heartbeats := r.Group("/api/v1/heartbeats")
+	{
+		heartbeats.GET("/:token", mh.PingHeartbeat)
+		heartbeats.POST("/:token", mh.PingHeartbeat)
+		heartbeats.GET("/:token/start", mh.PingHeartbeatStart)
+		heartbeats.POST("/:token/start", mh.PingHeartbeatStart)
+		heartbeats.GET("/:token/fail", mh.PingHeartbeatFail)
+		heartbeats.POST("/:token/fail", mh.PingHeartbeatFail)
+	}
+
+	// ================== Status Page ==================
+	// Public: read-only, shows only what admins published as status page components
+	status := r.Group("/api/v1/status")
+	{
+		status.GET("", mh.GetPublicStatus)
+		status.GET("/components/:componentId/badge.svg", mh.GetStatusBadge)
+	}
+
+	// ================== Notification Endpoints ==================
+	notifications := r.Group("/api/v1/notifications", authMiddleware, rbacService.RequireRole("admin", "super admin", "devops"))
+	{
+		notifications.GET("/channels", nh.ListChannels)
+		notifications.POST("/channels", nh.CreateChannel)
+		notifications.GET("/channels/:id", nh.GetChannel)
+		notifications.PUT("/channels/:id", nh.UpdateChannel)
+		notifications.DELETE("/channels/:id", nh.DeleteChannel)
+		notifications.POST("/channels/:id/test", nh.TestChannel)
+		notifications.GET("/channels/:id/routes", nh.ListRoutes)
+		notifications.POST("/channels/:id/routes", nh.CreateRoute)
+		notifications.DELETE("/routes/:routeId", nh.DeleteRoute)
+	}
+
 	// ================== Auth Endpoints ==================
 	auth := r.Group("/api/v1/auth")
 	{
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"log"
 
 	"github.com/badgerv/monitoring-api/internal/api"
@@ -9,7 +10,9 @@ import (
 	"github.com/badgerv/monitoring-api/internal/config"
 	"github.com/badgerv/monitoring-api/internal/emailservice"
 	"github.com/badgerv/monitoring-api/internal/gitlab"
+	"github.com/badgerv/monitoring-api/internal/metrics"
 	"github.com/badgerv/monitoring-api/internal/monitor"
+	"github.com/badgerv/monitoring-api/internal/notify"
 	"github.com/badgerv/monitoring-api/internal/rbac"
 	"github.com/badgerv/monitoring-api/internal/websocket"
 
@@ -32,17 +35,36 @@ func BootStrap(wbHub *websocket.Hub) *Application {
 	// Initialize DB
 	db := storage.NewDB()
 
+	// --- Notifications setup ---
+	notifyRepo := notify.NewPostgresRepository(db)
+	notifier := notify.NewService(notifyRepo, emailservice.NewEmailService())
+	notifyApiHandler := handlers.NewNotifyHandler(notifier)
+
 	// --- Monitor setup ---
 	monitorRepo := monitor.NewPostgresRepository(db)
-	monitorService := monitor.NewService(db, monitorRepo)
+	userRepo := auth.NewPostgresUserRepository(db)
+	monitorService := monitor.NewService(db, monitorRepo, notifier, userRepo)
 	monitorApiHandler := handlers.NewMonitorHandle(monitorService)
 
+	// Resume endpoint checks if they were running before this deploy
+	if err := monitorService.RestoreScheduler(context.Background()); err != nil {
+		log.Printf("Failed to restore endpoint checks scheduler: %v", err)
+	}
+
+	// Roll up and prune old check data in the background
+	go monitorService.RunRetentionJob(context.Background())
+
+	// Alert on SLOs burning their error budget
+	go monitorService.RunSLOJob(context.Background())
+
+	// Inspect TLS certificates and warn before they expire
+	go monitorService.RunCertificateJob(context.Background())
+
 	//Rbac setup
 	rbacRepo := rbac.NewPostgresRepository(db)
 	rbacService := rbac.NewService(rbacRepo)
 
 	// --- Auth setup ---
-	userRepo := auth.NewPostgresUserRepository(db)
 	sessionRepo := auth.NewPostgresSessionRepository(db)
 
 	authService := auth.NewService(userRepo, sessionRepo)
@@ -62,10 +84,13 @@ func BootStrap(wbHub *websocket.Hub) *Application {
 	}
 
 	gitlabRepo, _ := gitlab.NewPostgresRepository(db, logger, rbacService)
-	gitlabService := gitlab.NewPipelineService(gitlabRepo, git, emailservice.NewEmailService(), logger, wbHub, userRepo)
+	gitlabService := gitlab.NewPipelineService(gitlabRepo, git, notifier, logger, wbHub, userRepo)
+
+	// Expose connected WebSocket clients alongside the other process metrics
+	metrics.RegisterWebSocketClients(wbHub.ClientCount)
 
 	// --- Router ---
-	router := api.ApiRouter(monitorApiHandler, authApiHandler, authService.AuthMiddleware(), rbacService, gitlabService, wbHub, authService)
+	router := api.ApiRouter(monitorApiHandler, authApiHandler, notifyApiHandler, authService.AuthMiddleware(), rbacService, gitlabService, wbHub, authService)
 
 	return &Application{DB: db, Router: router}
 }
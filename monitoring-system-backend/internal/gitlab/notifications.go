@@ -0,0 +1,82 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/badgerv/monitoring-api/internal/notify"
+)
+
+// notifyAuthorizationRequest sends a pipeline event to the requester's delivery email
+// and to every channel routed to the macro service's pipelines.
+func (s *PipelineService) notifyAuthorizationRequest(ctx context.Context, event, severity, title, htmlDoc string, req *AuthorizationRequest, recipient string) error {
+	msg := notify.Message{
+		Event:    event,
+		Severity: severity,
+		Title:    title,
+		HTML:     htmlDoc,
+	}
+
+	var macroService string
+	if req != nil {
+		macroService = req.MacroServiceName
+		msg.Text = fmt.Sprintf("%s: %s requested by %s", title, req.MacroServiceName, req.RequesterName)
+		msg.Fields = []notify.Field{
+			{Name: "Macro Service", Value: req.MacroServiceName},
+			{Name: "Micro Services", Value: strings.Join(req.MicroServiceNames, ", ")},
+			{Name: "Requester", Value: req.RequesterName},
+			{Name: "Status", Value: string(req.Status)},
+		}
+		if req.ApproverName != "" {
+			msg.Fields = append(msg.Fields, notify.Field{Name: "Approver", Value: req.ApproverName})
+		}
+		if req.Comment != "" {
+			msg.Fields = append(msg.Fields, notify.Field{Name: "Comment", Value: req.Comment})
+		}
+		msg.Data = req
+	}
+
+	return s.notifyPipeline(ctx, macroService, msg, recipient)
+}
+
+// notifyExecutionHistory sends a pipeline execution outcome the same way.
+func (s *PipelineService) notifyExecutionHistory(ctx context.Context, event, severity, title, htmlDoc string, history *ExecutionHistory, recipient string) error {
+	msg := notify.Message{
+		Event:    event,
+		Severity: severity,
+		Title:    title,
+		HTML:     htmlDoc,
+	}
+
+	var macroService string
+	if history != nil {
+		macroService = history.MacroServiceName
+		msg.Text = fmt.Sprintf("%s: %s", title, history.MacroServiceName)
+		msg.Fields = []notify.Field{
+			{Name: "Pipeline Run ID", Value: history.PipelineRunID},
+			{Name: "Macro Service", Value: history.MacroServiceName},
+			{Name: "Micro Services", Value: strings.Join(history.MicroServiceNames, ", ")},
+			{Name: "Status", Value: string(history.Status)},
+		}
+		if history.ErrorMessage != "" {
+			msg.Fields = append(msg.Fields, notify.Field{Name: "Error", Value: history.ErrorMessage})
+		}
+		msg.Data = history
+	}
+
+	return s.notifyPipeline(ctx, macroService, msg, recipient)
+}
+
+func (s *PipelineService) notifyPipeline(ctx context.Context, macroService string, msg notify.Message, recipient string) error {
+	if s.notifier == nil {
+		return fmt.Errorf("notifications are not configured")
+	}
+
+	var extra []notify.Channel
+	if recipient != "" {
+		extra = append(extra, s.notifier.EmailTo(recipient))
+	}
+	target := notify.Target{Pipeline: true, MacroService: macroService}
+	return s.notifier.Notify(ctx, target, msg, extra...)
+}
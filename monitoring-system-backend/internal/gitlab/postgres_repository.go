@@ -975,7 +975,7 @@ func (r *PostgresRepository) ListPipelineUnits(ctx context.Context) ([]PipelineU
 
 // UpdateExecutionHistory updates an execution history entry with all relevant fields.
 func (r *PostgresRepository) UpdateExecutionHistory(ctx context.Context, history ExecutionHistory) error {
-	fmt.Println("\n\n\n\n This is the history given \n\n\n %v \n\n\n\n\n", history)
+	fmt.Printf("\n\n\n\n This is the history given \n\n\n %v \n\n\n\n\n", history)
 
 	query := `
 		UPDATE execution_history 
@@ -3,11 +3,12 @@ package gitlab
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/badgerv/monitoring-api/internal/auth"
-	"github.com/badgerv/monitoring-api/internal/emailservice"
+	"github.com/badgerv/monitoring-api/internal/notify"
 	"github.com/badgerv/monitoring-api/internal/websocket"
 	"github.com/google/uuid"
 	"gitlab.com/gitlab-org/api/client-go"
@@ -18,18 +19,18 @@ import (
 type PipelineService struct {
 	repo         Repository
 	gitlabClient *gitlab.Client
-	emailService *emailservice.EmailService
+	notifier     *notify.Service
 	logger       *zap.Logger
 	wsHub        *websocket.Hub
 	authRepo     auth.UserRepository
 }
 
 // NewPipelineService creates a new PipelineService instance.
-func NewPipelineService(repo Repository, gitlabClient *gitlab.Client, emailService *emailservice.EmailService, logger *zap.Logger, wsHub *websocket.Hub, authRepo auth.UserRepository) *PipelineService {
+func NewPipelineService(repo Repository, gitlabClient *gitlab.Client, notifier *notify.Service, logger *zap.Logger, wsHub *websocket.Hub, authRepo auth.UserRepository) *PipelineService {
 	return &PipelineService{
 		repo:         repo,
 		gitlabClient: gitlabClient,
-		emailService: emailService,
+		notifier:     notifier,
 		logger:       logger,
 		wsHub:        wsHub,
 		authRepo:     authRepo,
@@ -222,11 +223,9 @@ func (s *PipelineService) TriggerPipelineUnit(ctx context.Context, pipelineUnitI
 		userID, _ := uuid.Parse(authRequest.RequesterID)
 		userDeliveryEmail, _ := s.authRepo.GetDeliveryEmail(ctx, userID)
 
-		fmt.Println("\n\n\n - delivery email >>>>>>>>>>>>>>", userDeliveryEmail, "delivery \n\n\n\n")
-
 		htmlDoc, _ := s.RenderAuthorizationRequestToHTML(fullAuthRequest)
-		if err := s.emailService.SendHTML(
-			"Pipeline Triggered", htmlDoc, []string{userDeliveryEmail},
+		if err := s.notifyAuthorizationRequest(ctx, notify.EventPipelineTriggered, notify.SeverityInfo,
+			"Pipeline Triggered", htmlDoc, f, userDeliveryEmail,
 		); err != nil {
 			s.logger.Error("Failed to send email notification",
 				zap.String("pipeline_run_id", userDeliveryEmail),
@@ -351,8 +350,8 @@ func (s *PipelineService) ApprovePipelineRun(ctx context.Context, authRequestID,
 		s.logger.Error("\n\nFailed to create pipeline run - Unable to parse HTML document - ", zap.String("auth_request_id", authRequestID), zap.Error(err))
 	}
 
-	if err := s.emailService.SendHTML(
-		"Pipeline Has Been Approved", htlmDoc, []string{userDeliveryEmail},
+	if err := s.notifyAuthorizationRequest(ctx, notify.EventPipelineApproved, notify.SeverityInfo,
+		"Pipeline Has Been Approved", htlmDoc, fullAuthRequest, userDeliveryEmail,
 	); err != nil {
 		s.logger.Error("Failed to send email notification",
 			zap.String("pipeline_run_id", userDeliveryEmail),
@@ -467,8 +466,8 @@ func (s *PipelineService) RejectPipelineRun(ctx context.Context, authRequestID,
 	}
 
 	go func(userDeliveryEmail, htlmDoc string) {
-		if err := s.emailService.SendHTML(
-			"Pipeline Has Been Rejected", htlmDoc, []string{userDeliveryEmail},
+		if err := s.notifyAuthorizationRequest(context.Background(), notify.EventPipelineRejected, notify.SeverityWarning,
+			"Pipeline Has Been Rejected", htlmDoc, fullAuthRequest, userDeliveryEmail,
 		); err != nil {
 			s.logger.Error("Failed to send email notification",
 				zap.String("pipeline_run_id", userDeliveryEmail),
@@ -664,8 +663,8 @@ func (s *PipelineService) executePipelineChain(ctx context.Context, run *Pipelin
 
 		userDeliveryEmail, _ := s.authRepo.GetDeliveryEmail(ctx, userID)
 
-		if err := s.emailService.SendHTML(
-			"Pipeline Has Run And Completed Successful", htmlDoc, []string{userDeliveryEmail},
+		if err := s.notifyExecutionHistory(ctx, notify.EventPipelineCompleted, notify.SeverityResolved,
+			"Pipeline Has Run And Completed Successful", htmlDoc, historyFromID, userDeliveryEmail,
 		); err != nil {
 			s.logger.Error("Failed to send email notification",
 				zap.String("pipeline_run_id", userDeliveryEmail),
@@ -703,7 +702,19 @@ func (s *PipelineService) handlePipelineError(ctx context.Context, run *Pipeline
 	}
 
 	s.broadcastPipelineUpdate(ctx, run.ID, microServiceID, StatusRejected, errorMessage)
-	return fmt.Errorf(errorMessage)
+
+	go func(history ExecutionHistory) {
+		htmlDoc, _ := s.RenderExecutionHistoryToHTML(&history)
+		if err := s.notifyExecutionHistory(context.Background(), notify.EventPipelineFailed, notify.SeverityCritical,
+			"Pipeline Run Failed", htmlDoc, &history, "",
+		); err != nil {
+			s.logger.Error("Failed to send pipeline failure notification",
+				zap.String("pipeline_run_id", run.ID),
+				zap.Error(err))
+		}
+	}(*history)
+
+	return errors.New(errorMessage)
 }
 
 // fetchMicroServicesWithRetry fetches microservices with retry logic to handle temporary failures
@@ -0,0 +1,195 @@
+// Package metrics exposes check results and API process metrics in the Prometheus
+// text format. Endpoint metrics are recorded by the replica that runs the checks, so
+// Prometheus should scrape every replica.
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "devoptic"
+
+// endpointLabels are the labels of every per-endpoint metric. tags is the endpoint's
+// tags sorted and comma-joined, e.g. "critical,payments".
+var endpointLabels = []string{"endpoint_id", "service_name", "server_name", "tags"}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	endpointUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "endpoint_up",
+		Help:      "Whether the last check of the endpoint succeeded (1) or failed (0).",
+	}, endpointLabels)
+
+	endpointLastLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "endpoint_last_latency_seconds",
+		Help:      "Latency of the last check of the endpoint.",
+	}, endpointLabels)
+
+	endpointCheckDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "endpoint_check_duration_seconds",
+		Help:      "Latency of the endpoint's checks.",
+		Buckets:   []float64{.025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, endpointLabels)
+
+	endpointConsecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "endpoint_consecutive_failures",
+		Help:      "Failed checks of the endpoint in a row.",
+	}, endpointLabels)
+
+	endpointCertExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "endpoint_certificate_expiry_timestamp_seconds",
+		Help:      "When the endpoint's TLS certificate expires, as a Unix timestamp.",
+	}, endpointLabels)
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "API requests handled, by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle API requests, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		endpointUp,
+		endpointLastLatency,
+		endpointCheckDuration,
+		endpointConsecutiveFailures,
+		endpointCertExpiry,
+		httpRequests,
+		httpRequestDuration,
+	)
+}
+
+// Handler serves every registered metric.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterWebSocketClients exposes the number of connected WebSocket clients,
+// read from count on every scrape.
+func RegisterWebSocketClients(count func() int) {
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_clients",
+		Help:      "WebSocket clients currently connected.",
+	}, func() float64 { return float64(count()) }))
+}
+
+// Endpoint identifies the endpoint a check metric belongs to.
+type Endpoint struct {
+	ID          int
+	ServiceName string
+	ServerName  string
+	Tags        []string
+}
+
+func (ep Endpoint) labels() prometheus.Labels {
+	tags := append([]string(nil), ep.Tags...)
+	sort.Strings(tags)
+	return prometheus.Labels{
+		"endpoint_id":  strconv.Itoa(ep.ID),
+		"service_name": ep.ServiceName,
+		"server_name":  ep.ServerName,
+		"tags":         strings.Join(tags, ","),
+	}
+}
+
+var (
+	// current holds the labels each endpoint was last recorded with, so a rename or
+	// retag drops the old series instead of leaving it behind
+	currentMu sync.Mutex
+	current   = map[int]prometheus.Labels{}
+)
+
+// labelsFor returns ep's labels, dropping its series under any previous labels.
+func labelsFor(ep Endpoint) prometheus.Labels {
+	labels := ep.labels()
+
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if prev, ok := current[ep.ID]; ok && !sameLabels(prev, labels) {
+		deleteSeries(prev)
+	}
+	current[ep.ID] = labels
+	return labels
+}
+
+func sameLabels(a, b prometheus.Labels) bool {
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func deleteSeries(labels prometheus.Labels) {
+	endpointUp.Delete(labels)
+	endpointLastLatency.Delete(labels)
+	endpointCheckDuration.Delete(labels)
+	endpointConsecutiveFailures.Delete(labels)
+	endpointCertExpiry.Delete(labels)
+}
+
+// RecordCheck records the outcome of one check of an endpoint.
+func RecordCheck(ep Endpoint, up bool, latency time.Duration, consecutiveFailures int) {
+	labels := labelsFor(ep)
+
+	value := 0.0
+	if up {
+		value = 1
+	}
+	endpointUp.With(labels).Set(value)
+	endpointLastLatency.With(labels).Set(latency.Seconds())
+	endpointCheckDuration.With(labels).Observe(latency.Seconds())
+	endpointConsecutiveFailures.With(labels).Set(float64(consecutiveFailures))
+}
+
+// RecordCertificate records when an endpoint's TLS certificate expires.
+func RecordCertificate(ep Endpoint, notAfter time.Time) {
+	endpointCertExpiry.With(labelsFor(ep)).Set(float64(notAfter.Unix()))
+}
+
+// ForgetEndpoint drops every series of an endpoint that is no longer checked here.
+func ForgetEndpoint(id int) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if prev, ok := current[id]; ok {
+		deleteSeries(prev)
+		delete(current, id)
+	}
+}
+
+// ForgetEndpoints drops the series of every endpoint, once checks stop running here.
+func ForgetEndpoints() {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	for id, prev := range current {
+		deleteSeries(prev)
+		delete(current, id)
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware counts and times every API request by its route pattern, e.g.
+// /api/v1/monitor/:id/checks, so IDs don't explode the label set.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// GinHandler serves /metrics to scrapes that send token as a bearer token.
+func GinHandler(token string) gin.HandlerFunc {
+	h := Handler()
+	return func(c *gin.Context) {
+		got := c.GetHeader("Authorization")
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "invalid metrics token"})
+			return
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
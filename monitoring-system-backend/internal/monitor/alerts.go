@@ -0,0 +1,334 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badgerv/monitoring-api/internal/notify"
+	"github.com/google/uuid"
+)
+
+// defaultAlertFailureThreshold is how many consecutive failed checks mark an endpoint down.
+const defaultAlertFailureThreshold = 3
+
+// AlertKind is the state transition an alert announces.
+type AlertKind string
+
+const (
+	AlertDown      AlertKind = "down"
+	AlertRecovered AlertKind = "recovered"
+)
+
+// Alert is a single endpoint state transition to notify subscribers about.
+type Alert struct {
+	Kind       AlertKind
+	Endpoint   Endpoint
+	Incident   *Incident // nil if the incident couldn't be recorded
+	StatusCode int
+	Error      string
+	Failures   int
+	DownSince  time.Time
+	OccurredAt time.Time
+}
+
+// Duration is how long the endpoint has been (or was) down.
+func (a Alert) Duration() time.Duration {
+	return a.OccurredAt.Sub(a.DownSince).Round(time.Second)
+}
+
+// alertFailureThreshold reads ALERT_FAILURE_THRESHOLD, the number of consecutive failed
+// checks before an endpoint is considered down.
+func alertFailureThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("ALERT_FAILURE_THRESHOLD")); err == nil && v > 0 {
+		return v
+	}
+	return defaultAlertFailureThreshold
+}
+
+// evaluateAlertState turns the outcome of a scheduled check into a DOWN or RECOVERED
+// alert when the endpoint crosses the failure threshold or comes back, opening and
+// resolving the matching incident. The state flip is a conditional update, so
+// concurrent checks can't send the same alert twice.
+//
+// An endpoint isn't marked down during maintenance; if it is still failing once the
+// window closes, the next failed check does it. A silence or flapping keeps the
+// incident bookkeeping but mutes the alerts. Endpoints of the same server are grouped
+// into one server alert when they all go down, see sendDownAlert. An endpoint whose
+// dependency is already down gets that as its incident's root cause instead of alerts.
+func (s *Service) evaluateAlertState(ctx context.Context, ep Endpoint, result *CheckResult, failureCount int, isDown bool, sup suppression) error {
+	switch {
+	case !result.Success && !isDown && failureCount >= alertFailureThreshold():
+		if sup.Maintenance != nil {
+			return nil
+		}
+		downSince, changed, err := s.dbRepo.MarkEndpointDown(ctx, ep.ID, failureCount)
+		if err != nil || !changed {
+			return err
+		}
+		rootCause, err := s.dbRepo.FindRootCause(ctx, ep.ID)
+		if err != nil {
+			log.Printf("Failed to look up the root cause for endpoint %d: %v", ep.ID, err)
+		}
+		incident, err := s.dbRepo.OpenIncident(ctx, ep, downSince, result.Error, failureCount, rootCause)
+		if err != nil {
+			log.Printf("Failed to open incident for endpoint %d: %v", ep.ID, err)
+		}
+		sup.Upstream = rootCause
+		s.sendDownAlert(ctx, sup, Alert{
+			Kind:       AlertDown,
+			Endpoint:   ep,
+			Incident:   incident,
+			StatusCode: result.StatusCode,
+			Error:      result.Error,
+			Failures:   failureCount,
+			DownSince:  downSince,
+			OccurredAt: time.Now(),
+		})
+
+	case !result.Success && isDown:
+		if err := s.dbRepo.AttachIncidentCheck(ctx, ep.ID, result.CheckID); err != nil {
+			return err
+		}
+		return s.releaseWithheldAlert(ctx, ep, sup)
+
+	case result.Success && isDown:
+		downSince, changed, err := s.dbRepo.MarkEndpointRecovered(ctx, ep.ID)
+		if err != nil || !changed {
+			return err
+		}
+		incident, err := s.dbRepo.ResolveIncident(ctx, ep.ID)
+		if err != nil {
+			log.Printf("Failed to resolve incident for endpoint %d: %v", ep.ID, err)
+		}
+		sup.Upstream = incident.withheldByRootCause()
+		s.sendRecoveredAlert(ctx, sup, Alert{
+			Kind:       AlertRecovered,
+			Endpoint:   ep,
+			Incident:   incident,
+			StatusCode: result.StatusCode,
+			DownSince:  downSince,
+			OccurredAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+// sendAlert delivers an alert unless a maintenance window, silence or flapping
+// suppresses it.
+func (s *Service) sendAlert(ctx context.Context, sup suppression, alert Alert) {
+	if s.withholds(ctx, sup, alert) {
+		return
+	}
+	s.deliverAlert(alert)
+}
+
+// withholds reports whether sup suppresses a DOWN or RECOVERED alert. When flapping is
+// what holds it back, the alert owed once the endpoint settles is updated.
+func (s *Service) withholds(ctx context.Context, sup suppression, alert Alert) bool {
+	if !sup.suppresses(string(alert.Kind), alert.Endpoint) {
+		return false
+	}
+	if sup.Maintenance == nil && sup.Silence == nil && sup.Flapping {
+		owed := sup.FlapOwed.withhold(alert.Kind)
+		if err := s.dbRepo.SetFlapWithheldAlert(ctx, alert.Endpoint.ID, owed); err != nil {
+			log.Printf("Failed to record withheld %s alert for endpoint %d: %v", alert.Kind, alert.Endpoint.ID, err)
+		}
+	}
+	return true
+}
+
+// deliverAlert renders an alert and sends it.
+func (s *Service) deliverAlert(alert Alert) {
+	msg, err := alertMessage(alert)
+	if err != nil {
+		log.Printf("Failed to render alert for endpoint %d: %v", alert.Endpoint.ID, err)
+		return
+	}
+	s.deliverEndpointMessage(alert.Endpoint, msg)
+}
+
+// suppresses reports whether an alert about ep must not be sent, logging why.
+func (sup suppression) suppresses(kind string, ep Endpoint) bool {
+	switch {
+	case sup.Maintenance != nil:
+		log.Printf("%s alert for %s suppressed by maintenance window %d (%s)", kind, ep.ServiceName, sup.Maintenance.ID, sup.Maintenance.Name)
+	case sup.Silence != nil:
+		log.Printf("%s alert for %s suppressed by silence %d until %s", kind, ep.ServiceName, sup.Silence.ID, sup.Silence.ExpiresAt.Format(time.RFC3339))
+	case sup.Flapping:
+		log.Printf("%s alert for %s suppressed while the endpoint is flapping", kind, ep.ServiceName)
+	case sup.Upstream != nil:
+		log.Printf("%s alert for %s suppressed: its dependency %s (endpoint %d) is down", kind, ep.ServiceName, sup.Upstream.ServiceName, sup.Upstream.EndpointID)
+	default:
+		return false
+	}
+	return true
+}
+
+// deliverEndpointMessage sends msg in the background so a slow SMTP server or webhook
+// never holds up a check worker. Subscribers get it by email; channels routed to the
+// endpoint, one of its tags or its server get it too.
+func (s *Service) deliverEndpointMessage(ep Endpoint, msg notify.Message) {
+	if s.notifier == nil {
+		log.Printf("Alert for %s (%s) not sent: notifications are not configured", ep.ServiceName, msg.Event)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var extra []notify.Channel
+		recipients, err := s.alertRecipients(ctx, ep.ID)
+		if err != nil {
+			log.Printf("Failed to load alert recipients for endpoint %d: %v", ep.ID, err)
+		} else if len(recipients) > 0 {
+			extra = append(extra, s.notifier.EmailTo(recipients...))
+		}
+
+		tags, err := s.dbRepo.GetEndpointTags(ctx, ep.ID)
+		if err != nil {
+			log.Printf("Failed to load tags for endpoint %d: %v", ep.ID, err)
+		}
+
+		target := notify.Target{EndpointID: ep.ID, Tags: tags, ServerName: ep.ServerName}
+		if err := s.notifier.Notify(ctx, target, msg, extra...); err != nil {
+			log.Printf("Failed to deliver %s alert for endpoint %d: %v", msg.Event, ep.ID, err)
+		}
+	}()
+}
+
+// alertRecipients resolves subscribed users to their delivery emails, skipping users
+// without one.
+func (s *Service) alertRecipients(ctx context.Context, endpointID int) ([]string, error) {
+	userIDs, err := s.dbRepo.ListSubscriberIDs(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	recipients := []string{}
+	for _, id := range userIDs {
+		email, err := s.userRepo.GetDeliveryEmail(ctx, id)
+		if err != nil {
+			log.Printf("No delivery email for user %s: %v", id, err)
+			continue
+		}
+		email = strings.TrimSpace(email)
+		if email == "" || seen[email] {
+			continue
+		}
+		seen[email] = true
+		recipients = append(recipients, email)
+	}
+	return recipients, nil
+}
+
+var alertEmailTemplate = template.Must(template.New("alert").Parse(`
+	<html>
+	<head>
+		<style>
+			body { font-family: Arial, sans-serif; }
+			.container { border: 1px solid #ddd; padding: 16px; border-radius: 8px; }
+			.title { font-size: 20px; font-weight: bold; margin-bottom: 12px; }
+			.down { color: #c62828; }
+			.recovered { color: #2e7d32; }
+			.section { margin-bottom: 8px; }
+			.label { font-weight: bold; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			{{if eq .Kind "down"}}
+			<div class="title down">{{.Endpoint.ServiceName}} is DOWN</div>
+			{{else}}
+			<div class="title recovered">{{.Endpoint.ServiceName}} has RECOVERED</div>
+			{{end}}
+
+			{{if .Incident}}<div class="section"><span class="label">Incident:</span> #{{.Incident.ID}}</div>{{end}}
+			<div class="section"><span class="label">URL:</span> {{.Endpoint.APIMethod}} {{.Endpoint.URL}}</div>
+			<div class="section"><span class="label">Server:</span> {{.Endpoint.ServerName}}</div>
+			<div class="section"><span class="label">Down since:</span> {{.DownSince.Format "2006-01-02 15:04:05 MST"}}</div>
+			{{if eq .Kind "down"}}
+			<div class="section"><span class="label">Consecutive failures:</span> {{.Failures}}</div>
+			{{if .StatusCode}}<div class="section"><span class="label">Status code:</span> {{.StatusCode}} (expected {{.Endpoint.ExpectedCode}})</div>{{end}}
+			{{if .Error}}<div class="section"><span class="label">Error:</span> {{.Error}}</div>{{end}}
+			{{else}}
+			<div class="section"><span class="label">Outage duration:</span> {{.Duration}}</div>
+			{{end}}
+		</div>
+	</body>
+	</html>`))
+
+// alertMessage renders an alert for every channel: HTML for email, title, text and
+// fields for chat, and the alert details as webhook data.
+func alertMessage(alert Alert) (notify.Message, error) {
+	msg := notify.Message{
+		Fields: []notify.Field{
+			{Name: "URL", Value: alert.Endpoint.APIMethod + " " + alert.Endpoint.URL},
+			{Name: "Server", Value: alert.Endpoint.ServerName},
+			{Name: "Down since", Value: alert.DownSince.Format("2006-01-02 15:04:05 MST")},
+		},
+	}
+
+	data := map[string]interface{}{
+		"endpoint_id":  alert.Endpoint.ID,
+		"service_name": alert.Endpoint.ServiceName,
+		"server_name":  alert.Endpoint.ServerName,
+		"url":          alert.Endpoint.URL,
+		"down_since":   alert.DownSince,
+	}
+	if alert.Incident != nil {
+		data["incident_id"] = alert.Incident.ID
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Incident", Value: fmt.Sprintf("#%d", alert.Incident.ID)})
+	}
+
+	if alert.Kind == AlertDown {
+		msg.Event = notify.EventEndpointDown
+		msg.Severity = notify.SeverityCritical
+		msg.Title = fmt.Sprintf("[DOWN] %s (%s)", alert.Endpoint.ServiceName, alert.Endpoint.ServerName)
+		msg.Text = fmt.Sprintf("%s failed %d consecutive checks", alert.Endpoint.ServiceName, alert.Failures)
+		if alert.Error != "" {
+			msg.Text += ": " + alert.Error
+		}
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Consecutive failures", Value: strconv.Itoa(alert.Failures)})
+		data["failures"] = alert.Failures
+		data["status_code"] = alert.StatusCode
+		data["error"] = alert.Error
+	} else {
+		msg.Event = notify.EventEndpointRecovered
+		msg.Severity = notify.SeverityResolved
+		msg.Title = fmt.Sprintf("[RECOVERED] %s (%s) after %s", alert.Endpoint.ServiceName, alert.Endpoint.ServerName, alert.Duration())
+		msg.Text = fmt.Sprintf("%s is responding again after %s", alert.Endpoint.ServiceName, alert.Duration())
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Outage duration", Value: alert.Duration().String()})
+		data["duration_seconds"] = int64(alert.Duration().Seconds())
+	}
+	msg.Data = data
+
+	builder := &strings.Builder{}
+	if err := alertEmailTemplate.Execute(builder, alert); err != nil {
+		return msg, err
+	}
+	msg.HTML = builder.String()
+	return msg, nil
+}
+
+// Subscribe adds a user to an endpoint's alert recipients
+func (s *Service) Subscribe(ctx context.Context, endpointID int, userID uuid.UUID) error {
+	return s.dbRepo.AddSubscription(ctx, endpointID, userID)
+}
+
+// Unsubscribe removes a user from an endpoint's alert recipients
+func (s *Service) Unsubscribe(ctx context.Context, endpointID int, userID uuid.UUID) error {
+	return s.dbRepo.RemoveSubscription(ctx, endpointID, userID)
+}
+
+// ListSubscriptions returns the users subscribed to an endpoint's alerts
+func (s *Service) ListSubscriptions(ctx context.Context, endpointID int) ([]EndpointSubscription, error) {
+	return s.dbRepo.ListSubscriptions(ctx, endpointID)
+}
@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AssertionType identifies what an assertion inspects in a check response.
+type AssertionType string
+
+const (
+	AssertJSONPath    AssertionType = "json_path"     // value at Path equals Value
+	AssertRegex       AssertionType = "regex"         // body matches the Value pattern
+	AssertContains    AssertionType = "contains"      // body contains Value
+	AssertMaxBodySize AssertionType = "max_body_size" // body is at most Max bytes
+	AssertHeader      AssertionType = "header"        // Header is present, and equals Value when set
+	AssertMaxLatency  AssertionType = "max_latency"   // response arrived within Max milliseconds
+)
+
+const (
+	// maxAssertionBodyBytes caps how much of a response body is read for assertions.
+	maxAssertionBodyBytes = 1 << 20
+
+	// maxBodySizeLimit is the largest max_body_size accepted, so one endpoint can't make
+	// a check worker buffer an arbitrarily large body.
+	maxBodySizeLimit = 10 << 20
+)
+
+// Assertion is a single condition a check response must satisfy to count as "up".
+type Assertion struct {
+	Type   AssertionType `json:"type"`
+	Path   string        `json:"path,omitempty"`
+	Header string        `json:"header,omitempty"`
+	Value  string        `json:"value,omitempty"`
+	Max    int64         `json:"max,omitempty"`
+}
+
+// AssertionError reports which assertion a response failed and why.
+type AssertionError struct {
+	Index     int       `json:"index"`
+	Assertion Assertion `json:"assertion"`
+	Reason    string    `json:"reason"`
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("assertion #%d (%s) failed: %s", e.Index, e.Assertion.Type, e.Reason)
+}
+
+// needsBody reports whether any assertion has to look at the response body.
+func needsBody(assertions []Assertion) bool {
+	for _, a := range assertions {
+		switch a.Type {
+		case AssertJSONPath, AssertRegex, AssertContains, AssertMaxBodySize:
+			return true
+		}
+	}
+	return false
+}
+
+// bodyReadLimit returns how many bytes to read so max_body_size can still detect oversize
+// bodies, never more than one byte past maxBodySizeLimit.
+func bodyReadLimit(assertions []Assertion) int64 {
+	limit := int64(maxAssertionBodyBytes)
+	for _, a := range assertions {
+		if a.Type == AssertMaxBodySize && a.Max+1 > limit {
+			limit = a.Max + 1
+		}
+	}
+	return min(limit, maxBodySizeLimit+1)
+}
+
+// ValidateAssertions rejects assertions that could never be evaluated.
+func ValidateAssertions(assertions []Assertion) error {
+	for i, a := range assertions {
+		switch a.Type {
+		case AssertJSONPath:
+			if _, err := parseJSONPath(a.Path); err != nil {
+				return fmt.Errorf("assertion #%d: %w", i, err)
+			}
+		case AssertRegex:
+			if _, err := regexp.Compile(a.Value); err != nil {
+				return fmt.Errorf("assertion #%d: invalid regex: %w", i, err)
+			}
+		case AssertContains:
+			if a.Value == "" {
+				return fmt.Errorf("assertion #%d: contains needs a value", i)
+			}
+		case AssertMaxBodySize, AssertMaxLatency:
+			if a.Max <= 0 {
+				return fmt.Errorf("assertion #%d: %s needs a positive max", i, a.Type)
+			}
+			if a.Type == AssertMaxBodySize && a.Max > maxBodySizeLimit {
+				return fmt.Errorf("assertion #%d: max_body_size can be at most %d bytes", i, maxBodySizeLimit)
+			}
+		case AssertHeader:
+			if strings.TrimSpace(a.Header) == "" {
+				return fmt.Errorf("assertion #%d: header needs a header name", i)
+			}
+		default:
+			return fmt.Errorf("assertion #%d: unknown type %q", i, a.Type)
+		}
+	}
+	return nil
+}
+
+// evaluateAssertions runs each assertion in order and returns the first one that fails.
+func evaluateAssertions(assertions []Assertion, header http.Header, body []byte, latency time.Duration) *AssertionError {
+	var parsed interface{}
+	var parseErr error
+	parsedBody := false
+
+	for i, a := range assertions {
+		fail := func(format string, args ...interface{}) *AssertionError {
+			return &AssertionError{Index: i, Assertion: a, Reason: fmt.Sprintf(format, args...)}
+		}
+
+		switch a.Type {
+		case AssertJSONPath:
+			if !parsedBody {
+				parseErr = json.Unmarshal(body, &parsed)
+				parsedBody = true
+			}
+			if parseErr != nil {
+				return fail("response body is not valid JSON: %v", parseErr)
+			}
+			got, ok := lookupJSONPath(parsed, a.Path)
+			if !ok {
+				return fail("%s not found in response", a.Path)
+			}
+			if got != a.Value {
+				return fail("%s expected %q, got %q", a.Path, a.Value, got)
+			}
+		case AssertRegex:
+			re, err := regexp.Compile(a.Value)
+			if err != nil {
+				return fail("invalid regex: %v", err)
+			}
+			if !re.Match(body) {
+				return fail("response body does not match %q", a.Value)
+			}
+		case AssertContains:
+			if !strings.Contains(string(body), a.Value) {
+				return fail("response body does not contain %q", a.Value)
+			}
+		case AssertMaxBodySize:
+			if int64(len(body)) > a.Max {
+				return fail("response body exceeds %d bytes", a.Max)
+			}
+		case AssertHeader:
+			values, ok := header[http.CanonicalHeaderKey(a.Header)]
+			if !ok {
+				return fail("response header %s is missing", a.Header)
+			}
+			if a.Value != "" && (len(values) == 0 || values[0] != a.Value) {
+				return fail("response header %s expected %q, got %q", a.Header, a.Value, strings.Join(values, ","))
+			}
+		case AssertMaxLatency:
+			if latency.Milliseconds() > a.Max {
+				return fail("latency %dms exceeds %dms", latency.Milliseconds(), a.Max)
+			}
+		default:
+			return fail("unknown assertion type %q", a.Type)
+		}
+	}
+
+	return nil
+}
+
+// jsonPathStep is either a field name or an array index.
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath supports the dotted subset of JSONPath, e.g. $.status or $.checks[0].state.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	p := strings.TrimSpace(path)
+	if !strings.HasPrefix(p, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", path)
+	}
+	p = strings.TrimPrefix(p, "$")
+
+	var steps []jsonPathStep
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end == -1 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("json path %q has an empty field name", path)
+			}
+			steps = append(steps, jsonPathStep{key: p[:end]})
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("json path %q has an unclosed [", path)
+			}
+			inner := p[1:end]
+			if idx, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, jsonPathStep{index: idx, isIndex: true})
+			} else {
+				key := strings.Trim(inner, `'"`)
+				if key == "" {
+					return nil, fmt.Errorf("json path %q has an empty field name", path)
+				}
+				steps = append(steps, jsonPathStep{key: key})
+			}
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("json path %q is not supported", path)
+		}
+	}
+
+	return steps, nil
+}
+
+// lookupJSONPath resolves path against a decoded JSON document and returns the value
+// in its textual form, so "UP", 200 and true compare against "UP", "200" and "true".
+func lookupJSONPath(doc interface{}, path string) (string, bool) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return "", false
+	}
+
+	cur := doc
+	for _, step := range steps {
+		if step.isIndex {
+			arr, ok := cur.([]interface{})
+			if !ok || step.index < 0 || step.index >= len(arr) {
+				return "", false
+			}
+			cur = arr[step.index]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		cur, ok = obj[step.key]
+		if !ok {
+			return "", false
+		}
+	}
+
+	switch v := cur.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "null", true
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(raw), true
+	}
+}
@@ -0,0 +1,45 @@
+package monitor
+
+import (
+	"fmt"
+	"html"
+	"unicode/utf8"
+)
+
+// badgeColors follows the shields.io palette so badges sit well next to others in a README.
+var badgeColors = map[string]string{
+	ComponentOperational: "#4c1",
+	ComponentMaintenance: "#007ec6",
+	ComponentDegraded:    "#dfb317",
+	ComponentOutage:      "#e05d44",
+}
+
+// badgeTextWidth estimates the rendered width of 11px Verdana, which averages about
+// 7px a character, plus padding on both sides.
+func badgeTextWidth(s string) int {
+	return utf8.RuneCountInString(s)*7 + 10
+}
+
+// StatusBadgeSVG renders a flat "label | status" badge.
+func StatusBadgeSVG(label, status string) []byte {
+	color, ok := badgeColors[status]
+	if !ok {
+		color = "#9f9f9f"
+	}
+
+	labelWidth := badgeTextWidth(label)
+	statusWidth := badgeTextWidth(status)
+	width := labelWidth + statusWidth
+	label, status = html.EscapeString(label), html.EscapeString(status)
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">`+
+		`<title>%[2]s: %[3]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[4]d" height="20" fill="#555"/><rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text><text x="%[7]d" y="14">%[2]s</text>`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text><text x="%[8]d" y="14">%[3]s</text>`+
+		`</g></svg>`,
+		width, label, status, labelWidth, statusWidth, color, labelWidth/2, labelWidth+statusWidth/2))
+}
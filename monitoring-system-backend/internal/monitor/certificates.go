@@ -0,0 +1,333 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badgerv/monitoring-api/internal/metrics"
+	"github.com/badgerv/monitoring-api/internal/notify"
+)
+
+const (
+	// defaultCertCheckInterval is how often an HTTPS endpoint's certificate is re-inspected.
+	// Certificates rarely change, so this runs far less often than the checks themselves.
+	defaultCertCheckInterval = 6 * time.Hour
+
+	// defaultCertJobInterval is how often the certificate job looks for certificates due
+	// for inspection.
+	defaultCertJobInterval = 5 * time.Minute
+)
+
+// defaultCertExpiryThresholds are the days-to-expiry at which a warning is sent.
+var defaultCertExpiryThresholds = []int{30, 14, 3}
+
+// CertificateSummary describes one certificate of the chain the server presented.
+type CertificateSummary struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+	IsCA     bool      `json:"is_ca"`
+}
+
+// CertificateInfo is the last inspection of an endpoint's TLS certificate. Errors
+// lists why the hostname or chain didn't verify.
+type CertificateInfo struct {
+	EndpointID       int                  `json:"endpoint_id"`
+	Subject          string               `json:"subject"`
+	Issuer           string               `json:"issuer"`
+	SerialNumber     string               `json:"serial_number"`
+	DNSNames         []string             `json:"dns_names"`
+	NotBefore        time.Time            `json:"not_before"`
+	NotAfter         time.Time            `json:"not_after"`
+	DaysToExpiry     int                  `json:"days_to_expiry"`
+	HostnameValid    bool                 `json:"hostname_valid"`
+	ChainValid       bool                 `json:"chain_valid"`
+	Chain            []CertificateSummary `json:"chain"`
+	Errors           []string             `json:"errors"`
+	AlertedThreshold *int                 `json:"alerted_threshold_days"`
+	CheckedAt        time.Time            `json:"checked_at"`
+}
+
+// daysUntil counts whole days left until t; an expired certificate has a negative count.
+func daysUntil(t time.Time) int {
+	return int(math.Floor(time.Until(t).Hours() / 24))
+}
+
+// certExpiryThresholds reads CERT_EXPIRY_THRESHOLDS, e.g. "30,14,3", largest first.
+func certExpiryThresholds() []int {
+	raw := strings.TrimSpace(os.Getenv("CERT_EXPIRY_THRESHOLDS"))
+	if raw == "" {
+		return defaultCertExpiryThresholds
+	}
+
+	thresholds := []int{}
+	for _, part := range strings.Split(raw, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || days < 0 {
+			log.Printf("Ignoring invalid CERT_EXPIRY_THRESHOLDS entry %q", part)
+			continue
+		}
+		thresholds = append(thresholds, days)
+	}
+	if len(thresholds) == 0 {
+		return defaultCertExpiryThresholds
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+	return thresholds
+}
+
+// crossedThreshold returns the smallest threshold the certificate is within, or nil
+// if it is further from expiry than every threshold.
+func crossedThreshold(daysToExpiry int, thresholds []int) *int {
+	var crossed *int
+	for i := range thresholds {
+		if daysToExpiry <= thresholds[i] && (crossed == nil || thresholds[i] < *crossed) {
+			crossed = &thresholds[i]
+		}
+	}
+	return crossed
+}
+
+// inspectCertificate connects to an HTTPS endpoint and reads the certificate chain it
+// presents. Verification is done by hand after the handshake so an expired or
+// mismatched certificate is still captured rather than just failing the dial.
+func inspectCertificate(ctx context.Context, ep *Endpoint) (*CertificateInfo, error) {
+	u, err := url.Parse(ep.URL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: ep.checkTimeout()},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("tls handshake failed: %w", err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("server presented no certificate")
+	}
+	leaf := certs[0]
+
+	info := &CertificateInfo{
+		EndpointID:   ep.ID,
+		Subject:      leaf.Subject.String(),
+		Issuer:       leaf.Issuer.String(),
+		SerialNumber: leaf.SerialNumber.String(),
+		DNSNames:     leaf.DNSNames,
+		NotBefore:    leaf.NotBefore,
+		NotAfter:     leaf.NotAfter,
+		DaysToExpiry: daysUntil(leaf.NotAfter),
+		Chain:        make([]CertificateSummary, 0, len(certs)),
+		Errors:       []string{},
+		CheckedAt:    time.Now(),
+	}
+	if info.DNSNames == nil {
+		info.DNSNames = []string{}
+	}
+
+	intermediates := x509.NewCertPool()
+	for i, cert := range certs {
+		if i > 0 {
+			intermediates.AddCert(cert)
+		}
+		info.Chain = append(info.Chain, CertificateSummary{
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+			IsCA:     cert.IsCA,
+		})
+	}
+
+	if err := leaf.VerifyHostname(host); err != nil {
+		info.Errors = append(info.Errors, err.Error())
+	} else {
+		info.HostnameValid = true
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates}); err != nil {
+		info.Errors = append(info.Errors, err.Error())
+	} else {
+		info.ChainValid = true
+	}
+
+	return info, nil
+}
+
+// RunCertificateJob inspects the certificates due every CERT_JOB_INTERVAL, off the check
+// workers so a slow TLS handshake never delays a check. Only the replica running checks
+// inspects, so each warning is sent once.
+func (s *Service) RunCertificateJob(ctx context.Context) {
+	ticker := time.NewTicker(envSeconds("CERT_JOB_INTERVAL", defaultCertJobInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.runsChecks() {
+				continue
+			}
+			endpoints, err := s.dbRepo.GetAllEndpoints(ctx)
+			if err != nil {
+				log.Printf("Certificate job failed to load endpoints: %v", err)
+				continue
+			}
+			for _, ep := range endpoints {
+				s.refreshCertificate(ctx, ep)
+			}
+		}
+	}
+}
+
+// refreshCertificate re-inspects an HTTPS endpoint's certificate once
+// CERT_CHECK_INTERVAL has passed and sends a warning each time it crosses a closer
+// expiry threshold. A renewed certificate resets the thresholds.
+func (s *Service) refreshCertificate(ctx context.Context, ep Endpoint) {
+	if !strings.HasPrefix(strings.ToLower(ep.URL), "https://") {
+		return
+	}
+
+	prev, err := s.dbRepo.GetCertificate(ctx, ep.ID)
+	if err != nil {
+		log.Printf("Failed to load certificate for endpoint %d: %v", ep.ID, err)
+		return
+	}
+	if prev != nil && time.Since(prev.CheckedAt) < envSeconds("CERT_CHECK_INTERVAL", defaultCertCheckInterval) {
+		// Keep the expiry gauge set on a replica that hasn't inspected it itself yet
+		metrics.RecordCertificate(s.metricsEndpoint(ctx, ep), prev.NotAfter)
+		return
+	}
+
+	info, err := inspectCertificate(ctx, &ep)
+	if err != nil {
+		// The check itself reports unreachable endpoints; try again next time
+		log.Printf("Failed to inspect certificate for endpoint %d: %v", ep.ID, err)
+		return
+	}
+	if prev != nil {
+		info.AlertedThreshold = prev.AlertedThreshold
+	}
+
+	threshold := crossedThreshold(info.DaysToExpiry, certExpiryThresholds())
+	switch {
+	case threshold == nil:
+		info.AlertedThreshold = nil
+	case info.AlertedThreshold == nil || *threshold < *info.AlertedThreshold:
+		sup, err := s.activeSuppression(ctx, ep)
+		if err != nil {
+			log.Printf("Failed to load maintenance windows and silences for endpoint %d: %v", ep.ID, err)
+		}
+		// A suppressed warning isn't recorded, so it goes out on the next inspection
+		if !sup.suppresses("certificate", ep) {
+			msg, err := certificateMessage(ep, info)
+			if err != nil {
+				log.Printf("Failed to render certificate alert for endpoint %d: %v", ep.ID, err)
+			} else {
+				s.deliverEndpointMessage(ep, msg)
+				info.AlertedThreshold = threshold
+			}
+		}
+	}
+
+	if err := s.dbRepo.SaveCertificate(ctx, info); err != nil {
+		log.Printf("Failed to save certificate for endpoint %d: %v", ep.ID, err)
+	}
+	metrics.RecordCertificate(s.metricsEndpoint(ctx, ep), info.NotAfter)
+}
+
+// GetCertificate returns the last certificate inspection of an endpoint, or nil if it
+// has none
+func (s *Service) GetCertificate(ctx context.Context, endpointID int) (*CertificateInfo, error) {
+	return s.dbRepo.GetCertificate(ctx, endpointID)
+}
+
+var certificateEmailTemplate = template.Must(template.New("certificate").Parse(`
+	<html>
+	<head>
+		<style>
+			body { font-family: Arial, sans-serif; }
+			.container { border: 1px solid #ddd; padding: 16px; border-radius: 8px; }
+			.title { font-size: 20px; font-weight: bold; margin-bottom: 12px; color: #ef6c00; }
+			.section { margin-bottom: 8px; }
+			.label { font-weight: bold; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			{{if lt .Cert.DaysToExpiry 0}}
+			<div class="title">The certificate of {{.Endpoint.ServiceName}} has EXPIRED</div>
+			{{else}}
+			<div class="title">The certificate of {{.Endpoint.ServiceName}} expires in {{.Cert.DaysToExpiry}} days</div>
+			{{end}}
+
+			<div class="section"><span class="label">URL:</span> {{.Endpoint.URL}}</div>
+			<div class="section"><span class="label">Server:</span> {{.Endpoint.ServerName}}</div>
+			<div class="section"><span class="label">Expires:</span> {{.Cert.NotAfter.Format "2006-01-02 15:04:05 MST"}}</div>
+			<div class="section"><span class="label">Subject:</span> {{.Cert.Subject}}</div>
+			<div class="section"><span class="label">Issuer:</span> {{.Cert.Issuer}}</div>
+			<div class="section"><span class="label">Hostname valid:</span> {{.Cert.HostnameValid}}</div>
+			<div class="section"><span class="label">Chain valid:</span> {{.Cert.ChainValid}}</div>
+			{{range .Cert.Errors}}<div class="section"><span class="label">Error:</span> {{.}}</div>{{end}}
+		</div>
+	</body>
+	</html>`))
+
+// certificateMessage renders a certificate expiry warning for every channel.
+func certificateMessage(ep Endpoint, cert *CertificateInfo) (notify.Message, error) {
+	msg := notify.Message{
+		Event:    notify.EventCertificateExpiry,
+		Severity: notify.SeverityWarning,
+		Title:    fmt.Sprintf("[CERTIFICATE] %s (%s) expires in %d days", ep.ServiceName, ep.ServerName, cert.DaysToExpiry),
+		Text:     fmt.Sprintf("The certificate of %s expires on %s", ep.URL, cert.NotAfter.Format("2006-01-02")),
+		Fields: []notify.Field{
+			{Name: "URL", Value: ep.URL},
+			{Name: "Server", Value: ep.ServerName},
+			{Name: "Issuer", Value: cert.Issuer},
+			{Name: "Expires", Value: cert.NotAfter.Format("2006-01-02 15:04:05 MST")},
+			{Name: "Hostname valid", Value: strconv.FormatBool(cert.HostnameValid)},
+			{Name: "Chain valid", Value: strconv.FormatBool(cert.ChainValid)},
+		},
+		Data: map[string]interface{}{
+			"endpoint_id":  ep.ID,
+			"service_name": ep.ServiceName,
+			"server_name":  ep.ServerName,
+			"url":          ep.URL,
+			"certificate":  cert,
+		},
+	}
+	if cert.DaysToExpiry < 0 {
+		msg.Severity = notify.SeverityCritical
+		msg.Title = fmt.Sprintf("[CERTIFICATE] %s (%s) has expired", ep.ServiceName, ep.ServerName)
+		msg.Text = fmt.Sprintf("The certificate of %s expired on %s", ep.URL, cert.NotAfter.Format("2006-01-02"))
+	}
+
+	builder := &strings.Builder{}
+	err := certificateEmailTemplate.Execute(builder, map[string]interface{}{"Endpoint": ep, "Cert": cert})
+	if err != nil {
+		return msg, err
+	}
+	msg.HTML = builder.String()
+	return msg, nil
+}
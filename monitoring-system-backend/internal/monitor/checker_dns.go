@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// dnsLookups resolves a name for each supported record type into comparable strings.
+var dnsLookups = map[string]func(ctx context.Context, r *net.Resolver, name string) ([]string, error){
+	"A":    lookupIPs("ip4"),
+	"AAAA": lookupIPs("ip6"),
+	"CNAME": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		cname, err := r.LookupCNAME(ctx, name)
+		return []string{cname}, err
+	},
+	"MX": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		records, err := r.LookupMX(ctx, name)
+		values := make([]string, 0, len(records))
+		for _, mx := range records {
+			values = append(values, mx.Host)
+		}
+		return values, err
+	},
+	"NS": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		records, err := r.LookupNS(ctx, name)
+		values := make([]string, 0, len(records))
+		for _, ns := range records {
+			values = append(values, ns.Host)
+		}
+		return values, err
+	},
+	"TXT": func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		return r.LookupTXT(ctx, name)
+	},
+}
+
+func lookupIPs(network string) func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+	return func(ctx context.Context, r *net.Resolver, name string) ([]string, error) {
+		ips, err := r.LookupIP(ctx, network, name)
+		values := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			values = append(values, ip.String())
+		}
+		return values, err
+	}
+}
+
+func dnsRecordType(cfg CheckConfig) string {
+	if cfg.RecordType == "" {
+		return "A"
+	}
+	return strings.ToUpper(cfg.RecordType)
+}
+
+// normalizeDNSValue makes "Mail.Example.com." and "mail.example.com" compare equal.
+func normalizeDNSValue(v string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(v), "."))
+}
+
+// dnsChecker resolves the endpoint's name and checks the expected records are among
+// the answers. Without expectations any answer counts as up.
+type dnsChecker struct{}
+
+func (dnsChecker) Check(ctx context.Context, ep *Endpoint) *CheckResult {
+	result := &CheckResult{EndpointID: ep.ID}
+	cfg := ep.CheckConfig
+
+	lookup, ok := dnsLookups[dnsRecordType(cfg)]
+	if !ok {
+		result.Error = fmt.Sprintf("unsupported dns record_type %q", cfg.RecordType)
+		return result
+	}
+
+	resolver := net.DefaultResolver
+	if cfg.Resolver != "" {
+		dialer := &net.Dialer{Timeout: ep.checkTimeout()}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, cfg.Resolver)
+			},
+		}
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, ep.checkTimeout())
+	defer cancel()
+
+	start := time.Now()
+	answers, err := lookup(lookupCtx, resolver, checkTarget(ep.URL))
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if len(answers) == 0 {
+		result.Error = fmt.Sprintf("no %s records for %s", dnsRecordType(cfg), checkTarget(ep.URL))
+		return result
+	}
+
+	got := map[string]bool{}
+	for _, a := range answers {
+		got[normalizeDNSValue(a)] = true
+	}
+	var missing []string
+	for _, want := range cfg.Expected {
+		if !got[normalizeDNSValue(want)] {
+			missing = append(missing, want)
+		}
+	}
+	if len(missing) > 0 {
+		result.Error = fmt.Sprintf("expected %s records %s not found, got %s",
+			dnsRecordType(cfg), strings.Join(missing, ", "), strings.Join(answers, ", "))
+		return result
+	}
+
+	result.Success = true
+	return result
+}
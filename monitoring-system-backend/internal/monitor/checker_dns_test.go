@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSServer answers A queries for the names in records over UDP on a random local
+// port, and NXDOMAIN for anything else.
+func startDNSServer(t *testing.T, records map[string][4]byte) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) == 0 {
+				continue
+			}
+			q := req.Questions[0]
+
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true, RecursionAvailable: true},
+				Questions: req.Questions,
+			}
+			ip, ok := records[q.Name.String()]
+			switch {
+			case !ok:
+				resp.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: ip},
+				}}
+			}
+
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			pc.WriteTo(out, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestDNSChecker(t *testing.T) {
+	resolver := startDNSServer(t, map[string][4]byte{"api.example.test.": {10, 0, 0, 7}})
+	timeout := 2
+
+	tests := []struct {
+		name    string
+		target  string
+		cfg     CheckConfig
+		success bool
+		errPart string
+	}{
+		{name: "any answer", target: "api.example.test", success: true},
+		{name: "record match", target: "api.example.test", cfg: CheckConfig{Expected: []string{"10.0.0.7"}}, success: true},
+		{name: "record mismatch", target: "api.example.test", cfg: CheckConfig{Expected: []string{"10.0.0.8"}}, errPart: "10.0.0.8 not found, got 10.0.0.7"},
+		{name: "unknown name", target: "missing.example.test", errPart: "no such host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Resolver = resolver
+			ep := &Endpoint{ID: 1, URL: "dns://" + tt.target, CheckType: CheckDNS, CheckConfig: cfg, TimeoutSeconds: &timeout}
+
+			result := dnsChecker{}.Check(context.Background(), ep)
+			if result.Success != tt.success {
+				t.Fatalf("success = %v, want %v (error %q)", result.Success, tt.success, result.Error)
+			}
+			if !strings.Contains(result.Error, tt.errPart) {
+				t.Errorf("error = %q, want it to contain %q", result.Error, tt.errPart)
+			}
+		})
+	}
+}
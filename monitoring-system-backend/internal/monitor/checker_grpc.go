@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcChecker calls grpc.health.v1.Health/Check and counts only SERVING as up.
+type grpcChecker struct{}
+
+func (grpcChecker) Check(ctx context.Context, ep *Endpoint) *CheckResult {
+	result := &CheckResult{EndpointID: ep.ID}
+	cfg := ep.CheckConfig
+
+	creds := insecure.NewCredentials()
+	if cfg.TLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+
+	conn, err := grpc.NewClient(checkTarget(ep.URL), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	callCtx, cancel := context.WithTimeout(ctx, ep.checkTimeout())
+	defer cancel()
+
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(callCtx, &healthpb.HealthCheckRequest{Service: cfg.Service})
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		result.Error = fmt.Sprintf("health status is %s", resp.GetStatus())
+		return result
+	}
+
+	result.Success = true
+	return result
+}
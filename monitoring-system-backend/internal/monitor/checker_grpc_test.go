@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGRPCChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("orders.v1.Orders", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("billing.v1.Billing", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	timeout := 2
+	tests := []struct {
+		name    string
+		service string
+		success bool
+		errPart string
+	}{
+		{name: "server", service: "", success: true},
+		{name: "serving", service: "orders.v1.Orders", success: true},
+		{name: "not serving", service: "billing.v1.Billing", errPart: "NOT_SERVING"},
+		{name: "unknown service", service: "missing.v1.Missing", errPart: "NotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &Endpoint{ID: 1, URL: "grpc://" + ln.Addr().String(), CheckType: CheckGRPC,
+				CheckConfig: CheckConfig{Service: tt.service}, TimeoutSeconds: &timeout}
+
+			result := grpcChecker{}.Check(context.Background(), ep)
+			if result.Success != tt.success {
+				t.Fatalf("success = %v, want %v (error %q)", result.Success, tt.success, result.Error)
+			}
+			if !strings.Contains(result.Error, tt.errPart) {
+				t.Errorf("error = %q, want it to contain %q", result.Error, tt.errPart)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+)
+
+// maxBannerBytes caps how much a TCP check reads while waiting for the banner.
+const maxBannerBytes = 4096
+
+// tcpChecker connects to host:port and, when a banner is configured, waits for the
+// server's first bytes to match it.
+type tcpChecker struct{}
+
+func (tcpChecker) Check(ctx context.Context, ep *Endpoint) *CheckResult {
+	result := &CheckResult{EndpointID: ep.ID}
+	cfg := ep.CheckConfig
+
+	dialer := &net.Dialer{Timeout: ep.checkTimeout()}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", checkTarget(ep.URL))
+	if err != nil {
+		result.Latency = time.Since(start)
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	if cfg.Send == "" && cfg.Banner == "" {
+		result.Latency = time.Since(start)
+		result.Success = true
+		return result
+	}
+
+	deadline := time.Now().Add(ep.checkTimeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if cfg.Send != "" {
+		if _, err := conn.Write([]byte(cfg.Send)); err != nil {
+			result.Latency = time.Since(start)
+			result.Error = fmt.Sprintf("failed to send payload: %v", err)
+			return result
+		}
+	}
+
+	if cfg.Banner == "" {
+		result.Latency = time.Since(start)
+		result.Success = true
+		return result
+	}
+
+	re, err := regexp.Compile(cfg.Banner)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid banner regex: %v", err)
+		return result
+	}
+
+	// Read until the banner matches; servers may send it in several packets
+	var banner bytes.Buffer
+	buf := make([]byte, 512)
+	for banner.Len() < maxBannerBytes {
+		n, err := conn.Read(buf)
+		banner.Write(buf[:n])
+		if re.Match(banner.Bytes()) {
+			result.Latency = time.Since(start)
+			result.Success = true
+			return result
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	result.Latency = time.Since(start)
+	result.Error = fmt.Sprintf("banner %q does not match %q", truncate(banner.String(), 200), cfg.Banner)
+	return result
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startBannerServer accepts connections on a random local port and greets each with
+// banner, answering "PONG" to anything it is sent and hanging up when sent nothing.
+func startBannerServer(t *testing.T, banner string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if banner != "" {
+					conn.Write([]byte(banner))
+				}
+				conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+				buf := make([]byte, 64)
+				if n, _ := conn.Read(buf); n > 0 {
+					conn.Write([]byte("PONG\r\n"))
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// closedPort returns a local address nothing listens on.
+func closedPort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestTCPChecker(t *testing.T) {
+	greeting := startBannerServer(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	silent := startBannerServer(t, "")
+	timeout := 2
+
+	tests := []struct {
+		name    string
+		target  string
+		cfg     CheckConfig
+		success bool
+		errPart string
+	}{
+		{name: "connect only", target: greeting, success: true},
+		{name: "expected banner", target: greeting, cfg: CheckConfig{Banner: `^SSH-2\.0-`}, success: true},
+		{name: "wrong banner", target: greeting, cfg: CheckConfig{Banner: `^220 `}, errPart: "does not match"},
+		{name: "send and expect reply", target: silent, cfg: CheckConfig{Send: "PING\r\n", Banner: "PONG"}, success: true},
+		{name: "closed port", target: closedPort(t), errPart: "refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &Endpoint{ID: 1, URL: "tcp://" + tt.target, CheckType: CheckTCP, CheckConfig: tt.cfg, TimeoutSeconds: &timeout}
+			result := tcpChecker{}.Check(context.Background(), ep)
+			if result.Success != tt.success {
+				t.Fatalf("success = %v, want %v (error %q)", result.Success, tt.success, result.Error)
+			}
+			if !strings.Contains(result.Error, tt.errPart) {
+				t.Errorf("error = %q, want it to contain %q", result.Error, tt.errPart)
+			}
+		})
+	}
+}
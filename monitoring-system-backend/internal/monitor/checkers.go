@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Check types. An endpoint's URL is the target of its checker: an http(s) URL,
+// host:port for tcp and grpc, and the name to resolve for dns. A heartbeat is pushed
+// to rather than checked, and its URL is generated.
+const (
+	CheckHTTP      = "http"
+	CheckTCP       = "tcp"
+	CheckDNS       = "dns"
+	CheckGRPC      = "grpc"
+	CheckHeartbeat = "heartbeat"
+)
+
+// Checker performs one check of an endpoint. Every checker feeds the same checks and
+// endpoint_stats pipeline; a non-HTTP checker leaves StatusCode at 0.
+type Checker interface {
+	Check(ctx context.Context, ep *Endpoint) *CheckResult
+}
+
+// CheckConfig holds the settings of the non-HTTP check types; each type reads only
+// its own fields.
+type CheckConfig struct {
+	// tcp: written after connecting, e.g. "PING\r\n" for Redis
+	Send string `json:"send,omitempty"`
+	// tcp: regex the first bytes the server sends must match
+	Banner string `json:"banner,omitempty"`
+
+	// dns: A, AAAA, CNAME, MX, NS or TXT; defaults to A
+	RecordType string `json:"record_type,omitempty"`
+	// dns: values that must all be among the answers
+	Expected []string `json:"expected,omitempty"`
+	// dns: host:port of the resolver to ask instead of the system one
+	Resolver string `json:"resolver,omitempty"`
+
+	// grpc: service name sent in the health check request; empty checks the server
+	Service string `json:"service,omitempty"`
+	// grpc: connect with TLS instead of plaintext
+	TLS bool `json:"tls,omitempty"`
+
+	// heartbeat: how often the job is expected to report success, and how late it may be
+	PeriodSeconds int `json:"period_seconds,omitempty"`
+	GraceSeconds  int `json:"grace_seconds,omitempty"`
+}
+
+// newCheckers returns the checker of every check type. The heartbeat checker reads the
+// pings received through repo.
+func newCheckers(repo *PostgresRepository) map[string]Checker {
+	return map[string]Checker{
+		CheckHTTP:      httpChecker{},
+		CheckTCP:       tcpChecker{},
+		CheckDNS:       dnsChecker{},
+		CheckGRPC:      grpcChecker{},
+		CheckHeartbeat: heartbeatChecker{repo: repo},
+	}
+}
+
+// checkerFor returns the checker of an endpoint; endpoints stored before check types
+// existed are HTTP.
+func (s *Service) checkerFor(ep *Endpoint) (Checker, error) {
+	checkType := ep.CheckType
+	if checkType == "" {
+		checkType = CheckHTTP
+	}
+	c, ok := s.checkers[checkType]
+	if !ok {
+		return nil, fmt.Errorf("unknown check type %q", checkType)
+	}
+	return c, nil
+}
+
+// checkTarget strips an optional scheme such as tcp:// from a non-HTTP target.
+func checkTarget(raw string) string {
+	if i := strings.Index(raw, "://"); i >= 0 {
+		raw = raw[i+3:]
+	}
+	return strings.TrimSuffix(raw, "/")
+}
+
+// ValidateCheckConfig checks the target and settings of a non-HTTP endpoint
+func ValidateCheckConfig(checkType, target string, cfg CheckConfig) error {
+	switch checkType {
+	case CheckTCP, CheckGRPC:
+		if _, _, err := net.SplitHostPort(checkTarget(target)); err != nil {
+			return fmt.Errorf("%s target must be host:port: %w", checkType, err)
+		}
+		if cfg.Banner != "" {
+			if _, err := regexp.Compile(cfg.Banner); err != nil {
+				return fmt.Errorf("invalid banner regex: %w", err)
+			}
+		}
+	case CheckDNS:
+		if checkTarget(target) == "" {
+			return fmt.Errorf("dns target must be a hostname")
+		}
+		if _, ok := dnsLookups[dnsRecordType(cfg)]; !ok {
+			return fmt.Errorf("unsupported dns record_type %q", cfg.RecordType)
+		}
+		if cfg.Resolver != "" {
+			if _, _, err := net.SplitHostPort(cfg.Resolver); err != nil {
+				return fmt.Errorf("resolver must be host:port: %w", err)
+			}
+		}
+	case CheckHeartbeat:
+		if cfg.PeriodSeconds <= 0 {
+			return fmt.Errorf("heartbeat period_seconds must be positive")
+		}
+		if cfg.GraceSeconds < 0 {
+			return fmt.Errorf("heartbeat grace_seconds must not be negative")
+		}
+	default:
+		return fmt.Errorf("check_type must be http, tcp, dns, grpc or heartbeat")
+	}
+	return nil
+}
+
+// httpChecker sends the endpoint's configured request and evaluates the response.
+type httpChecker struct{}
+
+func (httpChecker) Check(ctx context.Context, ep *Endpoint) *CheckResult {
+	return checkHTTP(ctx, ep)
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Dependency sources. Manual dependencies are declared through the API; pipeline ones
+// are seeded from the macro/micro service structure of pipeline units.
+const (
+	DependencyManual   = "manual"
+	DependencyPipeline = "pipeline"
+)
+
+var (
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrDependencyCycle    = errors.New("dependency would create a cycle")
+)
+
+// EndpointDependency says EndpointID needs DependsOnID to work: when DependsOnID is
+// down, EndpointID failing too is expected and not alerted on.
+type EndpointDependency struct {
+	EndpointID  int       `json:"endpoint_id"`
+	DependsOnID int       `json:"depends_on_id"`
+	Source      string    `json:"source"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DependencyNode is an endpoint in the dependency graph.
+type DependencyNode struct {
+	ID          int    `json:"id"`
+	ServiceName string `json:"service_name"`
+	ServerName  string `json:"server_name"`
+	IsDown      bool   `json:"is_down"`
+}
+
+// DependencyGraph is every endpoint and the dependencies between them, for drawing.
+type DependencyGraph struct {
+	Nodes []DependencyNode     `json:"nodes"`
+	Edges []EndpointDependency `json:"edges"`
+}
+
+// DependencySeedResult reports what seeding from pipeline units did.
+type DependencySeedResult struct {
+	Created  []EndpointDependency `json:"created"`
+	Existing int                  `json:"existing"`
+	Cycles   []EndpointDependency `json:"cycles"` // skipped, they would have closed a cycle
+}
+
+// RootCause is the down upstream endpoint an outage is probably caused by.
+type RootCause struct {
+	EndpointID  int
+	ServiceName string
+}
+
+// withheldByRootCause returns the root cause the incident's alerts are held back for,
+// or nil once it recovered or if there is none.
+func (inc *Incident) withheldByRootCause() *RootCause {
+	if inc == nil || inc.RootCauseEndpointID == nil || inc.RootCauseRecoveredAt != nil {
+		return nil
+	}
+	rc := &RootCause{EndpointID: *inc.RootCauseEndpointID}
+	if inc.RootCauseServiceName != nil {
+		rc.ServiceName = *inc.RootCauseServiceName
+	}
+	return rc
+}
+
+func (s *Service) GetDependencyGraph(ctx context.Context) (*DependencyGraph, error) {
+	return s.dbRepo.GetDependencyGraph(ctx)
+}
+
+// AddDependency makes an endpoint depend on another. Declaring a dependency that was
+// seeded from a pipeline marks it manual.
+func (s *Service) AddDependency(ctx context.Context, endpointID, dependsOnID int, actor Actor) (*EndpointDependency, error) {
+	if endpointID == dependsOnID {
+		return nil, fmt.Errorf("an endpoint can't depend on itself")
+	}
+	dep, _, err := s.dbRepo.AddDependency(ctx, EndpointDependency{
+		EndpointID:  endpointID,
+		DependsOnID: dependsOnID,
+		Source:      DependencyManual,
+		CreatedBy:   actor.Username,
+	})
+	return dep, err
+}
+
+func (s *Service) RemoveDependency(ctx context.Context, endpointID, dependsOnID int) error {
+	return s.dbRepo.RemoveDependency(ctx, endpointID, dependsOnID)
+}
+
+// SeedDependenciesFromPipelines adds a dependency from every endpoint of a pipeline
+// unit's macro service to every endpoint of its micro services, matching endpoints to
+// services by GitLab URL. Existing dependencies are left alone.
+func (s *Service) SeedDependenciesFromPipelines(ctx context.Context, actor Actor) (*DependencySeedResult, error) {
+	candidates, err := s.dbRepo.ListPipelineDependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DependencySeedResult{Created: []EndpointDependency{}, Cycles: []EndpointDependency{}}
+	for _, dep := range candidates {
+		dep.Source = DependencyPipeline
+		dep.CreatedBy = actor.Username
+
+		created, isNew, err := s.dbRepo.AddDependency(ctx, dep)
+		switch {
+		case errors.Is(err, ErrDependencyCycle):
+			result.Cycles = append(result.Cycles, dep)
+		case err != nil:
+			return nil, err
+		case isNew:
+			result.Created = append(result.Created, *created)
+		default:
+			result.Existing++
+		}
+	}
+	return result, nil
+}
+
+// releaseWithheldAlert sends the DOWN alert of an endpoint that is still down after the
+// root cause its alert was held back for recovered: its outage has a cause of its own.
+func (s *Service) releaseWithheldAlert(ctx context.Context, ep Endpoint, sup suppression) error {
+	if sup.Flapping {
+		// The alert goes out once the endpoint settles
+		return nil
+	}
+	released, err := s.dbRepo.MarkRootCauseRecovered(ctx, ep.ID)
+	if err != nil || !released {
+		return err
+	}
+	log.Printf("Root cause of the outage of %s recovered but it is still down, sending its alert", ep.ServiceName)
+	s.alertSettledDown(ctx, ep)
+	return nil
+}
@@ -0,0 +1,189 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultConfirmRetries is how many times a failed check is repeated before the
+	// failure is recorded.
+	defaultConfirmRetries = 2
+
+	// defaultConfirmBackoff is the wait before the first confirmation retry; it doubles
+	// for each one after that.
+	defaultConfirmBackoff = time.Second
+
+	// defaultFlapWindow and defaultFlapThreshold: an endpoint whose up/down state
+	// changes more than the threshold within the window is flapping.
+	defaultFlapWindow    = 30 * time.Minute
+	defaultFlapThreshold = 5
+)
+
+// confirmRetries reads CHECK_CONFIRM_RETRIES; 0 records the first failure as is.
+func confirmRetries() int {
+	if v, err := strconv.Atoi(os.Getenv("CHECK_CONFIRM_RETRIES")); err == nil && v >= 0 {
+		return v
+	}
+	return defaultConfirmRetries
+}
+
+// flapThreshold reads FLAP_THRESHOLD, the number of state changes within
+// FLAP_DETECTION_WINDOW above which an endpoint is flapping.
+func flapThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("FLAP_THRESHOLD")); err == nil && v > 0 {
+		return v
+	}
+	return defaultFlapThreshold
+}
+
+// confirmBudget is how long a scheduled check may take including every confirmation
+// retry and the backoff between them.
+func (ep *Endpoint) confirmBudget() time.Duration {
+	retries := ep.confirmRetryCount()
+	backoff := envSeconds("CHECK_CONFIRM_BACKOFF", defaultConfirmBackoff)
+
+	budget := ep.checkTimeout()
+	for i := 0; i < retries; i++ {
+		budget += backoff<<i + ep.checkTimeout()
+	}
+	return budget
+}
+
+// executeConfirmedCheck runs a check and, if it fails, re-runs it up to
+// CHECK_CONFIRM_RETRIES times with exponential backoff, so a single timed-out request
+// doesn't count as a failure. The first success wins; otherwise the last failure is
+// returned.
+func (s *Service) executeConfirmedCheck(ctx context.Context, ep *Endpoint) *CheckResult {
+	retries := ep.confirmRetryCount()
+	backoff := envSeconds("CHECK_CONFIRM_BACKOFF", defaultConfirmBackoff)
+
+	result := s.executeCheck(ctx, ep)
+	result.Attempts = 1
+	for attempt := 0; !result.Success && attempt < retries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(backoff << attempt):
+		}
+
+		attempts := result.Attempts + 1
+		result = s.executeCheck(ctx, ep)
+		result.Attempts = attempts
+	}
+	return result
+}
+
+// flapOwed is the alert a flapping endpoint owes its subscribers once it settles, ""
+// for none.
+type flapOwed AlertKind
+
+// withhold returns what is owed after another state change's alert was withheld while
+// flapping. Withheld alerts alternate between DOWN and RECOVERED, so one that undoes
+// the alert still owed leaves subscribers with the state they last heard of.
+func (owed flapOwed) withhold(kind AlertKind) flapOwed {
+	if owed != "" && owed != flapOwed(kind) {
+		return ""
+	}
+	return flapOwed(kind)
+}
+
+// settle returns the alert to send when the endpoint stops flapping in the given
+// state, "" if subscribers already know it.
+func (owed flapOwed) settle(isDown bool) AlertKind {
+	switch {
+	case owed == flapOwed(AlertDown) && isDown:
+		return AlertDown
+	case owed == flapOwed(AlertRecovered) && !isDown:
+		return AlertRecovered
+	}
+	return ""
+}
+
+// updateFlapState recomputes whether an endpoint is flapping from its recent checks
+// and reports whether it is flapping now, with the alert owed once it settles. An
+// endpoint that stops flapping gets the alert for the state it settled in if the
+// alert for that state was withheld.
+func (s *Service) updateFlapState(ctx context.Context, ep Endpoint, isDown bool) (bool, flapOwed) {
+	window := envSeconds("FLAP_DETECTION_WINDOW", defaultFlapWindow)
+	flapping, wasFlapping, owed, err := s.dbRepo.UpdateFlapState(ctx, ep.ID, window, flapThreshold())
+	if err != nil {
+		log.Printf("Failed to update flap state for endpoint %d: %v", ep.ID, err)
+		return false, ""
+	}
+
+	switch {
+	case flapping && !wasFlapping:
+		log.Printf("Endpoint %d (%s) is flapping; alerts are suppressed until it settles", ep.ID, ep.ServiceName)
+	case !flapping && wasFlapping:
+		log.Printf("Endpoint %d (%s) has stopped flapping", ep.ID, ep.ServiceName)
+		switch owed.settle(isDown) {
+		case AlertDown:
+			s.alertSettledDown(ctx, ep)
+		case AlertRecovered:
+			s.alertSettledUp(ctx, ep)
+		}
+	}
+	return flapping, owed
+}
+
+// alertSettledDown sends the DOWN alert for an endpoint that settled in the down state.
+func (s *Service) alertSettledDown(ctx context.Context, ep Endpoint) {
+	sup, err := s.activeSuppression(ctx, ep)
+	if err != nil {
+		log.Printf("Failed to load maintenance windows and silences for endpoint %d: %v", ep.ID, err)
+	}
+
+	incident, err := s.dbRepo.GetOpenIncident(ctx, ep.ID)
+	if err != nil {
+		log.Printf("Failed to load open incident for endpoint %d: %v", ep.ID, err)
+	}
+
+	alert := Alert{
+		Kind:       AlertDown,
+		Endpoint:   ep,
+		Incident:   incident,
+		OccurredAt: time.Now(),
+		DownSince:  time.Now(),
+	}
+	if incident != nil {
+		sup.Upstream = incident.withheldByRootCause()
+		alert.DownSince = incident.StartedAt
+		alert.Error = incident.Error
+		alert.Failures = incident.FailedCheckCount
+	}
+	s.sendAlert(ctx, sup, alert)
+}
+
+// alertSettledUp sends the RECOVERED alert for an endpoint that settled in the up state
+// after its DOWN alert went out, using its last resolved incident.
+func (s *Service) alertSettledUp(ctx context.Context, ep Endpoint) {
+	sup, err := s.activeSuppression(ctx, ep)
+	if err != nil {
+		log.Printf("Failed to load maintenance windows and silences for endpoint %d: %v", ep.ID, err)
+	}
+
+	alert := Alert{
+		Kind:       AlertRecovered,
+		Endpoint:   ep,
+		OccurredAt: time.Now(),
+		DownSince:  time.Now(),
+	}
+	incidents, err := s.dbRepo.ListIncidents(ctx, IncidentFilter{Status: IncidentResolved, EndpointID: ep.ID, Limit: 1})
+	if err != nil {
+		log.Printf("Failed to load the last incident for endpoint %d: %v", ep.ID, err)
+	}
+	if len(incidents) > 0 {
+		incident := &incidents[0]
+		sup.Upstream = incident.withheldByRootCause()
+		alert.Incident = incident
+		alert.DownSince = incident.StartedAt
+		if incident.ResolvedAt != nil {
+			alert.OccurredAt = *incident.ResolvedAt
+		}
+	}
+	s.sendAlert(ctx, sup, alert)
+}
@@ -0,0 +1,33 @@
+package monitor
+
+import "testing"
+
+func TestFlapOwed(t *testing.T) {
+	tests := []struct {
+		name     string
+		withheld []AlertKind // alerts of the state changes while flapping, in order
+		isDown   bool        // state the endpoint settles in
+		want     AlertKind
+	}{
+		// DOWN went out before it started flapping
+		{name: "down, flap, down", withheld: []AlertKind{AlertRecovered, AlertDown}, isDown: true, want: ""},
+		{name: "down, flap, up", withheld: []AlertKind{AlertRecovered, AlertDown, AlertRecovered}, isDown: false, want: AlertRecovered},
+		{name: "down, flap without state change, down", isDown: true, want: ""},
+		// Up when it started flapping
+		{name: "up, flap, down", withheld: []AlertKind{AlertDown, AlertRecovered, AlertDown}, isDown: true, want: AlertDown},
+		{name: "up, flap, up", withheld: []AlertKind{AlertDown, AlertRecovered}, isDown: false, want: ""},
+		{name: "up, flap without state change, up", isDown: false, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var owed flapOwed
+			for _, kind := range tt.withheld {
+				owed = owed.withhold(kind)
+			}
+			if got := owed.settle(tt.isDown); got != tt.want {
+				t.Errorf("settle() = %q, want %q (owed %q)", got, tt.want, owed)
+			}
+		})
+	}
+}
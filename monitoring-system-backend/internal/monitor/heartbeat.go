@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Heartbeat ping kinds, sent by the job itself
+const (
+	PingStart   = "start"
+	PingSuccess = "success"
+	PingFail    = "fail"
+)
+
+// heartbeatScheme prefixes the generated URL of a heartbeat endpoint; the rest is the
+// token the job pings with.
+const heartbeatScheme = "heartbeat://"
+
+// maxPingMessage caps how much of a ping body is kept, e.g. the tail of a job's log.
+const maxPingMessage = 1000
+
+// defaultHeartbeatPings is how many recent pings GetHeartbeat returns.
+const defaultHeartbeatPings = 50
+
+var ErrHeartbeatNotFound = errors.New("heartbeat not found")
+
+// HeartbeatState is what the heartbeat checker evaluates: when monitoring began and
+// the last ping of each kind.
+type HeartbeatState struct {
+	EndpointID         int        `json:"endpoint_id"`
+	MonitoringSince    time.Time  `json:"monitoring_since"`
+	LastStartAt        *time.Time `json:"last_start_at"`
+	LastSuccessAt      *time.Time `json:"last_success_at"`
+	LastFailureAt      *time.Time `json:"last_failure_at"`
+	LastFailureMessage *string    `json:"last_failure_message"`
+	LastDurationMs     *int64     `json:"last_duration_ms"`
+}
+
+// HeartbeatPing is one ping received from a job. DurationMs is set on a success or
+// fail ping that follows a start ping.
+type HeartbeatPing struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	DurationMs *int64    `json:"duration_ms"`
+	Message    *string   `json:"message"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// HeartbeatDetail is a heartbeat endpoint's ping path, state and recent pings.
+type HeartbeatDetail struct {
+	HeartbeatState
+	PingPath      string          `json:"ping_path"`
+	PeriodSeconds int             `json:"period_seconds"`
+	GraceSeconds  int             `json:"grace_seconds"`
+	DueBy         time.Time       `json:"due_by"`
+	Pings         []HeartbeatPing `json:"pings"`
+}
+
+// newHeartbeatURL generates the URL of a new heartbeat endpoint
+func newHeartbeatURL() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate heartbeat token: %w", err)
+	}
+	return heartbeatScheme + hex.EncodeToString(b), nil
+}
+
+// HeartbeatPingPath is where a job sends its pings; append /start or /fail for
+// those kinds.
+func HeartbeatPingPath(ep *Endpoint) string {
+	return "/api/v1/heartbeats/" + strings.TrimPrefix(ep.URL, heartbeatScheme)
+}
+
+// dueBy is the latest time the next success ping may arrive before the heartbeat is
+// down. Before the first success it counts from when monitoring began.
+func (st *HeartbeatState) dueBy(cfg CheckConfig) time.Time {
+	since := st.MonitoringSince
+	if st.LastSuccessAt != nil {
+		since = *st.LastSuccessAt
+	}
+	return since.Add(time.Duration(cfg.PeriodSeconds+cfg.GraceSeconds) * time.Second)
+}
+
+// evaluate returns why the heartbeat is down at now, or nil if it is up. A fail ping
+// keeps it down until the next success ping.
+func (st *HeartbeatState) evaluate(cfg CheckConfig, now time.Time) error {
+	if st.LastFailureAt != nil && (st.LastSuccessAt == nil || st.LastFailureAt.After(*st.LastSuccessAt)) {
+		msg := fmt.Sprintf("job reported failure at %s", st.LastFailureAt.Format(time.RFC3339))
+		if st.LastFailureMessage != nil && *st.LastFailureMessage != "" {
+			msg += ": " + *st.LastFailureMessage
+		}
+		return errors.New(msg)
+	}
+
+	if now.After(st.dueBy(cfg)) {
+		if st.LastSuccessAt == nil {
+			return fmt.Errorf("no success ping received since monitoring began at %s", st.MonitoringSince.Format(time.RFC3339))
+		}
+		return fmt.Errorf("no success ping since %s, expected every %ds plus %ds grace",
+			st.LastSuccessAt.Format(time.RFC3339), cfg.PeriodSeconds, cfg.GraceSeconds)
+	}
+	return nil
+}
+
+// heartbeatChecker turns the pings received so far into a check result, so a missed
+// or failed job goes through the same stats, alerts and incidents as any endpoint.
+type heartbeatChecker struct {
+	repo *PostgresRepository
+}
+
+func (c heartbeatChecker) Check(ctx context.Context, ep *Endpoint) *CheckResult {
+	result := &CheckResult{EndpointID: ep.ID}
+
+	state, err := c.repo.GetHeartbeatState(ctx, ep.ID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := state.evaluate(ep.CheckConfig, time.Now()); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// confirmRetryCount is CHECK_CONFIRM_RETRIES for an endpoint. A heartbeat check only
+// reads pings already received, so repeating it straight away can't change the outcome.
+func (ep *Endpoint) confirmRetryCount() int {
+	if ep.CheckType == CheckHeartbeat {
+		return 0
+	}
+	return confirmRetries()
+}
+
+// assignHeartbeatURL gives a heartbeat endpoint its ping URL, keeping the one it
+// already has so jobs don't have to be reconfigured on every edit.
+func assignHeartbeatURL(ep *Endpoint, current *Endpoint) error {
+	if ep.CheckType != CheckHeartbeat {
+		return nil
+	}
+	if current != nil && strings.HasPrefix(current.URL, heartbeatScheme) {
+		ep.URL = current.URL
+		return nil
+	}
+	url, err := newHeartbeatURL()
+	if err != nil {
+		return err
+	}
+	ep.URL = url
+	return nil
+}
+
+// PingHeartbeat records a start, success or fail ping from a job. The endpoint's next
+// scheduled check picks it up.
+func (s *Service) PingHeartbeat(ctx context.Context, token, kind, message string) error {
+	ep, err := s.dbRepo.GetEndpointByURL(ctx, heartbeatScheme+strings.ToLower(token))
+	if err != nil {
+		return err
+	}
+	if ep == nil || ep.CheckType != CheckHeartbeat {
+		return ErrHeartbeatNotFound
+	}
+
+	message = strings.TrimSpace(message)
+	if len(message) > maxPingMessage {
+		message = message[len(message)-maxPingMessage:]
+	}
+
+	return s.dbRepo.RecordHeartbeatPing(ctx, ep.ID, kind, message)
+}
+
+// GetHeartbeat returns where a heartbeat endpoint is pinged, its state and recent pings
+func (s *Service) GetHeartbeat(ctx context.Context, endpointID int) (*HeartbeatDetail, error) {
+	ep, err := s.dbRepo.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if ep.CheckType != CheckHeartbeat {
+		return nil, ErrHeartbeatNotFound
+	}
+
+	state, err := s.dbRepo.GetHeartbeatState(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	pings, err := s.dbRepo.ListHeartbeatPings(ctx, endpointID, defaultHeartbeatPings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HeartbeatDetail{
+		HeartbeatState: *state,
+		PingPath:       HeartbeatPingPath(ep),
+		PeriodSeconds:  ep.CheckConfig.PeriodSeconds,
+		GraceSeconds:   ep.CheckConfig.GraceSeconds,
+		DueBy:          state.dueBy(ep.CheckConfig),
+		Pings:          pings,
+	}, nil
+}
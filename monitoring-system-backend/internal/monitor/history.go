@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+
+	// maxSeriesBuckets keeps a single chart request from scanning months of raw checks
+	maxSeriesBuckets = 2000
+)
+
+// seriesBuckets maps the accepted bucket names to their width.
+var seriesBuckets = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+}
+
+// ValidateCheckHistoryQuery fills in the default page size and rejects filters the
+// repository can't serve.
+func ValidateCheckHistoryQuery(q *CheckHistoryQuery) error {
+	if q.Limit == 0 {
+		q.Limit = defaultHistoryLimit
+	}
+	if q.Limit < 0 || q.Limit > maxHistoryLimit {
+		return fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+	}
+	switch q.Status {
+	case "", "up", "down":
+	default:
+		return fmt.Errorf("status must be up or down")
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	if q.Cursor != "" {
+		if _, _, err := decodeCheckCursor(q.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveSeriesRange validates a downsampling request and defaults the window to the
+// last hour of minutes or the last day of hours.
+func ResolveSeriesRange(bucket string, from, to *time.Time) (time.Time, time.Time, error) {
+	width, ok := seriesBuckets[bucket]
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("bucket must be minute or hour")
+	}
+
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-60 * width)
+	if bucket == "hour" {
+		start = end.Add(-24 * width)
+	}
+	if from != nil {
+		start = *from
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	if end.Sub(start)/width > maxSeriesBuckets {
+		return time.Time{}, time.Time{}, fmt.Errorf("range too large for %s buckets (max %d buckets)", bucket, maxSeriesBuckets)
+	}
+	return start, end, nil
+}
+
+// GetCheckHistory returns a page of raw check results for an endpoint
+func (s *Service) GetCheckHistory(ctx context.Context, endpointID int, q CheckHistoryQuery) (*CheckHistoryPage, error) {
+	if err := ValidateCheckHistoryQuery(&q); err != nil {
+		return nil, err
+	}
+	return s.dbRepo.GetCheckHistory(ctx, endpointID, q)
+}
+
+// GetCheckSeries returns an endpoint's checks downsampled into minute or hour buckets
+func (s *Service) GetCheckSeries(ctx context.Context, endpointID int, bucket string, from, to time.Time) ([]CheckBucket, error) {
+	return s.dbRepo.GetCheckSeries(ctx, endpointID, bucket, from, to)
+}
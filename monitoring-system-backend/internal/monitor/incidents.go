@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Incident statuses. An incident opens when an endpoint is marked down and resolves
+// when it recovers; acknowledging it in between moves it to acknowledged.
+const (
+	IncidentOpen         = "open"
+	IncidentAcknowledged = "acknowledged"
+	IncidentResolved     = "resolved"
+)
+
+var ErrIncidentNotFound = errors.New("incident not found")
+
+// Incident is one outage of an endpoint, from the first failed check of the streak
+// that marked it down until it recovered.
+type Incident struct {
+	ID               int             `json:"id"`
+	EndpointID       int             `json:"endpoint_id"`
+	ServiceName      string          `json:"service_name"`
+	ServerName       string          `json:"server_name"`
+	Status           string          `json:"status"`
+	Error            string          `json:"error"`
+	StartedAt        time.Time       `json:"started_at"`
+	AcknowledgedAt   *time.Time      `json:"acknowledged_at"`
+	AcknowledgedByID *uuid.UUID      `json:"acknowledged_by_id"`
+	AcknowledgedBy   *string         `json:"acknowledged_by"`
+	AssignedToID     *uuid.UUID      `json:"assigned_to_id"`
+	AssignedTo       *string         `json:"assigned_to"`
+	AssignedAt       *time.Time      `json:"assigned_at"`
+	ResolvedAt       *time.Time      `json:"resolved_at"`
+	DurationSeconds  *int64          `json:"duration_seconds"` // nil while unresolved
+	FailedCheckCount int             `json:"failed_check_count"`
+	Checks           []IncidentCheck `json:"checks,omitempty"`
+
+	// The down upstream endpoint the outage was probably caused by, see FindRootCause.
+	// Alerts are held back until it recovers; RootCauseRecoveredAt is set if this
+	// endpoint was still down then.
+	RootCauseEndpointID  *int       `json:"root_cause_endpoint_id"`
+	RootCauseServiceName *string    `json:"root_cause_service_name"`
+	RootCauseIncidentID  *int       `json:"root_cause_incident_id"`
+	RootCauseRecoveredAt *time.Time `json:"root_cause_recovered_at"`
+}
+
+// IncidentCheck is a failed check attached to an incident. The error is copied so it
+// survives raw check retention.
+type IncidentCheck struct {
+	CheckID    int64     `json:"check_id"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// IncidentFilter narrows the incident list. Zero values mean no filter.
+type IncidentFilter struct {
+	Status     string
+	EndpointID int
+	ServerName string
+	Limit      int
+	Offset     int
+}
+
+// IncidentMetrics summarises incident response for one endpoint or server.
+// MTTA and MTTR are measured from the incident start and are nil without data.
+type IncidentMetrics struct {
+	EndpointID   *int     `json:"endpoint_id,omitempty"`
+	ServiceName  string   `json:"service_name,omitempty"`
+	ServerName   string   `json:"server_name"`
+	Incidents    int      `json:"incidents"`
+	Acknowledged int      `json:"acknowledged"`
+	Resolved     int      `json:"resolved"`
+	MTTASeconds  *float64 `json:"mtta_seconds"`
+	MTTRSeconds  *float64 `json:"mttr_seconds"`
+}
+
+// IncidentMetricsReport holds MTTA/MTTR grouped per endpoint and per server_name.
+type IncidentMetricsReport struct {
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	ByEndpoint []IncidentMetrics `json:"by_endpoint"`
+	ByServer   []IncidentMetrics `json:"by_server"`
+}
+
+// ListIncidents returns incidents newest first
+func (s *Service) ListIncidents(ctx context.Context, filter IncidentFilter) ([]Incident, error) {
+	switch filter.Status {
+	case "", IncidentOpen, IncidentAcknowledged, IncidentResolved:
+	default:
+		return nil, fmt.Errorf("status must be open, acknowledged or resolved")
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.dbRepo.ListIncidents(ctx, filter)
+}
+
+// GetIncident returns an incident with its failed checks
+func (s *Service) GetIncident(ctx context.Context, id int) (*Incident, error) {
+	return s.dbRepo.GetIncident(ctx, id)
+}
+
+// AcknowledgeIncident records that the actor is looking into the incident
+func (s *Service) AcknowledgeIncident(ctx context.Context, id int, actor Actor) (*Incident, error) {
+	if actor.UserID == nil {
+		return nil, fmt.Errorf("acknowledging an incident requires a signed-in user")
+	}
+	return s.dbRepo.AcknowledgeIncident(ctx, id, *actor.UserID, actor.Username)
+}
+
+// AssignIncident hands the incident to a user, who must exist
+func (s *Service) AssignIncident(ctx context.Context, id int, userID uuid.UUID) (*Incident, error) {
+	if s.userRepo == nil {
+		return nil, fmt.Errorf("user lookup is not configured")
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+	return s.dbRepo.AssignIncident(ctx, id, user.ID, user.Username)
+}
+
+// GetIncidentMetrics returns MTTA/MTTR for incidents started within [from, to)
+func (s *Service) GetIncidentMetrics(ctx context.Context, from, to time.Time) (*IncidentMetricsReport, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	byEndpoint, byServer, err := s.dbRepo.GetIncidentMetrics(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &IncidentMetricsReport{From: from, To: to, ByEndpoint: byEndpoint, ByServer: byServer}, nil
+}
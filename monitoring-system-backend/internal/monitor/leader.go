@@ -0,0 +1,170 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schedulerLockKey is the Postgres advisory lock that only the leading replica holds.
+const schedulerLockKey int64 = 0x6465766f70746963 // "devoptic"
+
+// defaultLeaderRetryInterval is how often a standby replica tries to take over.
+const defaultLeaderRetryInterval = 10 * time.Second
+
+// leaderPingsPerRetry is how many times the leader pings its lock connection per retry
+// interval. A leader whose session is gone finds out well before a standby's next
+// attempt can take the lock, so two replicas don't both dispatch checks meanwhile.
+const leaderPingsPerRetry = 5
+
+// ReplicaID identifies this backend process in leader election and scheduler status.
+func ReplicaID() string {
+	if id := os.Getenv("REPLICA_ID"); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// LeaderElector makes sure only one replica runs checks at a time. The leader holds a
+// session-level advisory lock on a dedicated connection; if that replica dies its
+// connection closes, Postgres releases the lock and a standby takes over on its next retry.
+type LeaderElector struct {
+	pool         *pgxpool.Pool
+	key          int64
+	interval     time.Duration
+	pingInterval time.Duration
+
+	leader atomic.Bool
+
+	mu          sync.Mutex
+	conn        *pgxpool.Conn
+	lastAttempt time.Time
+}
+
+func NewLeaderElector(pool *pgxpool.Pool) *LeaderElector {
+	interval := envSeconds("SCHEDULER_LEADER_RETRY_INTERVAL", defaultLeaderRetryInterval)
+	return &LeaderElector{
+		pool:         pool,
+		key:          schedulerLockKey,
+		interval:     interval,
+		pingInterval: interval / leaderPingsPerRetry,
+	}
+}
+
+// IsLeader reports whether this replica currently holds the scheduler lock.
+func (le *LeaderElector) IsLeader() bool {
+	return le.leader.Load()
+}
+
+// Run campaigns for leadership until ctx is cancelled, then releases the lock. It ticks
+// at the leader's ping interval; a standby only tries to take over every interval.
+func (le *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(le.pingInterval)
+	defer ticker.Stop()
+	defer le.resign()
+
+	le.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			le.tick(ctx)
+		}
+	}
+}
+
+func (le *LeaderElector) tick(ctx context.Context) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	if le.conn != nil {
+		// Still leader as long as the connection holding the lock is alive. The ping
+		// must answer within the ping interval, so a hung session counts as lost too.
+		pingCtx, cancel := context.WithTimeout(ctx, le.pingInterval)
+		defer cancel()
+		if err := le.conn.Ping(pingCtx); err != nil {
+			log.Println("Scheduler leader lost its lock connection:", err)
+			le.dropConn()
+		}
+		return
+	}
+
+	if time.Since(le.lastAttempt) < le.interval {
+		return
+	}
+	le.lastAttempt = time.Now()
+
+	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	conn, err := le.pool.Acquire(checkCtx)
+	if err != nil {
+		log.Println("Scheduler leader election failed to acquire connection:", err)
+		return
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(checkCtx, `SELECT pg_try_advisory_lock($1)`, le.key).Scan(&acquired); err != nil {
+		log.Println("Scheduler leader election failed:", err)
+		conn.Release()
+		return
+	}
+
+	if !acquired {
+		conn.Release()
+		return
+	}
+
+	le.conn = conn
+	le.leader.Store(true)
+	log.Printf("Replica %s is now the scheduler leader", ReplicaID())
+}
+
+// dropConn closes the lock connection instead of returning it to the pool, so a
+// half-broken session can't keep holding the lock. Callers hold le.mu.
+func (le *LeaderElector) dropConn() {
+	le.leader.Store(false)
+	if le.conn == nil {
+		return
+	}
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	le.conn.Conn().Close(closeCtx)
+	le.conn.Release()
+	le.conn = nil
+}
+
+// resign gives up leadership so a standby replica can take over immediately.
+func (le *LeaderElector) resign() {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	if le.conn == nil {
+		return
+	}
+
+	unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := le.conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, le.key); err != nil {
+		log.Println("Failed to release scheduler leader lock:", err)
+		le.dropConn()
+		return
+	}
+
+	le.leader.Store(false)
+	le.conn.Release()
+	le.conn = nil
+	log.Printf("Replica %s resigned as scheduler leader", ReplicaID())
+}
@@ -0,0 +1,252 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// Scopes a maintenance window or silence can cover
+const (
+	ScopeEndpoint = "endpoint" // scope_value is the endpoint id
+	ScopeTag      = "tag"      // scope_value is an endpoint tag
+	ScopeServer   = "server"   // scope_value is a server_name
+)
+
+// maxSilenceDuration caps how long an ad-hoc silence can mute alerts.
+const maxSilenceDuration = 30 * 24 * time.Hour
+
+var (
+	ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+	ErrSilenceNotFound           = errors.New("silence not found")
+)
+
+// MaintenanceWindow is planned downtime. A one-off window runs from StartsAt to EndsAt;
+// a recurring window opens on every Schedule (a 5-field cron expression evaluated in
+// Timezone) and lasts DurationMinutes, optionally bounded by StartsAt/EndsAt. Checks
+// keep running during a window, but alerts are suppressed and the checks don't count
+// towards uptime.
+type MaintenanceWindow struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	ScopeType       string     `json:"scope_type"`
+	ScopeValue      string     `json:"scope_value"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	Schedule        string     `json:"schedule"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Timezone        string     `json:"timezone"`
+	CreatedByID     *uuid.UUID `json:"created_by_id"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Active          bool       `json:"active"`
+}
+
+// Silence mutes an endpoint's alerts until it expires. Unlike a maintenance window it
+// doesn't change uptime or incidents, only whether anyone is notified.
+type Silence struct {
+	ID          int        `json:"id"`
+	ScopeType   string     `json:"scope_type"`
+	ScopeValue  string     `json:"scope_value"`
+	Reason      string     `json:"reason"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedByID *uuid.UUID `json:"created_by_id"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// suppression is what muted a check: a maintenance window also takes the check out of
+// uptime, a silence or flapping only mutes alerts.
+type suppression struct {
+	Maintenance *MaintenanceWindow
+	Silence     *Silence
+	Flapping    bool
+	FlapOwed    flapOwed
+	Upstream    *RootCause // a dependency the endpoint's outage is blamed on is down
+}
+
+func validateScope(scopeType, scopeValue string) error {
+	switch scopeType {
+	case ScopeEndpoint:
+		if _, err := strconv.Atoi(scopeValue); err != nil {
+			return fmt.Errorf("scope_value must be an endpoint id for endpoint scope")
+		}
+	case ScopeTag, ScopeServer:
+		if strings.TrimSpace(scopeValue) == "" {
+			return fmt.Errorf("scope_value is required for %s scope", scopeType)
+		}
+	default:
+		return fmt.Errorf("scope_type must be endpoint, tag or server")
+	}
+	return nil
+}
+
+// ValidateMaintenanceWindow checks the scope and that the window is either one-off or
+// a parseable recurring schedule
+func ValidateMaintenanceWindow(w *MaintenanceWindow) error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := validateScope(w.ScopeType, w.ScopeValue); err != nil {
+		return err
+	}
+	if w.Timezone == "" {
+		w.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", w.Timezone)
+	}
+	if w.StartsAt != nil && w.EndsAt != nil && !w.EndsAt.After(*w.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+
+	if w.Schedule == "" {
+		if w.StartsAt == nil || w.EndsAt == nil {
+			return fmt.Errorf("a one-off window needs starts_at and ends_at")
+		}
+		return nil
+	}
+
+	if _, err := cron.ParseStandard(w.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if w.DurationMinutes <= 0 {
+		return fmt.Errorf("a recurring window needs a positive duration_minutes")
+	}
+	return nil
+}
+
+// ActiveAt reports whether the window covers t. A recurring window is active when an
+// occurrence started less than DurationMinutes before t.
+func (w *MaintenanceWindow) ActiveAt(t time.Time) bool {
+	if w.StartsAt != nil && t.Before(*w.StartsAt) {
+		return false
+	}
+	if w.EndsAt != nil && !t.Before(*w.EndsAt) {
+		return false
+	}
+	if w.Schedule == "" {
+		return w.StartsAt != nil && w.EndsAt != nil
+	}
+
+	sched, err := cron.ParseStandard(w.Schedule)
+	if err != nil {
+		return false
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	duration := time.Duration(w.DurationMinutes) * time.Minute
+	// The first occurrence after t-duration is the latest one that can still cover t
+	next := sched.Next(t.In(loc).Add(-duration))
+	return !next.After(t)
+}
+
+// ValidateSilence checks the scope and that the expiry is in the future and bounded
+func ValidateSilence(sl *Silence) error {
+	if err := validateScope(sl.ScopeType, sl.ScopeValue); err != nil {
+		return err
+	}
+	now := time.Now()
+	if !sl.ExpiresAt.After(now) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	if sl.ExpiresAt.Sub(now) > maxSilenceDuration {
+		return fmt.Errorf("a silence can last at most %d days", int(maxSilenceDuration.Hours()/24))
+	}
+	return nil
+}
+
+// activeSuppression finds the maintenance window or silence covering an endpoint right now
+func (s *Service) activeSuppression(ctx context.Context, ep Endpoint) (suppression, error) {
+	var sup suppression
+
+	windows, err := s.dbRepo.ListMaintenanceWindowsFor(ctx, ep.ID)
+	if err != nil {
+		return sup, err
+	}
+	now := time.Now()
+	for i := range windows {
+		if windows[i].ActiveAt(now) {
+			sup.Maintenance = &windows[i]
+			break
+		}
+	}
+
+	silence, err := s.dbRepo.GetActiveSilence(ctx, ep.ID)
+	if err != nil {
+		return sup, err
+	}
+	sup.Silence = silence
+	return sup, nil
+}
+
+func (s *Service) CreateMaintenanceWindow(ctx context.Context, w MaintenanceWindow, actor Actor) (*MaintenanceWindow, error) {
+	if err := ValidateMaintenanceWindow(&w); err != nil {
+		return nil, err
+	}
+	w.CreatedBy = actor.Username
+	w.CreatedByID = actor.UserID
+
+	created, err := s.dbRepo.CreateMaintenanceWindow(ctx, &w)
+	if err != nil {
+		return nil, err
+	}
+	created.Active = created.ActiveAt(time.Now())
+	return created, nil
+}
+
+func (s *Service) UpdateMaintenanceWindow(ctx context.Context, w MaintenanceWindow) (*MaintenanceWindow, error) {
+	if err := ValidateMaintenanceWindow(&w); err != nil {
+		return nil, err
+	}
+	updated, err := s.dbRepo.UpdateMaintenanceWindow(ctx, &w)
+	if err != nil {
+		return nil, err
+	}
+	updated.Active = updated.ActiveAt(time.Now())
+	return updated, nil
+}
+
+func (s *Service) DeleteMaintenanceWindow(ctx context.Context, id int) error {
+	return s.dbRepo.DeleteMaintenanceWindow(ctx, id)
+}
+
+// ListMaintenanceWindows returns every window, flagging the ones in effect right now
+func (s *Service) ListMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	windows, err := s.dbRepo.ListMaintenanceWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range windows {
+		windows[i].Active = windows[i].ActiveAt(now)
+	}
+	return windows, nil
+}
+
+func (s *Service) CreateSilence(ctx context.Context, sl Silence, actor Actor) (*Silence, error) {
+	if err := ValidateSilence(&sl); err != nil {
+		return nil, err
+	}
+	sl.CreatedBy = actor.Username
+	sl.CreatedByID = actor.UserID
+	return s.dbRepo.CreateSilence(ctx, &sl)
+}
+
+// ExpireSilence ends a silence now, keeping it for the record
+func (s *Service) ExpireSilence(ctx context.Context, id int) (*Silence, error) {
+	return s.dbRepo.ExpireSilence(ctx, id)
+}
+
+// ListSilences returns active silences, or every silence when includeExpired is set
+func (s *Service) ListSilences(ctx context.Context, includeExpired bool) ([]Silence, error) {
+	return s.dbRepo.ListSilences(ctx, includeExpired)
+}
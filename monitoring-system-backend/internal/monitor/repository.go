@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"time"
 )
 
 type Repository interface {
@@ -10,9 +11,10 @@ type Repository interface {
 	GetEndpointByID(ctx context.Context, id int64) (*Endpoint, error)
 	ListEndpoints(ctx context.Context) ([]*Endpoint, error)
 	UpdateEndpoint(ctx context.Context, endpoint *Endpoint) error
-	DeleteEndpoint(ctx context.Context, id int64) error
+	DeleteEndpoint(ctx context.Context, id int64, changedBy string) error
 
 	// Monitoring results/history
 	// SaveCheckResult(ctx context.Context, result *CheckResult) error
-	// GetCheckHistory(ctx context.Context, endpointID int64, limit int) ([]*CheckResult, error)
+	GetCheckHistory(ctx context.Context, endpointID int, q CheckHistoryQuery) (*CheckHistoryPage, error)
+	GetCheckSeries(ctx context.Context, endpointID int, bucket string, from, to time.Time) ([]CheckBucket, error)
 }
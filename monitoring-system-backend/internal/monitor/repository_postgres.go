@@ -3,13 +3,18 @@ package monitor
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/badgerv/monitoring-api/internal/storage"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type PostgresRepository struct {
@@ -20,8 +25,27 @@ func NewPostgresRepository(db *storage.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
+// endpointColumns lists the endpoints columns scanned by scanEndpoint, in order.
+const endpointColumns = `id, service_name, url, server_name, api_method, expected_status_code,
+	headers, query_params, body_template, assertions, check_interval_seconds, timeout_seconds,
+	check_type, check_config`
+
+func scanEndpoint(row pgx.Row, ep *Endpoint) error {
+	return row.Scan(&ep.ID, &ep.ServiceName, &ep.URL, &ep.ServerName, &ep.APIMethod, &ep.ExpectedCode,
+		&ep.Headers, &ep.QueryParams, &ep.BodyTemplate, &ep.Assertions, &ep.CheckIntervalSeconds, &ep.TimeoutSeconds,
+		&ep.CheckType, &ep.CheckConfig)
+}
+
+// nonNilMap keeps empty header/query maps from being stored as JSON null.
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
 func (r *PostgresRepository) GetAllEndpoints(ctx context.Context) ([]Endpoint, error) {
-	query := `SELECT id, service_name, url, server_name, api_method 
+	query := `SELECT ` + endpointColumns + ` 
 	          FROM endpoints`
 
 	rows, err := r.db.Pool.Query(ctx, query)
@@ -33,7 +57,7 @@ func (r *PostgresRepository) GetAllEndpoints(ctx context.Context) ([]Endpoint, e
 	var endpoints []Endpoint
 	for rows.Next() {
 		ep := Endpoint{}
-		err := rows.Scan(&ep.ID, &ep.ServiceName, &ep.URL, &ep.ServerName, &ep.APIMethod)
+		err := scanEndpoint(rows, &ep)
 		if err != nil {
 			return nil, err
 		}
@@ -47,6 +71,336 @@ func (r *PostgresRepository) GetAllEndpoints(ctx context.Context) ([]Endpoint, e
 	return endpoints, nil
 }
 
+// nonNilAssertions keeps an empty assertion list from being stored as JSON null.
+func nonNilAssertions(a []Assertion) []Assertion {
+	if a == nil {
+		return []Assertion{}
+	}
+	return a
+}
+
+// GetEndpoint returns a single endpoint with its request configuration
+func (r *PostgresRepository) GetEndpoint(ctx context.Context, id int) (*Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM endpoints WHERE id = $1`
+
+	ep := &Endpoint{}
+	if err := scanEndpoint(r.db.Pool.QueryRow(ctx, query, id), ep); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", ErrEndpointNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to query endpoint %v: %w", id, err)
+	}
+
+	return ep, nil
+}
+
+// GetEndpointWithInfo returns an endpoint together with its endpoint_info metadata
+func (r *PostgresRepository) GetEndpointWithInfo(ctx context.Context, id int) (*Endpoint, error) {
+	ep, err := r.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT gitlab_url, docker_container_name, kubernetes_pod_name, tags, description, last_changed_by
+		FROM endpoint_info
+		WHERE endpoint_id = $1`
+	err = r.db.Pool.QueryRow(ctx, query, id).Scan(
+		&ep.GitlabURL, &ep.DockerContainerName, &ep.KubernetesPodName, &ep.Tags, &ep.Description, &ep.LastChangedBy,
+	)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to query endpoint_info for endpoint %v: %w", id, err)
+	}
+
+	return ep, nil
+}
+
+// UpdateEndpoint replaces an endpoint and its endpoint_info in one transaction, marking it
+// as modified by ep.LastChangedBy and logging the before/after state
+func (r *PostgresRepository) UpdateEndpoint(ctx context.Context, ep *Endpoint) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.updateEndpoint(ctx, tx, ep); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// updateEndpoint is UpdateEndpoint within tx
+func (r *PostgresRepository) updateEndpoint(ctx context.Context, tx pgx.Tx, ep *Endpoint) error {
+	ep.URL = normalizeURL(ep.URL)
+
+	before, err := r.GetEndpointWithInfo(ctx, ep.ID)
+	if err != nil {
+		return err
+	}
+
+	var existingID int
+	err = tx.QueryRow(ctx, `SELECT id FROM endpoints WHERE url = $1 AND id <> $2`, ep.URL, ep.ID).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("endpoint with URL '%s' already exists (id=%d)", ep.URL, existingID)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed checking existing endpoint: %w", err)
+	}
+
+	if err := registerServer(ctx, tx, ep.ServerName); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE endpoints
+		SET service_name = $2, url = $3, server_name = $4, api_method = $5, expected_status_code = $6,
+		    headers = $7, query_params = $8, body_template = $9, assertions = $10,
+		    check_interval_seconds = $11, timeout_seconds = $12, check_type = $13, check_config = $14
+		WHERE id = $1`,
+		ep.ID, ep.ServiceName, ep.URL, ep.ServerName, ep.APIMethod, ep.ExpectedCode,
+		nonNilMap(ep.Headers), nonNilMap(ep.QueryParams), ep.BodyTemplate, nonNilAssertions(ep.Assertions),
+		ep.CheckIntervalSeconds, ep.TimeoutSeconds, ep.CheckType, ep.CheckConfig,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update endpoint: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO endpoint_info (
+			endpoint_id, gitlab_url, docker_container_name, kubernetes_pod_name, tags, description,
+			has_been_modified, last_changed_by, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, true, $7, NOW())
+		ON CONFLICT (endpoint_id) DO UPDATE
+		SET gitlab_url = EXCLUDED.gitlab_url,
+		    docker_container_name = EXCLUDED.docker_container_name,
+		    kubernetes_pod_name = EXCLUDED.kubernetes_pod_name,
+		    tags = EXCLUDED.tags,
+		    description = EXCLUDED.description,
+		    has_been_modified = true,
+		    last_changed_by = EXCLUDED.last_changed_by,
+		    updated_at = NOW()`,
+		ep.ID, ep.GitlabURL, ep.DockerContainerName, ep.KubernetesPodName, ep.Tags, ep.Description, ep.LastChangedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update endpoint_info: %w", err)
+	}
+
+	if ep.CheckType == CheckHeartbeat && before.CheckType != CheckHeartbeat {
+		if err := startHeartbeat(ctx, tx, ep.ID); err != nil {
+			return err
+		}
+	}
+
+	return insertEndpointChange(ctx, tx, ep.ID, "update", ep.LastChangedBy, before, ep)
+}
+
+// DeleteEndpoint removes an endpoint together with its checks, stats and endpoint_info.
+// The change log keeps a snapshot of what was deleted and by whom.
+func (r *PostgresRepository) DeleteEndpoint(ctx context.Context, id int64, changedBy string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.deleteEndpoint(ctx, tx, id, changedBy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// deleteEndpoint is DeleteEndpoint within tx
+func (r *PostgresRepository) deleteEndpoint(ctx context.Context, tx pgx.Tx, id int64, changedBy string) error {
+	before, err := r.GetEndpointWithInfo(ctx, int(id))
+	if err != nil {
+		return err
+	}
+
+	// checks and endpoint_stats reference endpoints without ON DELETE CASCADE
+	if _, err := tx.Exec(ctx, `DELETE FROM checks WHERE endpoint_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete checks: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM endpoint_stats WHERE endpoint_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete endpoint_stats: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete endpoint: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %v", ErrEndpointNotFound, id)
+	}
+
+	return insertEndpointChange(ctx, tx, int(id), "delete", &changedBy, before, nil)
+}
+
+// ApplyEndpointImport applies the changes of an import in one transaction, so when one
+// fails no endpoint is changed. It returns the index of the change that failed, or -1
+// if committing did. Created endpoints get their ID.
+func (r *PostgresRepository) ApplyEndpointImport(ctx context.Context, changes []ImportChange, changedBy string) (int, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range changes {
+		change := &changes[i]
+		ep := change.endpoint
+		ep.LastChangedBy = &changedBy
+
+		switch change.Action {
+		case ImportCreate:
+			created, err := createEndpoint(ctx, tx, &ep)
+			if err != nil {
+				return i, err
+			}
+			change.EndpointID = &created.ID
+		case ImportUpdate:
+			ep.ID = *change.EndpointID
+			if err := r.updateEndpoint(ctx, tx, &ep); err != nil {
+				return i, err
+			}
+		case ImportDelete:
+			if err := r.deleteEndpoint(ctx, tx, int64(*change.EndpointID), changedBy); err != nil {
+				return i, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return -1, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return -1, nil
+}
+
+// maskedSnapshot is a copy of ep for the change log, without its header values
+func maskedSnapshot(ep *Endpoint) *Endpoint {
+	if ep == nil {
+		return nil
+	}
+	snapshot := *ep
+	snapshot.Headers = maskHeaders(ep.Headers)
+	return &snapshot
+}
+
+func insertEndpointChange(ctx context.Context, db execer, endpointID int, action string, changedBy *string, before, after *Endpoint) error {
+	by := "unknown"
+	if changedBy != nil && *changedBy != "" {
+		by = *changedBy
+	}
+	before, after = maskedSnapshot(before), maskedSnapshot(after)
+
+	_, err := db.Exec(ctx, `
+		INSERT INTO endpoint_changes (endpoint_id, action, changed_by, before, after)
+		VALUES ($1, $2, $3, $4, $5)`,
+		endpointID, action, by, before, after,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record endpoint change: %w", err)
+	}
+	return nil
+}
+
+// ListEndpointChanges returns the change log of an endpoint, newest first. Header values
+// are masked, including in entries logged before they were masked on write.
+func (r *PostgresRepository) ListEndpointChanges(ctx context.Context, endpointID int) ([]EndpointChange, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, endpoint_id, action, changed_by, before, after, created_at
+		FROM endpoint_changes
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC, id DESC`, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []EndpointChange{}
+	for rows.Next() {
+		var c EndpointChange
+		if err := rows.Scan(&c.ID, &c.EndpointID, &c.Action, &c.ChangedBy, &c.Before, &c.After, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		c.Before, c.After = maskedSnapshot(c.Before), maskedSnapshot(c.After)
+		changes = append(changes, c)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return changes, nil
+}
+
+// UpdateEndpointAssertions replaces the response assertions evaluated for an endpoint
+func (r *PostgresRepository) UpdateEndpointAssertions(ctx context.Context, id int, assertions []Assertion) (*Endpoint, error) {
+	query := `
+		UPDATE endpoints
+		SET assertions = $2
+		WHERE id = $1
+		RETURNING ` + endpointColumns
+
+	ep := &Endpoint{}
+	if err := scanEndpoint(r.db.Pool.QueryRow(ctx, query, id, nonNilAssertions(assertions)), ep); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", ErrEndpointNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to update assertions for endpoint %v: %w", id, err)
+	}
+
+	return ep, nil
+}
+
+// UpdateEndpointSchedule stores the check interval and timeout of an endpoint
+func (r *PostgresRepository) UpdateEndpointSchedule(ctx context.Context, id int, cfg ScheduleConfig) (*Endpoint, error) {
+	query := `
+		UPDATE endpoints
+		SET check_interval_seconds = $2, timeout_seconds = $3
+		WHERE id = $1
+		RETURNING ` + endpointColumns
+
+	ep := &Endpoint{}
+	if err := scanEndpoint(r.db.Pool.QueryRow(ctx, query, id, cfg.CheckIntervalSeconds, cfg.TimeoutSeconds), ep); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", ErrEndpointNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to update schedule for endpoint %v: %w", id, err)
+	}
+
+	return ep, nil
+}
+
+// UpdateEndpointRequestConfig stores the request configuration used when checking an endpoint
+func (r *PostgresRepository) UpdateEndpointRequestConfig(ctx context.Context, id int, cfg RequestConfig) (*Endpoint, error) {
+	query := `
+		UPDATE endpoints
+		SET api_method = $2, headers = $3, query_params = $4, body_template = $5
+		WHERE id = $1
+		RETURNING ` + endpointColumns
+
+	ep := &Endpoint{}
+	err := scanEndpoint(r.db.Pool.QueryRow(ctx, query, id, cfg.APIMethod,
+		nonNilMap(cfg.Headers), nonNilMap(cfg.QueryParams), cfg.BodyTemplate), ep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", ErrEndpointNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to update request config for endpoint %v: %w", id, err)
+	}
+
+	return ep, nil
+}
+
 // func (r *PostgresRepository) CreateEndpoint(ctx context.Context) ([]Endpoint, error) {
 // 	query := `SELECT id, service_name, url, server_name, api_method
 // 	          FROM endpoints`
@@ -74,22 +428,45 @@ func (r *PostgresRepository) GetAllEndpoints(ctx context.Context) ([]Endpoint, e
 // 	return endpoints, nil
 // }
 
+// normalizeURL applies the same rules as the JSON loader so URLs compare consistently
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasSuffix(raw, "/") {
+		raw = strings.TrimSuffix(raw, "/")
+	}
+	return strings.ToLower(raw)
+}
+
 // Inserts a new endpoint into the database and returns the created record
 func (r *PostgresRepository) CreateEndpoint(ctx context.Context, ep *Endpoint) (*Endpoint, error) {
-    // Normalize URL (same rules as JSON loader)
-    normalizeURL := func(raw string) string {
-        raw = strings.TrimSpace(raw)
-        if strings.HasSuffix(raw, "/") {
-            raw = strings.TrimSuffix(raw, "/")
-        }
-        return strings.ToLower(raw)
+    // Start a transaction to ensure atomicity
+    tx, err := r.db.Pool.Begin(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx) // Rollback if not committed
+
+    newEp, err := createEndpoint(ctx, tx, ep)
+    if err != nil {
+        return nil, err
+    }
+
+    // Commit the transaction
+    if err := tx.Commit(ctx); err != nil {
+        return nil, fmt.Errorf("failed to commit transaction: %w", err)
     }
+
+    return newEp, nil
+}
+
+// createEndpoint is CreateEndpoint within tx
+func createEndpoint(ctx context.Context, tx pgx.Tx, ep *Endpoint) (*Endpoint, error) {
     ep.URL = normalizeURL(ep.URL)
 
     // First check if endpoint already exists
     var existingID int
     checkQuery := `SELECT id FROM endpoints WHERE url = $1`
-    err := r.db.Pool.QueryRow(ctx, checkQuery, ep.URL).Scan(&existingID)
+    err := tx.QueryRow(ctx, checkQuery, ep.URL).Scan(&existingID)
     if err == nil {
         // Found an existing endpoint → return error
         return nil, fmt.Errorf("endpoint with URL '%s' already exists (id=%d)", ep.URL, existingID)
@@ -99,22 +476,21 @@ func (r *PostgresRepository) CreateEndpoint(ctx context.Context, ep *Endpoint) (
         return nil, fmt.Errorf("failed checking existing endpoint: %w", err)
     }
 
-    // Start a transaction to ensure atomicity
-    tx, err := r.db.Pool.Begin(ctx)
-    if err != nil {
-        return nil, fmt.Errorf("failed to start transaction: %w", err)
+    if err := registerServer(ctx, tx, ep.ServerName); err != nil {
+        return nil, err
     }
-    defer tx.Rollback(ctx) // Rollback if not committed
 
     // Insert into endpoints table
     insertEndpointQuery := `
-        INSERT INTO endpoints (service_name, url, server_name, api_method, expected_status_code)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id, service_name, url, server_name, api_method, expected_status_code
-    `
-    row := tx.QueryRow(ctx, insertEndpointQuery, ep.ServiceName, ep.URL, ep.ServerName, ep.APIMethod, ep.ExpectedCode)
+        INSERT INTO endpoints (service_name, url, server_name, api_method, expected_status_code, headers, query_params, body_template, assertions,
+            check_interval_seconds, timeout_seconds, check_type, check_config)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING ` + endpointColumns
+    row := tx.QueryRow(ctx, insertEndpointQuery, ep.ServiceName, ep.URL, ep.ServerName, ep.APIMethod, ep.ExpectedCode,
+        nonNilMap(ep.Headers), nonNilMap(ep.QueryParams), ep.BodyTemplate, nonNilAssertions(ep.Assertions),
+        ep.CheckIntervalSeconds, ep.TimeoutSeconds, ep.CheckType, ep.CheckConfig)
     newEp := &Endpoint{}
-    err = row.Scan(&newEp.ID, &newEp.ServiceName, &newEp.URL, &newEp.ServerName, &newEp.APIMethod, &newEp.ExpectedCode)
+    err = scanEndpoint(row, newEp)
     if err != nil {
         return nil, fmt.Errorf("failed to insert endpoint: %w", err)
     }
@@ -151,29 +527,17 @@ func (r *PostgresRepository) CreateEndpoint(ctx context.Context, ep *Endpoint) (
         }
     }
 
-    // Commit the transaction
-    if err := tx.Commit(ctx); err != nil {
-        return nil, fmt.Errorf("failed to commit transaction: %w", err)
+    if newEp.CheckType == CheckHeartbeat {
+        if err := startHeartbeat(ctx, tx, newEp.ID); err != nil {
+            return nil, err
+        }
     }
 
-    return newEp, nil
-}
-
-func (r *PostgresRepository) GetEndpointExpectedCode(ctx context.Context, id int) (int, error) {
-	query := `
-		SELECT expected_status_code
-		FROM endpoints
-		WHERE id = $1
-	`
-
-	var expectedCode int
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(&expectedCode)
-	if err != nil {
-		log.Fatal(err)
-		return 0, err
-	}
+    if err := insertEndpointChange(ctx, tx, newEp.ID, "create", ep.LastChangedBy, nil, newEp); err != nil {
+        return nil, err
+    }
 
-	return expectedCode, nil
+    return newEp, nil
 }
 
 func (r *PostgresRepository) GetEndPointByID(ctx context.Context, id int) (*EndpointDetail, error) {
@@ -185,6 +549,14 @@ func (r *PostgresRepository) GetEndPointByID(ctx context.Context, id int) (*Endp
 			e.server_name,
 			e.api_method,
 			e.expected_status_code,
+			e.headers,
+			e.query_params,
+			e.body_template,
+			e.assertions,
+			e.check_interval_seconds,
+			e.timeout_seconds,
+			e.check_type,
+			e.check_config,
 
 			-- Endpoint Stats
 			COALESCE(es.endpoint_id, e.id) AS endpoint_id,
@@ -193,6 +565,10 @@ func (r *PostgresRepository) GetEndPointByID(ctx context.Context, id int) (*Endp
 			COALESCE(es.successful_checks, 0) AS successful_checks,
 			COALESCE(es.uptime_percentage, 0) AS uptime_percentage,
 			COALESCE(es.failure_count, 0) AS failure_count,
+			COALESCE(es.is_down, false) AS is_down,
+			es.down_since,
+			COALESCE(es.is_flapping, false) AS is_flapping,
+			es.flapping_since,
 			CASE 
 				WHEN es.last_run = true THEN 'success'
 				ELSE 'failure'
@@ -225,6 +601,14 @@ func (r *PostgresRepository) GetEndPointByID(ctx context.Context, id int) (*Endp
 		&detail.ServerName,
 		&detail.APIMethod,
 		&detail.ExpectedCode,
+		&detail.Headers,
+		&detail.QueryParams,
+		&detail.BodyTemplate,
+		&detail.Assertions,
+		&detail.CheckIntervalSeconds,
+		&detail.TimeoutSeconds,
+		&detail.CheckType,
+		&detail.CheckConfig,
 
 		// Stats
 		&detail.EndpointID,
@@ -233,6 +617,10 @@ func (r *PostgresRepository) GetEndPointByID(ctx context.Context, id int) (*Endp
 		&detail.SuccessfulChecks,
 		&detail.UptimePercentage,
 		&detail.FailureCount,
+		&detail.IsDown,
+		&detail.DownSince,
+		&detail.IsFlapping,
+		&detail.FlappingSince,
 		&detail.LastRunSucceeded,
 		// Info
 		&detail.Description,
@@ -250,13 +638,38 @@ func (r *PostgresRepository) GetEndPointByID(ctx context.Context, id int) (*Endp
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			log.Printf("endpoint %v not found", id)
-			return nil, fmt.Errorf("endpoint %v not found", id)
+			return nil, fmt.Errorf("%w: %v", ErrEndpointNotFound, id)
 		default:
 			log.Printf("db query failed for endpoint %v: %v", id, err)
 			return nil, fmt.Errorf("failed to query endpoint %v: %w", id, err)
 		}
 	}
 
+	windows, err := r.GetWindowStats(ctx, &id)
+	if err != nil {
+		return nil, err
+	}
+	set, ok := windows[id]
+	if !ok {
+		set = newWindowSet()
+	}
+	detail.Windows = set.stats()
+
+	timing, err := r.GetTimingAverages(ctx, id, 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	detail.AvgTiming24h = *timing
+
+	cert, err := r.GetCertificate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	detail.Certificate = cert
+	if cert != nil {
+		detail.CertificateDaysToExpiry = &cert.DaysToExpiry
+	}
+
 	return &detail, nil
 }
 
@@ -308,9 +721,18 @@ LEFT JOIN endpoint_stats i ON e.id = i.endpoint_id;
 		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
 	}
 
+	windows, err := r.GetWindowStats(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	// Step 2: Transform into []EndpointBasicsDTO with calculations
 	dtos := make([]EndpointBasicsDTO, len(basics))
 	for i, b := range basics {
+		set, ok := windows[b.ID]
+		if !ok {
+			set = newWindowSet()
+		}
 		downTimeCount := b.TotalChecks - b.SuccessfulChecks
 		dtos[i] = EndpointBasicsDTO{
 			ID:               b.ID,
@@ -324,6 +746,7 @@ LEFT JOIN endpoint_stats i ON e.id = i.endpoint_id;
 			AverageLatency:   b.AverageLatency,
 			LastRun:          b.LastRun,
 			FailureCount:     b.FailureCount,
+			Windows:          set.stats(),
 		}
 	}
 	return &dtos, nil
@@ -338,7 +761,12 @@ func (r *PostgresRepository) GetAggregateStats(ctx context.Context) (*AggregateD
 	}
 
 	if len(*endpoints) == 0 {
-		return &AggregateDTO{}, nil
+		return &AggregateDTO{Windows: newWindowSet().stats()}, nil
+	}
+
+	windows, err := r.GetWindowStats(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	var totalChecks, totalSuccess, totalDown, weightedLatencySum int
@@ -373,9 +801,105 @@ func (r *PostgresRepository) GetAggregateStats(ctx context.Context) (*AggregateD
 		DownTimeCount:    totalDown,
 		OverallUptime:    overallUptime,
 		AverageLatency:   avgLatency,
+		Windows:          mergeWindowSets(windows).stats(),
 	}, nil
 }
 
+// GetSchedulerState returns the persisted desired scheduler state, defaulting to stopped
+func (r *PostgresRepository) GetSchedulerState(ctx context.Context) (*SchedulerState, error) {
+	query := `SELECT running, changed_by_id, changed_by, changed_at FROM scheduler_state WHERE id = 1`
+
+	state := &SchedulerState{}
+	err := r.db.Pool.QueryRow(ctx, query).Scan(&state.Running, &state.ChangedByID, &state.ChangedBy, &state.ChangedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &SchedulerState{}, nil
+		}
+		return nil, fmt.Errorf("failed to get scheduler state: %w", err)
+	}
+
+	return state, nil
+}
+
+// SetSchedulerState persists the desired scheduler state and records the action in one transaction
+func (r *PostgresRepository) SetSchedulerState(ctx context.Context, running bool, action string, actor Actor) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO scheduler_state (id, running, changed_by_id, changed_by, changed_at)
+		VALUES (1, $1, $2, $3, NOW())
+		ON CONFLICT (id) DO UPDATE
+		SET running = EXCLUDED.running,
+		    changed_by_id = EXCLUDED.changed_by_id,
+		    changed_by = EXCLUDED.changed_by,
+		    changed_at = EXCLUDED.changed_at`,
+		running, actor.UserID, actor.Username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save scheduler state: %w", err)
+	}
+
+	if err := insertSchedulerAction(ctx, tx, action, actor); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RecordSchedulerAction logs a scheduler action that does not change the desired state, e.g. a restore on boot
+func (r *PostgresRepository) RecordSchedulerAction(ctx context.Context, action string, actor Actor) error {
+	return insertSchedulerAction(ctx, r.db.Pool, action, actor)
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func insertSchedulerAction(ctx context.Context, db execer, action string, actor Actor) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO scheduler_actions (action, actor_id, actor_name) VALUES ($1, $2, $3)`,
+		action, actor.UserID, actor.Username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record scheduler action: %w", err)
+	}
+	return nil
+}
+
+// ListSchedulerActions returns the most recent scheduler start/stop actions, newest first
+func (r *PostgresRepository) ListSchedulerActions(ctx context.Context, limit int) ([]SchedulerAction, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, action, actor_id, actor_name, created_at
+		FROM scheduler_actions
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []SchedulerAction{}
+	for rows.Next() {
+		var a SchedulerAction
+		if err := rows.Scan(&a.ID, &a.Action, &a.ActorID, &a.ActorName, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		actions = append(actions, a)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return actions, nil
+}
+
 // func (r *PostgresRepository) GetEndpointByID(ctx context.Context, id int64) (*Endpoint, error) {
 // 	query := `SELECT id, name, url, status, last_checked FROM endpoints WHERE id = $1`
 // 	row := r.db.Pool.QueryRow(ctx, query, id)
@@ -423,3 +947,2281 @@ func (r *PostgresRepository) GetAggregateStats(ctx context.Context) (*AggregateD
 
 // 	return endpoints, nil
 // }
+
+// encodeCheckCursor turns the position of the last returned check into an opaque cursor
+func encodeCheckCursor(c CheckRecord) string {
+	raw := fmt.Sprintf("%s|%d", c.CheckedAt.Format(time.RFC3339Nano), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCheckCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	checkedAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor")
+	}
+	return checkedAt, id, nil
+}
+
+// GetCheckHistory returns one page of raw checks for an endpoint, newest first, using
+// keyset pagination on (checked_at, id)
+func (r *PostgresRepository) GetCheckHistory(ctx context.Context, endpointID int, q CheckHistoryQuery) (*CheckHistoryPage, error) {
+	conditions := []string{"endpoint_id = $1"}
+	args := []interface{}{endpointID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.From != nil {
+		conditions = append(conditions, "checked_at >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		conditions = append(conditions, "checked_at < "+arg(*q.To))
+	}
+	switch q.Status {
+	case "up":
+		conditions = append(conditions, "success = true")
+	case "down":
+		conditions = append(conditions, "success = false")
+	}
+	if q.ErrorContains != "" {
+		conditions = append(conditions, "error ILIKE '%' || "+arg(q.ErrorContains)+" || '%'")
+	}
+	if q.Cursor != "" {
+		checkedAt, id, err := decodeCheckCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(checked_at, id) < (%s, %s)", arg(checkedAt), arg(id)))
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	query := `
+		SELECT id, endpoint_id, COALESCE(status_code, 0), COALESCE(latency_ms, 0), success, COALESCE(error, ''), attempts, in_maintenance,
+			dns_ms, connect_ms, tls_ms, ttfb_ms, transfer_ms, checked_at
+		FROM checks
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY checked_at DESC, id DESC
+		LIMIT ` + arg(q.Limit+1)
+
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []CheckRecord{}
+	for rows.Next() {
+		var c CheckRecord
+		if err := rows.Scan(&c.ID, &c.EndpointID, &c.StatusCode, &c.LatencyMs, &c.Success, &c.Error, &c.Attempts, &c.InMaintenance,
+			&c.Timing.DNSMs, &c.Timing.ConnectMs, &c.Timing.TLSMs, &c.Timing.TTFBMs, &c.Timing.TransferMs, &c.CheckedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		checks = append(checks, c)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	page := &CheckHistoryPage{Checks: checks}
+	if len(checks) > q.Limit {
+		page.Checks = checks[:q.Limit]
+		next := encodeCheckCursor(page.Checks[q.Limit-1])
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+// avgTimingColumns averages each request phase in CheckTiming order. AVG skips the
+// NULLs of phases that didn't happen, so a bucket without TLS has a NULL tls average.
+const avgTimingColumns = `AVG(dns_ms), AVG(connect_ms), AVG(tls_ms), AVG(ttfb_ms), AVG(transfer_ms)`
+
+// GetTimingAverages averages each request phase of an endpoint's checks over the
+// trailing window, leaving out checks taken during maintenance
+func (r *PostgresRepository) GetTimingAverages(ctx context.Context, endpointID int, window time.Duration) (*CheckTiming, error) {
+	var t CheckTiming
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT `+avgTimingColumns+`
+		FROM checks
+		WHERE endpoint_id = $1 AND checked_at >= now() - $2 * interval '1 second' AND NOT in_maintenance`,
+		endpointID, window.Seconds()).Scan(&t.DNSMs, &t.ConnectMs, &t.TLSMs, &t.TTFBMs, &t.TransferMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to average request timings for endpoint %v: %w", endpointID, err)
+	}
+	return &t, nil
+}
+
+// GetCheckSeries downsamples an endpoint's checks into minute or hour buckets. Checks
+// taken during maintenance are left out, as they are from uptime.
+func (r *PostgresRepository) GetCheckSeries(ctx context.Context, endpointID int, bucket string, from, to time.Time) ([]CheckBucket, error) {
+	query := `
+		SELECT
+			date_trunc($2, checked_at) AS bucket_start,
+			COUNT(*) AS total_checks,
+			COUNT(*) FILTER (WHERE success) AS successful_checks,
+			COALESCE(MIN(latency_ms), 0)::float8 AS min_latency,
+			COALESCE(AVG(latency_ms), 0)::float8 AS avg_latency,
+			COALESCE(MAX(latency_ms), 0)::float8 AS max_latency,
+			` + avgTimingColumns + `
+		FROM checks
+		WHERE endpoint_id = $1 AND checked_at >= $3 AND checked_at < $4 AND NOT in_maintenance
+		GROUP BY bucket_start
+		ORDER BY bucket_start`
+
+	rows, err := r.db.Pool.Query(ctx, query, endpointID, bucket, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []CheckBucket{}
+	for rows.Next() {
+		var b CheckBucket
+		if err := rows.Scan(&b.BucketStart, &b.TotalChecks, &b.SuccessfulChecks, &b.MinLatency, &b.AvgLatency, &b.MaxLatency,
+			&b.AvgTiming.DNSMs, &b.AvgTiming.ConnectMs, &b.AvgTiming.TLSMs, &b.AvgTiming.TTFBMs, &b.AvgTiming.TransferMs); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		if b.TotalChecks > 0 {
+			b.Availability = float64(b.SuccessfulChecks) / float64(b.TotalChecks) * 100
+		}
+		buckets = append(buckets, b)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return buckets, nil
+}
+
+// GetWindowStats returns rolling-window accumulators per endpoint. A nil endpointID
+// loads every endpoint. The 1h window comes from raw checks so it reacts immediately;
+// longer windows are summed from check_rollups_hourly. Checks taken during maintenance
+// don't count.
+func (r *PostgresRepository) GetWindowStats(ctx context.Context, endpointID *int) (map[int]windowSet, error) {
+	names := make([]string, 0, len(uptimeWindows))
+	spans := make([]int64, 0, len(uptimeWindows))
+	for _, w := range uptimeWindows[1:] {
+		names = append(names, w.Name)
+		spans = append(spans, int64(w.Span.Seconds()))
+	}
+
+	sets := map[int]windowSet{}
+	setFor := func(id int) windowSet {
+		if _, ok := sets[id]; !ok {
+			sets[id] = newWindowSet()
+		}
+		return sets[id]
+	}
+
+	totalsQuery := `
+		SELECT endpoint_id, '1h', COUNT(*), COUNT(*) FILTER (WHERE success),
+			COALESCE(SUM(latency_ms), 0), COALESCE(MAX(latency_ms), 0)
+		FROM checks
+		WHERE checked_at >= now() - interval '1 hour' AND NOT in_maintenance
+			AND ($1::int IS NULL OR endpoint_id = $1)
+		GROUP BY endpoint_id
+		UNION ALL
+		SELECT r.endpoint_id, w.name, SUM(r.total_checks)::bigint, SUM(r.successful_checks)::bigint,
+			SUM(r.latency_sum)::bigint, MAX(r.latency_max)
+		FROM check_rollups_hourly r
+		JOIN unnest($2::text[], $3::bigint[]) AS w(name, secs)
+			ON r.bucket_start >= date_trunc('hour', now() - w.secs * interval '1 second')
+		WHERE $1::int IS NULL OR r.endpoint_id = $1
+		GROUP BY r.endpoint_id, w.name`
+
+	rows, err := r.db.Pool.Query(ctx, totalsQuery, endpointID, names, spans)
+	if err != nil {
+		return nil, fmt.Errorf("window totals query failed: %w", err)
+	}
+	for rows.Next() {
+		var id int
+		var window string
+		var total, successful, latencySum, latencyMax int64
+		if err := rows.Scan(&id, &window, &total, &successful, &latencySum, &latencyMax); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		acc := setFor(id)[window]
+		acc.total = int(total)
+		acc.successful = int(successful)
+		acc.latencySum = latencySum
+		acc.latencyMax = latencyMax
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	// Histogram buckets are one-based in Postgres, zero-based in Go
+	histogramQuery := `
+		SELECT endpoint_id, '1h', width_bucket(COALESCE(latency_ms, 0), $4::bigint[]) + 1, COUNT(*)
+		FROM checks
+		WHERE checked_at >= now() - interval '1 hour' AND NOT in_maintenance
+			AND ($1::int IS NULL OR endpoint_id = $1)
+		GROUP BY 1, 3
+		UNION ALL
+		SELECT r.endpoint_id, w.name, h.idx, SUM(COALESCE(h.n, 0))::bigint
+		FROM check_rollups_hourly r
+		JOIN unnest($2::text[], $3::bigint[]) AS w(name, secs)
+			ON r.bucket_start >= date_trunc('hour', now() - w.secs * interval '1 second')
+		CROSS JOIN LATERAL unnest(r.latency_histogram) WITH ORDINALITY AS h(n, idx)
+		WHERE $1::int IS NULL OR r.endpoint_id = $1
+		GROUP BY 1, 2, 3`
+
+	rows, err = r.db.Pool.Query(ctx, histogramQuery, endpointID, names, spans, latencyBucketBounds)
+	if err != nil {
+		return nil, fmt.Errorf("window histogram query failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var window string
+		var idx, n int64
+		if err := rows.Scan(&id, &window, &idx, &n); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		hist := setFor(id)[window].histogram
+		if idx >= 1 && int(idx) <= len(hist) {
+			hist[idx-1] += n
+		}
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	return sets, nil
+}
+
+// retentionDaysCTE is the number of days each endpoint's raw history is kept: the
+// longest retention of its tags ($2 and $3), or the default ($1).
+const retentionDaysCTE = `retention AS (
+			SELECT e.id, COALESCE(MAX(t.days), $1) AS days
+			FROM endpoints e
+			LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+			LEFT JOIN unnest($2::text[], $3::int[]) AS t(tag, days) ON t.tag = ANY(i.tags)
+			GROUP BY e.id
+		)`
+
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// RunRetention rolls up and prunes check data in a single transaction, holding the
+// retention advisory lock so concurrent replicas skip instead of doing the work twice.
+// Raw checks are authoritative while they exist, so closed hours since the last
+// successful run are recomputed from them rather than trusted from the live upserts.
+func (r *PostgresRepository) RunRetention(ctx context.Context, cfg RetentionConfig, run *RetentionRun) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, retentionLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take retention lock: %w", err)
+	}
+	if !locked {
+		return ErrRetentionRunning
+	}
+
+	// Use database time so the next run's "since" lines up with checks.checked_at
+	if err := tx.QueryRow(ctx, `SELECT now()::timestamp`).Scan(&run.StartedAt); err != nil {
+		return fmt.Errorf("failed to read database time: %w", err)
+	}
+
+	buckets := len(latencyBucketBounds) + 1
+
+	// 1. Hourly rollups for every closed hour since the last successful run
+	tag, err := tx.Exec(ctx, `
+		WITH since AS (
+			SELECT COALESCE(date_trunc('hour', MAX(started_at)) - interval '1 hour', '-infinity'::timestamp) AS t
+			FROM check_retention_runs
+			WHERE finished_at IS NOT NULL AND error IS NULL
+		), per_bucket AS (
+			SELECT c.endpoint_id,
+				date_trunc('hour', c.checked_at) AS bucket_start,
+				width_bucket(COALESCE(c.latency_ms, 0), $1::bigint[]) + 1 AS idx,
+				COUNT(*) AS n,
+				COUNT(*) FILTER (WHERE c.success) AS successful,
+				COALESCE(SUM(c.latency_ms), 0) AS latency_sum,
+				COALESCE(MIN(c.latency_ms), 0) AS latency_min,
+				COALESCE(MAX(c.latency_ms), 0) AS latency_max
+			FROM checks c, since
+			WHERE c.checked_at >= since.t AND c.checked_at < date_trunc('hour', now()) AND NOT c.in_maintenance
+			GROUP BY 1, 2, 3
+		), hours AS (
+			SELECT endpoint_id, bucket_start, SUM(n) AS total, SUM(successful) AS successful,
+				SUM(latency_sum) AS latency_sum, MIN(latency_min) AS latency_min, MAX(latency_max) AS latency_max
+			FROM per_bucket
+			GROUP BY 1, 2
+		)
+		INSERT INTO check_rollups_hourly (endpoint_id, bucket_start, total_checks, successful_checks, latency_sum, latency_min, latency_max, latency_histogram)
+		SELECT h.endpoint_id, h.bucket_start, h.total, h.successful, h.latency_sum, h.latency_min, h.latency_max,
+			ARRAY(
+				SELECT COALESCE(p.n, 0)
+				FROM generate_series(1, $2) AS g(i)
+				LEFT JOIN per_bucket p ON p.endpoint_id = h.endpoint_id AND p.bucket_start = h.bucket_start AND p.idx = g.i
+				ORDER BY g.i
+			)
+		FROM hours h
+		ON CONFLICT (endpoint_id, bucket_start) DO UPDATE
+		SET total_checks = EXCLUDED.total_checks,
+			successful_checks = EXCLUDED.successful_checks,
+			latency_sum = EXCLUDED.latency_sum,
+			latency_min = EXCLUDED.latency_min,
+			latency_max = EXCLUDED.latency_max,
+			latency_histogram = EXCLUDED.latency_histogram`,
+		latencyBucketBounds, buckets)
+	if err != nil {
+		return fmt.Errorf("failed to roll up hourly checks: %w", err)
+	}
+	run.HourlyRollups = tag.RowsAffected()
+
+	// 2. Daily rollups for every closed day since the last one written
+	tag, err = tx.Exec(ctx, `
+		WITH since AS (
+			SELECT COALESCE(MAX(day), '-infinity'::date) AS d FROM check_rollups_daily
+		), src AS (
+			SELECT h.*
+			FROM check_rollups_hourly h, since
+			WHERE h.bucket_start >= since.d AND h.bucket_start < date_trunc('day', now())
+		), hist AS (
+			SELECT s.endpoint_id, s.bucket_start::date AS day, u.i, SUM(COALESCE(u.n, 0)) AS n
+			FROM src s
+			CROSS JOIN LATERAL unnest(s.latency_histogram) WITH ORDINALITY AS u(n, i)
+			GROUP BY 1, 2, 3
+		), days AS (
+			SELECT endpoint_id, bucket_start::date AS day, SUM(total_checks) AS total, SUM(successful_checks) AS successful,
+				SUM(latency_sum) AS latency_sum, MIN(latency_min) AS latency_min, MAX(latency_max) AS latency_max
+			FROM src
+			GROUP BY 1, 2
+		)
+		INSERT INTO check_rollups_daily (endpoint_id, day, total_checks, successful_checks, latency_sum, latency_min, latency_max, latency_histogram)
+		SELECT d.endpoint_id, d.day, d.total, d.successful, d.latency_sum, d.latency_min, d.latency_max,
+			ARRAY(
+				SELECT COALESCE(h.n, 0)::bigint
+				FROM generate_series(1, $1) AS g(i)
+				LEFT JOIN hist h ON h.endpoint_id = d.endpoint_id AND h.day = d.day AND h.i = g.i
+				ORDER BY g.i
+			)
+		FROM days d
+		ON CONFLICT (endpoint_id, day) DO UPDATE
+		SET total_checks = EXCLUDED.total_checks,
+			successful_checks = EXCLUDED.successful_checks,
+			latency_sum = EXCLUDED.latency_sum,
+			latency_min = EXCLUDED.latency_min,
+			latency_max = EXCLUDED.latency_max,
+			latency_histogram = EXCLUDED.latency_histogram`,
+		buckets)
+	if err != nil {
+		return fmt.Errorf("failed to roll up daily checks: %w", err)
+	}
+	run.DailyRollups = tag.RowsAffected()
+
+	// 3. Raw checks past retention; an endpoint's longest tag retention wins over the default
+	tags := make([]string, 0, len(cfg.TagRetentionDays))
+	days := make([]int, 0, len(cfg.TagRetentionDays))
+	for t, d := range cfg.TagRetentionDays {
+		tags = append(tags, t)
+		days = append(days, d)
+	}
+
+	rows, err := tx.Query(ctx, `
+		WITH `+retentionDaysCTE+`, deleted AS (
+			DELETE FROM checks c
+			USING retention r
+			WHERE c.endpoint_id = r.id
+				AND c.checked_at < date_trunc('hour', now() - r.days * interval '1 day')
+			RETURNING c.endpoint_id
+		)
+		SELECT endpoint_id, COUNT(*) FROM deleted GROUP BY endpoint_id`,
+		cfg.CheckRetentionDays, tags, days)
+	if err != nil {
+		return fmt.Errorf("failed to prune checks: %w", err)
+	}
+
+	run.PrunedByEndpoint = map[int]int64{}
+	for rows.Next() {
+		var endpointID int
+		var n int64
+		if err := rows.Scan(&endpointID, &n); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan failed: %w", err)
+		}
+		run.PrunedByEndpoint[endpointID] = n
+		run.ChecksPruned += n
+	}
+	rows.Close()
+	if rows.Err() != nil {
+		return fmt.Errorf("failed to prune checks: %w", rows.Err())
+	}
+
+	// Heartbeat pings are raw history too and follow the same retention
+	if _, err := tx.Exec(ctx, `
+		WITH `+retentionDaysCTE+`
+		DELETE FROM heartbeat_pings p
+		USING retention r
+		WHERE p.endpoint_id = r.id
+			AND p.received_at < date_trunc('hour', now() - r.days * interval '1 day')`,
+		cfg.CheckRetentionDays, tags, days); err != nil {
+		return fmt.Errorf("failed to prune heartbeat pings: %w", err)
+	}
+
+	// 4. Hourly rollups past retention, only for days already folded into daily rollups
+	tag, err = tx.Exec(ctx, `
+		DELETE FROM check_rollups_hourly
+		WHERE bucket_start < date_trunc('day', now() - $1 * interval '1 day')`,
+		cfg.HourlyRollupRetentionDays)
+	if err != nil {
+		return fmt.Errorf("failed to prune hourly rollups: %w", err)
+	}
+	run.HourlyRollupsPruned = tag.RowsAffected()
+
+	var finishedAt time.Time
+	if err := tx.QueryRow(ctx, `SELECT clock_timestamp()::timestamp`).Scan(&finishedAt); err != nil {
+		return fmt.Errorf("failed to read database time: %w", err)
+	}
+	run.FinishedAt = &finishedAt
+	if err := insertRetentionRun(ctx, tx, run); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RecordRetentionRun stores a run outside the retention transaction, used for failures
+func (r *PostgresRepository) RecordRetentionRun(ctx context.Context, run *RetentionRun) error {
+	return insertRetentionRun(ctx, r.db.Pool, run)
+}
+
+func insertRetentionRun(ctx context.Context, db rowQuerier, run *RetentionRun) error {
+	err := db.QueryRow(ctx, `
+		INSERT INTO check_retention_runs (trigger, triggered_by, started_at, finished_at, hourly_rollups, daily_rollups,
+			checks_pruned, hourly_rollups_pruned, pruned_by_endpoint, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`,
+		run.Trigger, run.TriggeredBy, run.StartedAt, run.FinishedAt, run.HourlyRollups, run.DailyRollups,
+		run.ChecksPruned, run.HourlyRollupsPruned, run.PrunedByEndpoint, run.Error,
+	).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return nil
+}
+
+// ListRetentionRuns returns the most recent retention runs, newest first
+func (r *PostgresRepository) ListRetentionRuns(ctx context.Context, limit int) ([]RetentionRun, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, trigger, triggered_by, started_at, finished_at, hourly_rollups, daily_rollups,
+			checks_pruned, hourly_rollups_pruned, pruned_by_endpoint, error
+		FROM check_retention_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []RetentionRun{}
+	for rows.Next() {
+		var run RetentionRun
+		if err := rows.Scan(&run.ID, &run.Trigger, &run.TriggeredBy, &run.StartedAt, &run.FinishedAt,
+			&run.HourlyRollups, &run.DailyRollups, &run.ChecksPruned, &run.HourlyRollupsPruned,
+			&run.PrunedByEndpoint, &run.Error); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return runs, nil
+}
+
+// MarkEndpointDown flips an endpoint to down. down_since is the time of the first
+// failed check in the current streak. changed is false if it was already down.
+func (r *PostgresRepository) MarkEndpointDown(ctx context.Context, endpointID int, failures int) (time.Time, bool, error) {
+	var downSince time.Time
+	err := r.db.Pool.QueryRow(ctx, `
+		UPDATE endpoint_stats
+		SET is_down = true,
+			down_since = COALESCE((
+				SELECT MIN(checked_at) FROM (
+					SELECT checked_at FROM checks
+					WHERE endpoint_id = $1
+					ORDER BY checked_at DESC
+					LIMIT $2
+				) streak
+			), now())
+		WHERE endpoint_id = $1 AND NOT is_down
+		RETURNING down_since`, endpointID, failures).Scan(&downSince)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to mark endpoint %d down: %w", endpointID, err)
+	}
+	return downSince, true, nil
+}
+
+// MarkEndpointRecovered flips an endpoint back to up and returns when the outage
+// started. changed is false if it wasn't down.
+func (r *PostgresRepository) MarkEndpointRecovered(ctx context.Context, endpointID int) (time.Time, bool, error) {
+	var downSince *time.Time
+	err := r.db.Pool.QueryRow(ctx, `
+		WITH prev AS (
+			SELECT down_since FROM endpoint_stats
+			WHERE endpoint_id = $1 AND is_down
+			FOR UPDATE
+		)
+		UPDATE endpoint_stats es
+		SET is_down = false, down_since = NULL
+		FROM prev
+		WHERE es.endpoint_id = $1
+		RETURNING prev.down_since`, endpointID).Scan(&downSince)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to mark endpoint %d recovered: %w", endpointID, err)
+	}
+	if downSince == nil {
+		return time.Now(), true, nil
+	}
+	return *downSince, true, nil
+}
+
+// ListSubscriberIDs returns the users subscribed to an endpoint's alerts
+func (r *PostgresRepository) ListSubscriberIDs(ctx context.Context, endpointID int) ([]uuid.UUID, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT user_id FROM endpoint_subscriptions WHERE endpoint_id = $1`, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return ids, nil
+}
+
+// ListSubscriptions returns an endpoint's subscribers with their usernames
+func (r *PostgresRepository) ListSubscriptions(ctx context.Context, endpointID int) ([]EndpointSubscription, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT s.endpoint_id, s.user_id, COALESCE(u.username, ''), s.created_at
+		FROM endpoint_subscriptions s
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.endpoint_id = $1
+		ORDER BY s.created_at`, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []EndpointSubscription{}
+	for rows.Next() {
+		var sub EndpointSubscription
+		if err := rows.Scan(&sub.EndpointID, &sub.UserID, &sub.Username, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return subs, nil
+}
+
+// AddSubscription subscribes a user to an endpoint's alerts; subscribing twice is a no-op
+func (r *PostgresRepository) AddSubscription(ctx context.Context, endpointID int, userID uuid.UUID) error {
+	tag, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO endpoint_subscriptions (endpoint_id, user_id)
+		SELECT id, $2 FROM endpoints WHERE id = $1
+		ON CONFLICT (endpoint_id, user_id) DO NOTHING`, endpointID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err := r.db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM endpoints WHERE id = $1)`, endpointID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("%w: %v", ErrEndpointNotFound, endpointID)
+		}
+	}
+	return nil
+}
+
+// RemoveSubscription unsubscribes a user from an endpoint's alerts
+func (r *PostgresRepository) RemoveSubscription(ctx context.Context, endpointID int, userID uuid.UUID) error {
+	_, err := r.db.Pool.Exec(ctx, `DELETE FROM endpoint_subscriptions WHERE endpoint_id = $1 AND user_id = $2`, endpointID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	return nil
+}
+
+const incidentColumns = `i.id, i.endpoint_id, i.service_name, i.server_name, i.status, i.error, i.started_at,
+	i.acknowledged_at, i.acknowledged_by_id, i.acknowledged_by, i.assigned_to_id, i.assigned_to, i.assigned_at,
+	i.resolved_at,
+	CASE WHEN i.resolved_at IS NOT NULL THEN EXTRACT(EPOCH FROM i.resolved_at - i.started_at)::bigint END,
+	(SELECT COUNT(*) FROM incident_checks ic WHERE ic.incident_id = i.id),
+	i.root_cause_endpoint_id, (SELECT re.service_name FROM endpoints re WHERE re.id = i.root_cause_endpoint_id),
+	i.root_cause_incident_id, i.root_cause_recovered_at`
+
+func scanIncident(row pgx.Row, inc *Incident) error {
+	return row.Scan(&inc.ID, &inc.EndpointID, &inc.ServiceName, &inc.ServerName, &inc.Status, &inc.Error, &inc.StartedAt,
+		&inc.AcknowledgedAt, &inc.AcknowledgedByID, &inc.AcknowledgedBy, &inc.AssignedToID, &inc.AssignedTo, &inc.AssignedAt,
+		&inc.ResolvedAt, &inc.DurationSeconds, &inc.FailedCheckCount,
+		&inc.RootCauseEndpointID, &inc.RootCauseServiceName, &inc.RootCauseIncidentID, &inc.RootCauseRecoveredAt)
+}
+
+// OpenIncident records a new incident for an endpoint that was just marked down and
+// attaches the failed checks of the streak that caused it. rootCause, if any, is linked
+// together with its open incident.
+func (r *PostgresRepository) OpenIncident(ctx context.Context, ep Endpoint, startedAt time.Time, errMsg string, failures int, rootCause *RootCause) (*Incident, error) {
+	var rootCauseID *int
+	if rootCause != nil {
+		rootCauseID = &rootCause.EndpointID
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO incidents (endpoint_id, service_name, server_name, status, error, started_at,
+			root_cause_endpoint_id, root_cause_incident_id)
+		VALUES ($1, $2, $3, $4, $5, $6,
+			$7::int, (SELECT id FROM incidents WHERE endpoint_id = $7::int AND resolved_at IS NULL))
+		RETURNING id`,
+		ep.ID, ep.ServiceName, ep.ServerName, IncidentOpen, errMsg, startedAt, rootCauseID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open incident: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO incident_checks (incident_id, check_id, status_code, error, checked_at)
+		SELECT $1, id, COALESCE(status_code, 0), COALESCE(error, ''), checked_at
+		FROM checks
+		WHERE endpoint_id = $2 AND NOT success
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $3`, id, ep.ID, failures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach checks to incident: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetIncident(ctx, id)
+}
+
+// AttachIncidentCheck adds a failed check to the endpoint's unresolved incident, if any
+func (r *PostgresRepository) AttachIncidentCheck(ctx context.Context, endpointID int, checkID int64) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO incident_checks (incident_id, check_id, status_code, error, checked_at)
+		SELECT i.id, c.id, COALESCE(c.status_code, 0), COALESCE(c.error, ''), c.checked_at
+		FROM incidents i
+		JOIN checks c ON c.id = $2
+		WHERE i.endpoint_id = $1 AND i.resolved_at IS NULL
+		ON CONFLICT (incident_id, check_id) DO NOTHING`, endpointID, checkID)
+	if err != nil {
+		return fmt.Errorf("failed to attach check %d to incident: %w", checkID, err)
+	}
+	return nil
+}
+
+// ResolveIncident closes the endpoint's unresolved incident. It returns nil if there
+// was none.
+func (r *PostgresRepository) ResolveIncident(ctx context.Context, endpointID int) (*Incident, error) {
+	var id int
+	err := r.db.Pool.QueryRow(ctx, `
+		UPDATE incidents
+		SET status = $2, resolved_at = now()
+		WHERE endpoint_id = $1 AND resolved_at IS NULL
+		RETURNING id`, endpointID, IncidentResolved).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve incident: %w", err)
+	}
+	return r.GetIncident(ctx, id)
+}
+
+// GetOpenIncident returns the endpoint's unresolved incident, or nil if there is none
+func (r *PostgresRepository) GetOpenIncident(ctx context.Context, endpointID int) (*Incident, error) {
+	var id int
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT id FROM incidents WHERE endpoint_id = $1 AND resolved_at IS NULL`, endpointID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open incident: %w", err)
+	}
+	return r.GetIncident(ctx, id)
+}
+
+// ListIncidents returns incidents matching the filter, newest first
+func (r *PostgresRepository) ListIncidents(ctx context.Context, filter IncidentFilter) ([]Incident, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT `+incidentColumns+`
+		FROM incidents i
+		WHERE ($1 = '' OR i.status = $1)
+			AND ($2 = 0 OR i.endpoint_id = $2)
+			AND ($3 = '' OR i.server_name = $3)
+		ORDER BY i.started_at DESC, i.id DESC
+		LIMIT $4 OFFSET $5`,
+		filter.Status, filter.EndpointID, filter.ServerName, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []Incident{}
+	for rows.Next() {
+		var inc Incident
+		if err := scanIncident(rows, &inc); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		incidents = append(incidents, inc)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return incidents, nil
+}
+
+// GetIncident returns an incident with its failed checks, oldest first
+func (r *PostgresRepository) GetIncident(ctx context.Context, id int) (*Incident, error) {
+	var inc Incident
+	row := r.db.Pool.QueryRow(ctx, `SELECT `+incidentColumns+` FROM incidents i WHERE i.id = $1`, id)
+	if err := scanIncident(row, &inc); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIncidentNotFound
+		}
+		return nil, fmt.Errorf("failed to query incident %v: %w", id, err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT check_id, status_code, error, checked_at
+		FROM incident_checks
+		WHERE incident_id = $1
+		ORDER BY checked_at, check_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	inc.Checks = []IncidentCheck{}
+	for rows.Next() {
+		var ic IncidentCheck
+		if err := rows.Scan(&ic.CheckID, &ic.StatusCode, &ic.Error, &ic.CheckedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		inc.Checks = append(inc.Checks, ic)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return &inc, nil
+}
+
+// AcknowledgeIncident stamps the first acknowledgement; later ones keep the original
+func (r *PostgresRepository) AcknowledgeIncident(ctx context.Context, id int, userID uuid.UUID, username string) (*Incident, error) {
+	tag, err := r.db.Pool.Exec(ctx, `
+		UPDATE incidents
+		SET acknowledged_at = COALESCE(acknowledged_at, now()),
+			acknowledged_by_id = COALESCE(acknowledged_by_id, $2),
+			acknowledged_by = COALESCE(acknowledged_by, $3),
+			status = CASE WHEN status = $4 THEN $5 ELSE status END
+		WHERE id = $1`, id, userID, username, IncidentOpen, IncidentAcknowledged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge incident: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrIncidentNotFound
+	}
+	return r.GetIncident(ctx, id)
+}
+
+// AssignIncident sets the user responsible for an incident
+func (r *PostgresRepository) AssignIncident(ctx context.Context, id int, userID uuid.UUID, username string) (*Incident, error) {
+	tag, err := r.db.Pool.Exec(ctx, `
+		UPDATE incidents
+		SET assigned_to_id = $2, assigned_to = $3, assigned_at = now()
+		WHERE id = $1`, id, userID, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign incident: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrIncidentNotFound
+	}
+	return r.GetIncident(ctx, id)
+}
+
+// GetIncidentMetrics computes MTTA and MTTR per endpoint and per server_name for
+// incidents started within [from, to)
+func (r *PostgresRepository) GetIncidentMetrics(ctx context.Context, from, to time.Time) ([]IncidentMetrics, []IncidentMetrics, error) {
+	query := `
+		SELECT %s,
+			COUNT(*),
+			COUNT(acknowledged_at),
+			COUNT(resolved_at),
+			AVG(EXTRACT(EPOCH FROM acknowledged_at - started_at))::float8,
+			AVG(EXTRACT(EPOCH FROM resolved_at - started_at))::float8
+		FROM incidents
+		WHERE started_at >= $1 AND started_at < $2
+		GROUP BY %s
+		ORDER BY COUNT(*) DESC`
+
+	collect := func(sql string, keys func(*IncidentMetrics) []interface{}) ([]IncidentMetrics, error) {
+		rows, err := r.db.Pool.Query(ctx, sql, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("query failed: %w", err)
+		}
+		defer rows.Close()
+
+		metrics := []IncidentMetrics{}
+		for rows.Next() {
+			var m IncidentMetrics
+			dest := append(keys(&m), &m.Incidents, &m.Acknowledged, &m.Resolved, &m.MTTASeconds, &m.MTTRSeconds)
+			if err := rows.Scan(dest...); err != nil {
+				return nil, fmt.Errorf("scan failed: %w", err)
+			}
+			metrics = append(metrics, m)
+		}
+
+		if rows.Err() != nil {
+			return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+		}
+		return metrics, nil
+	}
+
+	byEndpoint, err := collect(
+		fmt.Sprintf(query, "endpoint_id, MAX(service_name), MAX(server_name)", "endpoint_id"),
+		func(m *IncidentMetrics) []interface{} { return []interface{}{&m.EndpointID, &m.ServiceName, &m.ServerName} },
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byServer, err := collect(
+		fmt.Sprintf(query, "server_name", "server_name"),
+		func(m *IncidentMetrics) []interface{} { return []interface{}{&m.ServerName} },
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return byEndpoint, byServer, nil
+}
+
+// GetEndpointTags returns the tags of an endpoint, used to route its notifications
+func (r *PostgresRepository) GetEndpointTags(ctx context.Context, id int) ([]string, error) {
+	var tags []string
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(i.tags, ARRAY[]::TEXT[])
+		FROM endpoints e
+		LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+		WHERE e.id = $1`, id).Scan(&tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags for endpoint %v: %w", id, err)
+	}
+	return tags, nil
+}
+
+// scopeMatches is the condition under which a scope_type/scope_value row covers the
+// endpoint e (joined with its endpoint_info as i)
+const scopeMatches = `(
+		(s.scope_type = 'endpoint' AND s.scope_value = e.id::text)
+		OR (s.scope_type = 'server' AND s.scope_value = e.server_name)
+		OR (s.scope_type = 'tag' AND s.scope_value = ANY(COALESCE(i.tags, ARRAY[]::TEXT[])))
+	)`
+
+const maintenanceWindowColumns = `s.id, s.name, s.scope_type, s.scope_value, s.starts_at, s.ends_at, s.schedule,
+	s.duration_minutes, s.timezone, s.created_by_id, s.created_by, s.created_at`
+
+func scanMaintenanceWindow(row pgx.Row, w *MaintenanceWindow) error {
+	return row.Scan(&w.ID, &w.Name, &w.ScopeType, &w.ScopeValue, &w.StartsAt, &w.EndsAt, &w.Schedule,
+		&w.DurationMinutes, &w.Timezone, &w.CreatedByID, &w.CreatedBy, &w.CreatedAt)
+}
+
+func (r *PostgresRepository) queryMaintenanceWindows(ctx context.Context, query string, args ...interface{}) ([]MaintenanceWindow, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []MaintenanceWindow{}
+	for rows.Next() {
+		var w MaintenanceWindow
+		if err := scanMaintenanceWindow(rows, &w); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		windows = append(windows, w)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return windows, nil
+}
+
+func (r *PostgresRepository) CreateMaintenanceWindow(ctx context.Context, w *MaintenanceWindow) (*MaintenanceWindow, error) {
+	var created MaintenanceWindow
+	err := scanMaintenanceWindow(r.db.Pool.QueryRow(ctx, `
+		INSERT INTO maintenance_windows AS s (name, scope_type, scope_value, starts_at, ends_at, schedule, duration_minutes, timezone, created_by_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+maintenanceWindowColumns,
+		w.Name, w.ScopeType, w.ScopeValue, w.StartsAt, w.EndsAt, w.Schedule, w.DurationMinutes, w.Timezone, w.CreatedByID, w.CreatedBy,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *PostgresRepository) UpdateMaintenanceWindow(ctx context.Context, w *MaintenanceWindow) (*MaintenanceWindow, error) {
+	var updated MaintenanceWindow
+	err := scanMaintenanceWindow(r.db.Pool.QueryRow(ctx, `
+		UPDATE maintenance_windows AS s
+		SET name = $2, scope_type = $3, scope_value = $4, starts_at = $5, ends_at = $6,
+			schedule = $7, duration_minutes = $8, timezone = $9
+		WHERE s.id = $1
+		RETURNING `+maintenanceWindowColumns,
+		w.ID, w.Name, w.ScopeType, w.ScopeValue, w.StartsAt, w.EndsAt, w.Schedule, w.DurationMinutes, w.Timezone,
+	), &updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMaintenanceWindowNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update maintenance window: %w", err)
+	}
+	return &updated, nil
+}
+
+func (r *PostgresRepository) DeleteMaintenanceWindow(ctx context.Context, id int) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance window: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMaintenanceWindowNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error) {
+	return r.queryMaintenanceWindows(ctx, `
+		SELECT `+maintenanceWindowColumns+`
+		FROM maintenance_windows s
+		ORDER BY s.starts_at DESC NULLS LAST, s.id DESC`)
+}
+
+// ListMaintenanceWindowsFor returns the windows covering an endpoint that haven't ended
+// yet. Whether a recurring one is open right now is decided by the caller.
+func (r *PostgresRepository) ListMaintenanceWindowsFor(ctx context.Context, endpointID int) ([]MaintenanceWindow, error) {
+	return r.queryMaintenanceWindows(ctx, `
+		SELECT `+maintenanceWindowColumns+`
+		FROM endpoints e
+		LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+		JOIN maintenance_windows s ON `+scopeMatches+`
+		WHERE e.id = $1
+			AND (s.starts_at IS NULL OR s.starts_at <= now())
+			AND (s.ends_at IS NULL OR s.ends_at > now())
+		ORDER BY s.id`, endpointID)
+}
+
+const silenceColumns = `s.id, s.scope_type, s.scope_value, s.reason, s.expires_at, s.created_by_id, s.created_by, s.created_at`
+
+func scanSilence(row pgx.Row, sl *Silence) error {
+	return row.Scan(&sl.ID, &sl.ScopeType, &sl.ScopeValue, &sl.Reason, &sl.ExpiresAt, &sl.CreatedByID, &sl.CreatedBy, &sl.CreatedAt)
+}
+
+func (r *PostgresRepository) CreateSilence(ctx context.Context, sl *Silence) (*Silence, error) {
+	var created Silence
+	err := scanSilence(r.db.Pool.QueryRow(ctx, `
+		INSERT INTO alert_silences AS s (scope_type, scope_value, reason, expires_at, created_by_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+silenceColumns,
+		sl.ScopeType, sl.ScopeValue, sl.Reason, sl.ExpiresAt, sl.CreatedByID, sl.CreatedBy,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create silence: %w", err)
+	}
+	return &created, nil
+}
+
+// ExpireSilence moves the expiry to now; already expired silences are left as they were
+func (r *PostgresRepository) ExpireSilence(ctx context.Context, id int) (*Silence, error) {
+	var sl Silence
+	err := scanSilence(r.db.Pool.QueryRow(ctx, `
+		UPDATE alert_silences AS s
+		SET expires_at = LEAST(s.expires_at, now())
+		WHERE s.id = $1
+		RETURNING `+silenceColumns, id), &sl)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSilenceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire silence: %w", err)
+	}
+	return &sl, nil
+}
+
+func (r *PostgresRepository) ListSilences(ctx context.Context, includeExpired bool) ([]Silence, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT `+silenceColumns+`
+		FROM alert_silences s
+		WHERE $1 OR s.expires_at > now()
+		ORDER BY s.expires_at DESC, s.id DESC`, includeExpired)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	silences := []Silence{}
+	for rows.Next() {
+		var sl Silence
+		if err := scanSilence(rows, &sl); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		silences = append(silences, sl)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return silences, nil
+}
+
+// GetActiveSilence returns the unexpired silence covering an endpoint that lasts the
+// longest, or nil if alerts for it aren't silenced
+func (r *PostgresRepository) GetActiveSilence(ctx context.Context, endpointID int) (*Silence, error) {
+	var sl Silence
+	err := scanSilence(r.db.Pool.QueryRow(ctx, `
+		SELECT `+silenceColumns+`
+		FROM endpoints e
+		LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+		JOIN alert_silences s ON `+scopeMatches+`
+		WHERE e.id = $1 AND s.expires_at > now()
+		ORDER BY s.expires_at DESC
+		LIMIT 1`, endpointID), &sl)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query silences for endpoint %v: %w", endpointID, err)
+	}
+	return &sl, nil
+}
+
+// UpdateFlapState counts the up/down state changes in an endpoint's checks over the
+// window. More than threshold changes marks it flapping; it settles once the count
+// drops to half the threshold, so it doesn't flap in and out of flapping. Checks taken
+// during maintenance are ignored. It also returns the alert owed for the state changes
+// withheld while flapping, which is cleared once the endpoint settles.
+func (r *PostgresRepository) UpdateFlapState(ctx context.Context, endpointID int, window time.Duration, threshold int) (flapping bool, wasFlapping bool, owed flapOwed, err error) {
+	err = r.db.Pool.QueryRow(ctx, `
+		WITH changes AS (
+			SELECT COUNT(*) FILTER (WHERE prev IS NOT NULL AND success <> prev) AS n
+			FROM (
+				SELECT success, lag(success) OVER (ORDER BY checked_at, id) AS prev
+				FROM checks
+				WHERE endpoint_id = $1 AND NOT in_maintenance
+					AND checked_at >= now() - $2 * interval '1 second'
+			) t
+		), state AS (
+			SELECT es.endpoint_id, es.is_flapping AS was, es.flap_withheld_alert AS owed,
+				CASE
+					WHEN c.n > $3 THEN true
+					WHEN c.n * 2 <= $3 THEN false
+					ELSE es.is_flapping
+				END AS now_flapping
+			FROM endpoint_stats es, changes c
+			WHERE es.endpoint_id = $1
+			FOR UPDATE OF es
+		)
+		UPDATE endpoint_stats es
+		SET is_flapping = s.now_flapping,
+			flapping_since = CASE
+				WHEN NOT s.now_flapping THEN NULL
+				WHEN s.was THEN es.flapping_since
+				ELSE now()
+			END,
+			flap_withheld_alert = CASE WHEN s.now_flapping THEN es.flap_withheld_alert END
+		FROM state s
+		WHERE es.endpoint_id = s.endpoint_id
+		RETURNING s.now_flapping, s.was, COALESCE(s.owed, '')`,
+		endpointID, int64(window.Seconds()), threshold,
+	).Scan(&flapping, &wasFlapping, &owed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, false, "", nil
+	}
+	if err != nil {
+		return false, false, "", fmt.Errorf("failed to update flap state for endpoint %d: %w", endpointID, err)
+	}
+	return flapping, wasFlapping, owed, nil
+}
+
+// SetFlapWithheldAlert records the alert a flapping endpoint owes once it settles, ""
+// for none.
+func (r *PostgresRepository) SetFlapWithheldAlert(ctx context.Context, endpointID int, owed flapOwed) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		UPDATE endpoint_stats SET flap_withheld_alert = NULLIF($2, '')
+		WHERE endpoint_id = $1 AND is_flapping`, endpointID, string(owed))
+	if err != nil {
+		return fmt.Errorf("failed to record withheld alert for endpoint %d: %w", endpointID, err)
+	}
+	return nil
+}
+
+// GetCertificate returns the stored certificate of an endpoint, or nil if it hasn't
+// been inspected
+func (r *PostgresRepository) GetCertificate(ctx context.Context, endpointID int) (*CertificateInfo, error) {
+	var c CertificateInfo
+	err := r.db.Pool.QueryRow(ctx, `
+		SELECT endpoint_id, subject, issuer, serial_number, dns_names, not_before, not_after,
+			hostname_valid, chain_valid, chain, errors, alerted_threshold_days, checked_at
+		FROM endpoint_certificates
+		WHERE endpoint_id = $1`, endpointID).Scan(
+		&c.EndpointID, &c.Subject, &c.Issuer, &c.SerialNumber, &c.DNSNames, &c.NotBefore, &c.NotAfter,
+		&c.HostnameValid, &c.ChainValid, &c.Chain, &c.Errors, &c.AlertedThreshold, &c.CheckedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query certificate for endpoint %v: %w", endpointID, err)
+	}
+	c.DaysToExpiry = daysUntil(c.NotAfter)
+	return &c, nil
+}
+
+// SaveCertificate replaces the stored certificate of an endpoint
+func (r *PostgresRepository) SaveCertificate(ctx context.Context, c *CertificateInfo) error {
+	_, err := r.db.Pool.Exec(ctx, `
+		INSERT INTO endpoint_certificates (endpoint_id, subject, issuer, serial_number, dns_names, not_before, not_after,
+			hostname_valid, chain_valid, chain, errors, alerted_threshold_days, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (endpoint_id) DO UPDATE
+		SET subject = EXCLUDED.subject,
+			issuer = EXCLUDED.issuer,
+			serial_number = EXCLUDED.serial_number,
+			dns_names = EXCLUDED.dns_names,
+			not_before = EXCLUDED.not_before,
+			not_after = EXCLUDED.not_after,
+			hostname_valid = EXCLUDED.hostname_valid,
+			chain_valid = EXCLUDED.chain_valid,
+			chain = EXCLUDED.chain,
+			errors = EXCLUDED.errors,
+			alerted_threshold_days = EXCLUDED.alerted_threshold_days,
+			checked_at = EXCLUDED.checked_at`,
+		c.EndpointID, c.Subject, c.Issuer, c.SerialNumber, c.DNSNames, c.NotBefore, c.NotAfter,
+		c.HostnameValid, c.ChainValid, c.Chain, c.Errors, c.AlertedThreshold, c.CheckedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save certificate for endpoint %v: %w", c.EndpointID, err)
+	}
+	return nil
+}
+
+// GetEndpointByURL returns the endpoint with the given normalized URL, or nil if there is none
+func (r *PostgresRepository) GetEndpointByURL(ctx context.Context, url string) (*Endpoint, error) {
+	ep := &Endpoint{}
+	err := scanEndpoint(r.db.Pool.QueryRow(ctx, `SELECT `+endpointColumns+` FROM endpoints WHERE url = $1`, url), ep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query endpoint by url: %w", err)
+	}
+	return ep, nil
+}
+
+// heartbeatStateColumns lists the heartbeats columns scanned into a HeartbeatState, in order.
+const heartbeatStateColumns = `endpoint_id, monitoring_since, last_start_at, last_success_at, last_failure_at,
+	last_failure_message, last_duration_ms`
+
+func scanHeartbeatState(row pgx.Row, st *HeartbeatState) error {
+	return row.Scan(&st.EndpointID, &st.MonitoringSince, &st.LastStartAt, &st.LastSuccessAt, &st.LastFailureAt,
+		&st.LastFailureMessage, &st.LastDurationMs)
+}
+
+// GetHeartbeatState returns the ping state of a heartbeat endpoint, or a zero state if
+// it has none.
+func (r *PostgresRepository) GetHeartbeatState(ctx context.Context, endpointID int) (*HeartbeatState, error) {
+	st := HeartbeatState{EndpointID: endpointID}
+	err := scanHeartbeatState(r.db.Pool.QueryRow(ctx, `
+		SELECT `+heartbeatStateColumns+` FROM heartbeats WHERE endpoint_id = $1`, endpointID), &st)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &HeartbeatState{EndpointID: endpointID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load heartbeat state for endpoint %v: %w", endpointID, err)
+	}
+	return &st, nil
+}
+
+// startHeartbeat creates the ping state of an endpoint that has become a heartbeat,
+// which is when monitoring of the heartbeat begins. State left from an earlier time
+// as a heartbeat is reset.
+func startHeartbeat(ctx context.Context, db execer, endpointID int) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO heartbeats (endpoint_id) VALUES ($1)
+		ON CONFLICT (endpoint_id) DO UPDATE
+		SET monitoring_since = NOW(), last_start_at = NULL, last_success_at = NULL, last_failure_at = NULL,
+		    last_failure_message = NULL, last_duration_ms = NULL`, endpointID)
+	if err != nil {
+		return fmt.Errorf("failed to start heartbeat for endpoint %v: %w", endpointID, err)
+	}
+	return nil
+}
+
+// RecordHeartbeatPing stores a ping and moves the heartbeat state on. A success or
+// fail ping that follows a start ping records how long the job ran.
+func (r *PostgresRepository) RecordHeartbeatPing(ctx context.Context, endpointID int, kind, message string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The upsert locks the row, so concurrent pings from one job are applied in turn
+	var st HeartbeatState
+	err = scanHeartbeatState(tx.QueryRow(ctx, `
+		INSERT INTO heartbeats (endpoint_id) VALUES ($1)
+		ON CONFLICT (endpoint_id) DO UPDATE SET endpoint_id = EXCLUDED.endpoint_id
+		RETURNING `+heartbeatStateColumns, endpointID), &st)
+	if err != nil {
+		return fmt.Errorf("failed to load heartbeat state for endpoint %v: %w", endpointID, err)
+	}
+
+	now := time.Now()
+	var duration *int64
+	if kind != PingStart && st.LastStartAt != nil &&
+		(st.LastSuccessAt == nil || st.LastStartAt.After(*st.LastSuccessAt)) &&
+		(st.LastFailureAt == nil || st.LastStartAt.After(*st.LastFailureAt)) {
+		ms := now.Sub(*st.LastStartAt).Milliseconds()
+		duration = &ms
+	}
+
+	var msg *string
+	if message != "" {
+		msg = &message
+	}
+
+	switch kind {
+	case PingStart:
+		_, err = tx.Exec(ctx, `UPDATE heartbeats SET last_start_at = $2 WHERE endpoint_id = $1`, endpointID, now)
+	case PingSuccess:
+		_, err = tx.Exec(ctx, `
+			UPDATE heartbeats SET last_success_at = $2, last_duration_ms = COALESCE($3, last_duration_ms)
+			WHERE endpoint_id = $1`, endpointID, now, duration)
+	case PingFail:
+		_, err = tx.Exec(ctx, `
+			UPDATE heartbeats SET last_failure_at = $2, last_failure_message = $3, last_duration_ms = COALESCE($4, last_duration_ms)
+			WHERE endpoint_id = $1`, endpointID, now, msg, duration)
+	default:
+		return fmt.Errorf("unknown ping kind %q", kind)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update heartbeat state for endpoint %v: %w", endpointID, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO heartbeat_pings (endpoint_id, kind, duration_ms, message, received_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		endpointID, kind, duration, msg, now)
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat ping for endpoint %v: %w", endpointID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListHeartbeatPings returns the most recent pings of a heartbeat endpoint, newest first
+func (r *PostgresRepository) ListHeartbeatPings(ctx context.Context, endpointID int, limit int) ([]HeartbeatPing, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, kind, duration_ms, message, received_at
+		FROM heartbeat_pings
+		WHERE endpoint_id = $1
+		ORDER BY received_at DESC, id DESC
+		LIMIT $2`, endpointID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query heartbeat pings: %w", err)
+	}
+	defer rows.Close()
+
+	pings := []HeartbeatPing{}
+	for rows.Next() {
+		var p HeartbeatPing
+		if err := rows.Scan(&p.ID, &p.Kind, &p.DurationMs, &p.Message, &p.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		pings = append(pings, p)
+	}
+	return pings, rows.Err()
+}
+
+const sloColumns = `s.id, s.name, s.scope_type, s.scope_value, s.kind, s.target, s.latency_threshold_ms, s.window_days,
+	s.alert_level, s.alert_changed_at, s.created_by_id, s.created_by, s.created_at`
+
+func scanSLO(row pgx.Row, slo *SLO) error {
+	return row.Scan(&slo.ID, &slo.Name, &slo.ScopeType, &slo.ScopeValue, &slo.Kind, &slo.Target, &slo.LatencyThresholdMs,
+		&slo.WindowDays, &slo.AlertLevel, &slo.AlertChangedAt, &slo.CreatedByID, &slo.CreatedBy, &slo.CreatedAt)
+}
+
+func (r *PostgresRepository) CreateSLO(ctx context.Context, slo *SLO) (*SLO, error) {
+	var created SLO
+	err := scanSLO(r.db.Pool.QueryRow(ctx, `
+		INSERT INTO slos AS s (name, scope_type, scope_value, kind, target, latency_threshold_ms, window_days, created_by_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+sloColumns,
+		slo.Name, slo.ScopeType, slo.ScopeValue, slo.Kind, slo.Target, slo.LatencyThresholdMs, slo.WindowDays, slo.CreatedByID, slo.CreatedBy,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SLO: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *PostgresRepository) UpdateSLO(ctx context.Context, slo *SLO) (*SLO, error) {
+	var updated SLO
+	err := scanSLO(r.db.Pool.QueryRow(ctx, `
+		UPDATE slos AS s
+		SET name = $2, scope_type = $3, scope_value = $4, kind = $5, target = $6, latency_threshold_ms = $7, window_days = $8
+		WHERE s.id = $1
+		RETURNING `+sloColumns,
+		slo.ID, slo.Name, slo.ScopeType, slo.ScopeValue, slo.Kind, slo.Target, slo.LatencyThresholdMs, slo.WindowDays,
+	), &updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSLONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update SLO: %w", err)
+	}
+	return &updated, nil
+}
+
+func (r *PostgresRepository) DeleteSLO(ctx context.Context, id int) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM slos WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete SLO: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSLONotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetSLO(ctx context.Context, id int) (*SLO, error) {
+	var slo SLO
+	err := scanSLO(r.db.Pool.QueryRow(ctx, `SELECT `+sloColumns+` FROM slos s WHERE s.id = $1`, id), &slo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSLONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SLO: %w", err)
+	}
+	return &slo, nil
+}
+
+func (r *PostgresRepository) ListSLOs(ctx context.Context) ([]SLO, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT `+sloColumns+` FROM slos s ORDER BY s.name, s.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	slos := []SLO{}
+	for rows.Next() {
+		var slo SLO
+		if err := scanSLO(rows, &slo); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		slos = append(slos, slo)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return slos, nil
+}
+
+// GetSLOCounts counts the checks of an SLO's endpoints over each of its lookbacks, in
+// order. Checks during maintenance are left out; a latency SLO only counts successful
+// checks and calls those within the threshold good. An availability SLO's window is the
+// last WindowDays calendar days, today included.
+func (r *PostgresRepository) GetSLOCounts(ctx context.Context, slo *SLO) ([]SLOWindowCounts, error) {
+	lookbacks := slo.lookbacks()
+	seconds := make([]int64, len(lookbacks))
+	for i, d := range lookbacks {
+		seconds[i] = int64(d / time.Second)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT count(c.checked_at), count(*) FILTER (WHERE c.good)
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS w(seconds, ord)
+		LEFT JOIN (
+			SELECT c.checked_at,
+				CASE WHEN s.kind = 'latency' THEN c.latency_ms <= s.latency_threshold_ms ELSE c.success END AS good
+			FROM slos s
+			JOIN endpoints e ON TRUE
+			LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+			JOIN checks c ON c.endpoint_id = e.id
+			WHERE s.id = $1 AND `+scopeMatches+`
+				AND c.checked_at >= now() - s.window_days * interval '1 day'
+				AND NOT c.in_maintenance
+				AND (s.kind = 'availability' OR c.success)
+		) c ON c.checked_at >= now() - w.seconds * interval '1 second'
+		GROUP BY w.ord
+		ORDER BY w.ord`, slo.ID, seconds)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []SLOWindowCounts{}
+	for rows.Next() {
+		var c SLOWindowCounts
+		if err := rows.Scan(&c.Total, &c.Good); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	// Raw checks only cover CHECK_RETENTION_DAYS, so an availability SLO's window is
+	// counted over whole days from the daily rollups and the raw checks not rolled up yet
+	if slo.Kind == SLOAvailability && len(counts) > 0 {
+		if err := r.db.Pool.QueryRow(ctx, `
+			WITH members AS (
+				SELECT e.id AS endpoint_id
+				FROM slos s
+				JOIN endpoints e ON TRUE
+				LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+				WHERE s.id = $1 AND `+scopeMatches+`
+			), rolled AS (
+				SELECT SUM(d.total_checks) AS total, SUM(d.successful_checks) AS good
+				FROM members m
+				JOIN check_rollups_daily d ON d.endpoint_id = m.endpoint_id
+				WHERE d.day > current_date - $2::int
+			), raw AS (
+				SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE c.success) AS good
+				FROM members m
+				JOIN checks c ON c.endpoint_id = m.endpoint_id
+				WHERE c.checked_at >= GREATEST((SELECT MAX(day) + 1 FROM check_rollups_daily), current_date - $2::int + 1)
+					AND NOT c.in_maintenance
+			)
+			SELECT COALESCE(rolled.total, 0) + raw.total, COALESCE(rolled.good, 0) + raw.good
+			FROM rolled, raw`, slo.ID, slo.WindowDays).Scan(&counts[0].Total, &counts[0].Good); err != nil {
+			return nil, fmt.Errorf("query failed: %w", err)
+		}
+	}
+	return counts, nil
+}
+
+// SetSLOAlertLevel moves an SLO from one alert level to another ("" for none). It
+// reports false if the level had already changed, e.g. on another replica.
+func (r *PostgresRepository) SetSLOAlertLevel(ctx context.Context, id int, from, to string) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx, `
+		UPDATE slos SET alert_level = NULLIF($3, ''), alert_changed_at = now()
+		WHERE id = $1 AND COALESCE(alert_level, '') = $2`, id, from, to)
+	if err != nil {
+		return false, fmt.Errorf("failed to set SLO alert level: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+const statusComponentColumns = `s.id, s.display_name, s.description, s.scope_type, s.scope_value, s.position,
+	s.created_by_id, s.created_by, s.created_at`
+
+func scanStatusComponent(row pgx.Row, sc *StatusComponent) error {
+	return row.Scan(&sc.ID, &sc.DisplayName, &sc.Description, &sc.ScopeType, &sc.ScopeValue, &sc.Position,
+		&sc.CreatedByID, &sc.CreatedBy, &sc.CreatedAt)
+}
+
+func (r *PostgresRepository) CreateStatusComponent(ctx context.Context, sc *StatusComponent) (*StatusComponent, error) {
+	var created StatusComponent
+	err := scanStatusComponent(r.db.Pool.QueryRow(ctx, `
+		INSERT INTO status_page_components AS s (display_name, description, scope_type, scope_value, position, created_by_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+statusComponentColumns,
+		sc.DisplayName, sc.Description, sc.ScopeType, sc.ScopeValue, sc.Position, sc.CreatedByID, sc.CreatedBy,
+	), &created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status page component: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *PostgresRepository) UpdateStatusComponent(ctx context.Context, sc *StatusComponent) (*StatusComponent, error) {
+	var updated StatusComponent
+	err := scanStatusComponent(r.db.Pool.QueryRow(ctx, `
+		UPDATE status_page_components AS s
+		SET display_name = $2, description = $3, scope_type = $4, scope_value = $5, position = $6
+		WHERE s.id = $1
+		RETURNING `+statusComponentColumns,
+		sc.ID, sc.DisplayName, sc.Description, sc.ScopeType, sc.ScopeValue, sc.Position,
+	), &updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStatusComponentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update status page component: %w", err)
+	}
+	return &updated, nil
+}
+
+func (r *PostgresRepository) DeleteStatusComponent(ctx context.Context, id int) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM status_page_components WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete status page component: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrStatusComponentNotFound
+	}
+	return nil
+}
+
+// ListStatusComponents returns the components in the order they are shown
+func (r *PostgresRepository) ListStatusComponents(ctx context.Context) ([]StatusComponent, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT `+statusComponentColumns+`
+		FROM status_page_components s
+		ORDER BY s.position, s.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	components := []StatusComponent{}
+	for rows.Next() {
+		var sc StatusComponent
+		if err := scanStatusComponent(rows, &sc); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		components = append(components, sc)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return components, nil
+}
+
+// GetStatusComponentHealth counts, per component, its endpoints, those marked down and
+// those whose last check ran during maintenance
+func (r *PostgresRepository) GetStatusComponentHealth(ctx context.Context) (map[int]componentHealth, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT s.id,
+			COUNT(e.id),
+			COUNT(*) FILTER (WHERE st.is_down),
+			COUNT(*) FILTER (WHERE last.in_maintenance)
+		FROM status_page_components s
+		LEFT JOIN (endpoints e LEFT JOIN endpoint_info i ON i.endpoint_id = e.id) ON `+scopeMatches+`
+		LEFT JOIN endpoint_stats st ON st.endpoint_id = e.id
+		LEFT JOIN LATERAL (
+			SELECT c.in_maintenance FROM checks c
+			WHERE c.endpoint_id = e.id
+			ORDER BY c.checked_at DESC
+			LIMIT 1
+		) last ON TRUE
+		GROUP BY s.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	health := map[int]componentHealth{}
+	for rows.Next() {
+		var id int
+		var h componentHealth
+		if err := rows.Scan(&id, &h.Endpoints, &h.Down, &h.InMaintenance); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		health[id] = h
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return health, nil
+}
+
+// GetStatusComponentDays returns the last `days` days of uptime per component, oldest
+// first. Closed days come from the daily rollups; days not rolled up yet, like today,
+// from raw checks. Checks during maintenance don't count.
+func (r *PostgresRepository) GetStatusComponentDays(ctx context.Context, days int) (map[int][]UptimeDay, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		WITH members AS (
+			SELECT s.id AS component_id, e.id AS endpoint_id
+			FROM status_page_components s
+			JOIN endpoints e ON TRUE
+			LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+			WHERE `+scopeMatches+`
+		), rolled AS (
+			SELECT m.component_id, d.day, SUM(d.total_checks) AS total, SUM(d.successful_checks) AS good
+			FROM members m
+			JOIN check_rollups_daily d ON d.endpoint_id = m.endpoint_id
+			WHERE d.day > current_date - $1::int
+			GROUP BY 1, 2
+		), raw AS (
+			SELECT m.component_id, c.checked_at::date AS day, COUNT(*) AS total, COUNT(*) FILTER (WHERE c.success) AS good
+			FROM members m
+			JOIN checks c ON c.endpoint_id = m.endpoint_id
+			WHERE c.checked_at >= GREATEST((SELECT MAX(day) + 1 FROM check_rollups_daily), current_date - $1::int + 1)
+				AND NOT c.in_maintenance
+			GROUP BY 1, 2
+		), totals AS (
+			SELECT * FROM rolled
+			UNION ALL
+			SELECT * FROM raw
+		)
+		SELECT s.id, g.day::date, COALESCE(t.total, 0), COALESCE(t.good, 0)
+		FROM status_page_components s
+		CROSS JOIN generate_series(current_date - $1::int + 1, current_date, interval '1 day') AS g(day)
+		LEFT JOIN totals t ON t.component_id = s.id AND t.day = g.day::date
+		ORDER BY s.id, g.day`, days)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	result := map[int][]UptimeDay{}
+	for rows.Next() {
+		var id int
+		var day time.Time
+		var d UptimeDay
+		if err := rows.Scan(&id, &day, &d.TotalChecks, &d.GoodChecks); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		d.Date = day.Format("2006-01-02")
+		if d.TotalChecks > 0 {
+			uptime := float64(d.GoodChecks) / float64(d.TotalChecks) * 100
+			d.Uptime = &uptime
+		}
+		result[id] = append(result[id], d)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return result, nil
+}
+
+// ListStatusPageIncidents returns the unresolved incidents of published endpoints and
+// those started in the last `days` days, newest first, with the components they affect
+func (r *PostgresRepository) ListStatusPageIncidents(ctx context.Context, days, limit int) ([]publicIncidentRow, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT inc.id, inc.started_at, inc.resolved_at, array_agg(DISTINCT s.id ORDER BY s.id)
+		FROM status_page_components s
+		JOIN endpoints e ON TRUE
+		LEFT JOIN endpoint_info i ON i.endpoint_id = e.id
+		JOIN incidents inc ON inc.endpoint_id = e.id
+		WHERE `+scopeMatches+`
+			AND (inc.resolved_at IS NULL OR inc.started_at >= now() - $1 * interval '1 day')
+		GROUP BY inc.id
+		ORDER BY inc.started_at DESC
+		LIMIT $2`, days, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []publicIncidentRow{}
+	for rows.Next() {
+		var inc publicIncidentRow
+		if err := rows.Scan(&inc.ID, &inc.StartedAt, &inc.ResolvedAt, &inc.ComponentIDs); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		inc.Status = "ongoing"
+		if inc.ResolvedAt != nil {
+			inc.Status = "resolved"
+			duration := int64(inc.ResolvedAt.Sub(inc.StartedAt).Seconds())
+			inc.DurationSeconds = &duration
+		}
+		incidents = append(incidents, inc)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return incidents, nil
+}
+
+// ListEndpointsWithInfo returns every endpoint together with its endpoint_info metadata
+func (r *PostgresRepository) ListEndpointsWithInfo(ctx context.Context) ([]Endpoint, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT `+endpointColumns+`,
+			i.gitlab_url, i.docker_container_name, i.kubernetes_pod_name, i.tags, i.description, i.last_changed_by
+		FROM endpoints e
+		LEFT JOIN (
+			SELECT endpoint_id, gitlab_url, docker_container_name, kubernetes_pod_name, tags, description, last_changed_by
+			FROM endpoint_info
+		) i ON i.endpoint_id = e.id
+		ORDER BY e.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		var ep Endpoint
+		err := rows.Scan(&ep.ID, &ep.ServiceName, &ep.URL, &ep.ServerName, &ep.APIMethod, &ep.ExpectedCode,
+			&ep.Headers, &ep.QueryParams, &ep.BodyTemplate, &ep.Assertions, &ep.CheckIntervalSeconds, &ep.TimeoutSeconds,
+			&ep.CheckType, &ep.CheckConfig,
+			&ep.GitlabURL, &ep.DockerContainerName, &ep.KubernetesPodName, &ep.Tags, &ep.Description, &ep.LastChangedBy)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return endpoints, nil
+}
+
+// registerServer adds the server an endpoint names if it isn't registered yet, so
+// server_name always refers to a server
+func registerServer(ctx context.Context, db execer, name string) error {
+	_, err := db.Exec(ctx, `INSERT INTO servers (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name)
+	if err != nil {
+		return fmt.Errorf("failed to register server %q: %w", name, err)
+	}
+	return nil
+}
+
+const serverColumns = `s.id, s.name, s.ip_address, s.environment, s.owner, s.tags, s.description,
+	s.is_down, s.down_since, s.created_at, s.updated_at`
+
+func scanServer(row pgx.Row, srv *Server) error {
+	return row.Scan(&srv.ID, &srv.Name, &srv.IPAddress, &srv.Environment, &srv.Owner, &srv.Tags, &srv.Description,
+		&srv.IsDown, &srv.DownSince, &srv.CreatedAt, &srv.UpdatedAt)
+}
+
+// serverWriteError maps constraint violations on servers to their sentinel errors
+func serverWriteError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrServerExists
+		case "23503":
+			return ErrServerInUse
+		}
+	}
+	return fmt.Errorf("failed to %s server: %w", action, err)
+}
+
+func (r *PostgresRepository) CreateServer(ctx context.Context, srv *Server) (*Server, error) {
+	var created Server
+	err := scanServer(r.db.Pool.QueryRow(ctx, `
+		INSERT INTO servers AS s (name, ip_address, environment, owner, tags, description)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+serverColumns,
+		srv.Name, srv.IPAddress, srv.Environment, srv.Owner, srv.Tags, srv.Description,
+	), &created)
+	if err != nil {
+		return nil, serverWriteError("create", err)
+	}
+	return &created, nil
+}
+
+// UpdateServer replaces a server's details. Its endpoints follow a rename through the
+// foreign key; maintenance windows, silences, status page components and notification
+// routes scoped to the old name are moved over in the same transaction.
+func (r *PostgresRepository) UpdateServer(ctx context.Context, srv *Server) (*Server, bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldName string
+	err = tx.QueryRow(ctx, `SELECT name FROM servers WHERE id = $1 FOR UPDATE`, srv.ID).Scan(&oldName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, ErrServerNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get server: %w", err)
+	}
+
+	var updated Server
+	err = scanServer(tx.QueryRow(ctx, `
+		UPDATE servers AS s
+		SET name = $2, ip_address = $3, environment = $4, owner = $5, tags = $6, description = $7, updated_at = now()
+		WHERE s.id = $1
+		RETURNING `+serverColumns,
+		srv.ID, srv.Name, srv.IPAddress, srv.Environment, srv.Owner, srv.Tags, srv.Description,
+	), &updated)
+	if err != nil {
+		return nil, false, serverWriteError("update", err)
+	}
+
+	renamed := oldName != updated.Name
+	if renamed {
+		for _, table := range []string{"maintenance_windows", "alert_silences", "status_page_components", "notification_routes"} {
+			_, err := tx.Exec(ctx, `UPDATE `+table+` SET scope_value = $2 WHERE scope_type = 'server' AND scope_value = $1`,
+				oldName, updated.Name)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to rename server scope in %s: %w", table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &updated, renamed, nil
+}
+
+// DeleteServer removes a server. It fails with ErrServerInUse while endpoints still
+// belong to it.
+func (r *PostgresRepository) DeleteServer(ctx context.Context, id int) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM servers WHERE id = $1`, id)
+	if err != nil {
+		return serverWriteError("delete", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServerNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetServerByName(ctx context.Context, name string) (*Server, error) {
+	var srv Server
+	err := scanServer(r.db.Pool.QueryRow(ctx, `SELECT `+serverColumns+` FROM servers s WHERE s.name = $1`, name), &srv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrServerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+	return &srv, nil
+}
+
+// serverHealthQuery aggregates each server's endpoints: how many there are and are up,
+// the slowest last check and the open incidents. %s is the WHERE clause.
+const serverHealthQuery = `
+	SELECT ` + serverColumns + `,
+		COUNT(e.id),
+		COUNT(e.id) FILTER (WHERE NOT COALESCE(st.is_down, false)),
+		MAX(last.latency_ms),
+		(ARRAY_AGG(e.id ORDER BY last.latency_ms DESC NULLS LAST))[1],
+		COUNT(i.id)
+	FROM servers s
+	LEFT JOIN endpoints e ON e.server_name = s.name
+	LEFT JOIN endpoint_stats st ON st.endpoint_id = e.id
+	LEFT JOIN LATERAL (
+		SELECT c.latency_ms FROM checks c
+		WHERE c.endpoint_id = e.id
+		ORDER BY c.checked_at DESC
+		LIMIT 1
+	) last ON TRUE
+	LEFT JOIN incidents i ON i.endpoint_id = e.id AND i.resolved_at IS NULL
+	%s
+	GROUP BY s.id
+	ORDER BY s.name`
+
+func scanServerHealth(row pgx.Row, h *ServerHealth) error {
+	err := row.Scan(&h.ID, &h.Name, &h.IPAddress, &h.Environment, &h.Owner, &h.Tags, &h.Description,
+		&h.IsDown, &h.DownSince, &h.CreatedAt, &h.UpdatedAt,
+		&h.Endpoints, &h.EndpointsUp, &h.WorstLatencyMs, &h.WorstLatencyEndpointID, &h.OpenIncidents)
+	if err != nil {
+		return err
+	}
+	if h.WorstLatencyMs == nil {
+		h.WorstLatencyEndpointID = nil
+	}
+	h.computeUpPercentage()
+	return nil
+}
+
+// ListServerHealth returns every server matching the filter with its aggregated health
+func (r *PostgresRepository) ListServerHealth(ctx context.Context, filter ServerFilter) ([]ServerHealth, error) {
+	rows, err := r.db.Pool.Query(ctx, fmt.Sprintf(serverHealthQuery, `
+		WHERE ($1 = '' OR s.environment = $1)
+			AND ($2 = '' OR $2 = ANY(s.tags))`),
+		filter.Environment, filter.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	servers := []ServerHealth{}
+	for rows.Next() {
+		var h ServerHealth
+		if err := scanServerHealth(rows, &h); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		servers = append(servers, h)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return servers, nil
+}
+
+func (r *PostgresRepository) GetServerHealth(ctx context.Context, id int) (*ServerHealth, error) {
+	var h ServerHealth
+	err := scanServerHealth(r.db.Pool.QueryRow(ctx, fmt.Sprintf(serverHealthQuery, `WHERE s.id = $1`), id), &h)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrServerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+	return &h, nil
+}
+
+// ListServerEndpoints returns the endpoints of a server with their down state and the
+// latency of their last check
+func (r *PostgresRepository) ListServerEndpoints(ctx context.Context, name string) ([]ServerEndpoint, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT e.id, e.service_name, e.url, e.check_type, COALESCE(st.is_down, false), st.down_since, last.latency_ms
+		FROM endpoints e
+		LEFT JOIN endpoint_stats st ON st.endpoint_id = e.id
+		LEFT JOIN LATERAL (
+			SELECT c.latency_ms FROM checks c
+			WHERE c.endpoint_id = e.id
+			ORDER BY c.checked_at DESC
+			LIMIT 1
+		) last ON TRUE
+		WHERE e.server_name = $1
+		ORDER BY e.service_name, e.id`, name)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []ServerEndpoint{}
+	for rows.Next() {
+		var se ServerEndpoint
+		err := rows.Scan(&se.ID, &se.ServiceName, &se.URL, &se.CheckType, &se.IsDown, &se.DownSince, &se.LastLatencyMs)
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		endpoints = append(endpoints, se)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return endpoints, nil
+}
+
+// MarkServerDown flips a server to down once every one of its endpoints is. down_since
+// is when the first of them went down. changed is false if the server was already down
+// or some endpoint is still up.
+func (r *PostgresRepository) MarkServerDown(ctx context.Context, name string) (*Server, bool, error) {
+	var srv Server
+	err := scanServer(r.db.Pool.QueryRow(ctx, `
+		UPDATE servers AS s
+		SET is_down = true,
+			down_since = COALESCE((
+				SELECT MIN(st.down_since) FROM endpoints e
+				JOIN endpoint_stats st ON st.endpoint_id = e.id
+				WHERE e.server_name = s.name
+			), now())
+		WHERE s.name = $1 AND NOT s.is_down
+			AND EXISTS (SELECT 1 FROM endpoints e WHERE e.server_name = s.name)
+			AND NOT EXISTS (
+				SELECT 1 FROM endpoints e
+				LEFT JOIN endpoint_stats st ON st.endpoint_id = e.id
+				WHERE e.server_name = s.name AND NOT COALESCE(st.is_down, false)
+			)
+		RETURNING `+serverColumns, name), &srv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to mark server %q down: %w", name, err)
+	}
+	return &srv, true, nil
+}
+
+// MarkServerRecovered flips a server back to up and returns it with the time its
+// outage started. changed is false if it wasn't down.
+func (r *PostgresRepository) MarkServerRecovered(ctx context.Context, name string) (*Server, bool, error) {
+	var srv Server
+	err := scanServer(r.db.Pool.QueryRow(ctx, `
+		WITH prev AS (
+			SELECT id, down_since FROM servers
+			WHERE name = $1 AND is_down
+			FOR UPDATE
+		)
+		UPDATE servers AS s
+		SET is_down = false, down_since = NULL
+		FROM prev
+		WHERE s.id = prev.id
+		RETURNING s.id, s.name, s.ip_address, s.environment, s.owner, s.tags, s.description,
+			s.is_down, prev.down_since, s.created_at, s.updated_at`, name), &srv)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to mark server %q recovered: %w", name, err)
+	}
+	return &srv, true, nil
+}
+
+const dependencyColumns = `d.endpoint_id, d.depends_on_id, d.source, d.created_by, d.created_at`
+
+func scanDependency(row pgx.Row, dep *EndpointDependency) error {
+	return row.Scan(&dep.EndpointID, &dep.DependsOnID, &dep.Source, &dep.CreatedBy, &dep.CreatedAt)
+}
+
+// AddDependency stores a dependency unless it would close a cycle. A manual dependency
+// takes over a pipeline one; a pipeline dependency never replaces an existing one, in
+// which case nil is returned. created is true for a new dependency.
+func (r *PostgresRepository) AddDependency(ctx context.Context, dep EndpointDependency) (*EndpointDependency, bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Keeps a concurrent insert from closing a cycle the check below can't see yet
+	if _, err := tx.Exec(ctx, `LOCK TABLE endpoint_dependencies IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, false, fmt.Errorf("failed to lock dependencies: %w", err)
+	}
+
+	var cycle bool
+	err = tx.QueryRow(ctx, `
+		WITH RECURSIVE upstream AS (
+			SELECT $2::int AS id
+			UNION
+			SELECT d.depends_on_id FROM endpoint_dependencies d JOIN upstream u ON d.endpoint_id = u.id
+		)
+		SELECT EXISTS (SELECT 1 FROM upstream WHERE id = $1)`, dep.EndpointID, dep.DependsOnID).Scan(&cycle)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check for dependency cycles: %w", err)
+	}
+	if cycle {
+		return nil, false, ErrDependencyCycle
+	}
+
+	var stored EndpointDependency
+	var created bool
+	err = tx.QueryRow(ctx, `
+		INSERT INTO endpoint_dependencies AS d (endpoint_id, depends_on_id, source, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (endpoint_id, depends_on_id) DO UPDATE
+		SET source = EXCLUDED.source
+		WHERE EXCLUDED.source = 'manual'
+		RETURNING `+dependencyColumns+`, (xmax = 0)`,
+		dep.EndpointID, dep.DependsOnID, dep.Source, dep.CreatedBy,
+	).Scan(&stored.EndpointID, &stored.DependsOnID, &stored.Source, &stored.CreatedBy, &stored.CreatedAt, &created)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return nil, false, ErrEndpointNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to add dependency: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &stored, created, nil
+}
+
+func (r *PostgresRepository) RemoveDependency(ctx context.Context, endpointID, dependsOnID int) error {
+	tag, err := r.db.Pool.Exec(ctx, `
+		DELETE FROM endpoint_dependencies WHERE endpoint_id = $1 AND depends_on_id = $2`, endpointID, dependsOnID)
+	if err != nil {
+		return fmt.Errorf("failed to remove dependency: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDependencyNotFound
+	}
+	return nil
+}
+
+// GetDependencyGraph returns every endpoint with its down state and every dependency
+func (r *PostgresRepository) GetDependencyGraph(ctx context.Context) (*DependencyGraph, error) {
+	graph := &DependencyGraph{Nodes: []DependencyNode{}, Edges: []EndpointDependency{}}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT e.id, e.service_name, e.server_name, COALESCE(st.is_down, false)
+		FROM endpoints e
+		LEFT JOIN endpoint_stats st ON st.endpoint_id = e.id
+		ORDER BY e.id`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n DependencyNode
+		if err := rows.Scan(&n.ID, &n.ServiceName, &n.ServerName, &n.IsDown); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+
+	rows, err = r.db.Pool.Query(ctx, `
+		SELECT `+dependencyColumns+`
+		FROM endpoint_dependencies d
+		ORDER BY d.endpoint_id, d.depends_on_id`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dep EndpointDependency
+		if err := scanDependency(rows, &dep); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		graph.Edges = append(graph.Edges, dep)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return graph, nil
+}
+
+// gitlabURLKey normalises a GitLab URL column so repository URLs match however they
+// were entered.
+const gitlabURLKey = `lower(rtrim(regexp_replace(trim(%s), '\.git/?$', ''), '/'))`
+
+// ListPipelineDependencies derives dependencies from pipeline units: endpoints of the
+// macro service depend on the endpoints of each of its micro services. Endpoints are
+// matched to services through their GitLab URL.
+func (r *PostgresRepository) ListPipelineDependencies(ctx context.Context) ([]EndpointDependency, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT DISTINCT macro_ep.endpoint_id, micro_ep.endpoint_id
+		FROM pipeline_units pu
+		JOIN services macro ON macro.id = pu.macro_service_id
+		JOIN pipeline_dependencies pd ON pd.pipeline_unit_id = pu.id
+		JOIN services micro ON micro.id = pd.micro_service_id
+		JOIN endpoint_info macro_ep ON `+fmt.Sprintf(gitlabURLKey, "macro_ep.gitlab_url")+` = `+fmt.Sprintf(gitlabURLKey, "macro.url")+`
+		JOIN endpoint_info micro_ep ON `+fmt.Sprintf(gitlabURLKey, "micro_ep.gitlab_url")+` = `+fmt.Sprintf(gitlabURLKey, "micro.url")+`
+		WHERE macro_ep.endpoint_id <> micro_ep.endpoint_id
+		ORDER BY 1, 2`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	deps := []EndpointDependency{}
+	for rows.Next() {
+		var dep EndpointDependency
+		if err := rows.Scan(&dep.EndpointID, &dep.DependsOnID); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		deps = append(deps, dep)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return deps, nil
+}
+
+// FindRootCause follows an endpoint's dependencies through those that are down and
+// returns the furthest one, where the outage most likely started. Among equally far
+// ones the first to go down wins. It returns nil when no dependency is down.
+func (r *PostgresRepository) FindRootCause(ctx context.Context, endpointID int) (*RootCause, error) {
+	var rc RootCause
+	err := r.db.Pool.QueryRow(ctx, `
+		WITH RECURSIVE upstream AS (
+			SELECT d.depends_on_id AS id, 1 AS depth, ARRAY[d.endpoint_id, d.depends_on_id] AS path
+			FROM endpoint_dependencies d
+			JOIN endpoint_stats st ON st.endpoint_id = d.depends_on_id AND st.is_down
+			WHERE d.endpoint_id = $1
+			UNION ALL
+			SELECT d.depends_on_id, u.depth + 1, u.path || d.depends_on_id
+			FROM upstream u
+			JOIN endpoint_dependencies d ON d.endpoint_id = u.id
+			JOIN endpoint_stats st ON st.endpoint_id = d.depends_on_id AND st.is_down
+			WHERE NOT d.depends_on_id = ANY(u.path)
+		)
+		SELECT e.id, e.service_name
+		FROM upstream u
+		JOIN endpoints e ON e.id = u.id
+		JOIN endpoint_stats st ON st.endpoint_id = u.id
+		ORDER BY u.depth DESC, st.down_since NULLS LAST, e.id
+		LIMIT 1`, endpointID).Scan(&rc.EndpointID, &rc.ServiceName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find root cause: %w", err)
+	}
+	return &rc, nil
+}
+
+// MarkRootCauseRecovered records that the root cause of an endpoint's open incident is
+// up again while the endpoint is not. released is true the first time, when the
+// endpoint's held back alerts are due.
+func (r *PostgresRepository) MarkRootCauseRecovered(ctx context.Context, endpointID int) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx, `
+		UPDATE incidents i
+		SET root_cause_recovered_at = now()
+		WHERE i.endpoint_id = $1 AND i.resolved_at IS NULL
+			AND i.root_cause_endpoint_id IS NOT NULL AND i.root_cause_recovered_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM endpoint_stats st
+				WHERE st.endpoint_id = i.root_cause_endpoint_id AND st.is_down
+			)`, endpointID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update root cause of endpoint %d: %w", endpointID, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
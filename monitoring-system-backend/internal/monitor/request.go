@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var allowedMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// bodyTemplateData is what a body template can reference, e.g. {{.ServiceName}} or {{.RequestID}}.
+// Values are inserted as is; in a JSON body use the json function, e.g.
+// {"service": {{json .ServiceName}}}, so quotes and backslashes are escaped.
+type bodyTemplateData struct {
+	ServiceName string
+	ServerName  string
+	URL         string
+	Now         time.Time
+	Timestamp   int64
+	RequestID   string
+}
+
+// bodyTemplateFuncs are the functions a body template can call.
+var bodyTemplateFuncs = template.FuncMap{
+	// json renders a value as JSON, quotes included for strings
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parseBodyTemplate parses a body template with bodyTemplateFuncs available.
+func parseBodyTemplate(text string) (*template.Template, error) {
+	return template.New("body").Option("missingkey=error").Funcs(bodyTemplateFuncs).Parse(text)
+}
+
+// normalizeMethod upper-cases the configured method, falling back to GET when it is empty.
+func normalizeMethod(method string) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		return http.MethodGet
+	}
+	return method
+}
+
+// ValidateRequestConfig makes sure a request configuration can actually be sent
+// before it is stored against an endpoint.
+func ValidateRequestConfig(cfg RequestConfig) error {
+	method := normalizeMethod(cfg.APIMethod)
+	if !allowedMethods[method] {
+		return fmt.Errorf("unsupported api_method %q", cfg.APIMethod)
+	}
+
+	for k := range cfg.Headers {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("header names cannot be empty")
+		}
+	}
+
+	if cfg.BodyTemplate != nil && *cfg.BodyTemplate != "" {
+		if _, err := parseBodyTemplate(*cfg.BodyTemplate); err != nil {
+			return fmt.Errorf("invalid body_template: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func renderBodyTemplate(ep *Endpoint) (string, error) {
+	tmpl, err := parseBodyTemplate(*ep.BodyTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid body_template: %w", err)
+	}
+
+	now := time.Now()
+	data := bodyTemplateData{
+		ServiceName: ep.ServiceName,
+		ServerName:  ep.ServerName,
+		URL:         ep.URL,
+		Now:         now,
+		Timestamp:   now.Unix(),
+		RequestID:   uuid.NewString(),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render body_template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// buildCheckRequest assembles the outbound request for an endpoint check from its
+// configured method, query params, headers and body template. The scheduled and
+// on-demand check paths both go through here so they always send the same request.
+func buildCheckRequest(ctx context.Context, ep *Endpoint) (*http.Request, error) {
+	method := normalizeMethod(ep.APIMethod)
+
+	u, err := url.Parse(ep.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint url %q: %w", ep.URL, err)
+	}
+
+	if len(ep.QueryParams) > 0 {
+		q := u.Query()
+		for k, v := range ep.QueryParams {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var body io.Reader
+	if ep.BodyTemplate != nil && *ep.BodyTemplate != "" {
+		rendered, err := renderBodyTemplate(ep)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(rendered)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range ep.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retentionLockKey is the advisory lock held while a retention run is in progress, so
+// only one replica rolls up and prunes at a time.
+const retentionLockKey int64 = schedulerLockKey + 1
+
+const (
+	defaultCheckRetentionDays        = 30
+	defaultHourlyRollupRetentionDays = 90
+	defaultRetentionInterval         = time.Hour
+
+	// minHourlyRollupRetentionDays keeps enough hourly rollups for the 30d window
+	minHourlyRollupRetentionDays = 31
+)
+
+var ErrRetentionRunning = errors.New("a retention run is already in progress")
+
+// RetentionConfig controls how long raw checks and hourly rollups are kept. Daily
+// rollups are kept forever.
+type RetentionConfig struct {
+	CheckRetentionDays        int            `json:"check_retention_days"`
+	TagRetentionDays          map[string]int `json:"tag_retention_days"`
+	HourlyRollupRetentionDays int            `json:"hourly_rollup_retention_days"`
+	Interval                  time.Duration  `json:"-"`
+}
+
+// LoadRetentionConfig reads the retention settings from the environment:
+// CHECK_RETENTION_DAYS, CHECK_RETENTION_TAGS (e.g. "critical=180,payments=90"),
+// HOURLY_ROLLUP_RETENTION_DAYS and RETENTION_JOB_INTERVAL.
+func LoadRetentionConfig() (RetentionConfig, error) {
+	cfg := RetentionConfig{
+		CheckRetentionDays:        defaultCheckRetentionDays,
+		TagRetentionDays:          map[string]int{},
+		HourlyRollupRetentionDays: defaultHourlyRollupRetentionDays,
+		Interval:                  envSeconds("RETENTION_JOB_INTERVAL", defaultRetentionInterval),
+	}
+
+	if raw := os.Getenv("CHECK_RETENTION_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 {
+			return cfg, fmt.Errorf("CHECK_RETENTION_DAYS must be a positive number of days")
+		}
+		cfg.CheckRetentionDays = days
+	}
+
+	if raw := os.Getenv("HOURLY_ROLLUP_RETENTION_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < minHourlyRollupRetentionDays {
+			return cfg, fmt.Errorf("HOURLY_ROLLUP_RETENTION_DAYS must be at least %d days", minHourlyRollupRetentionDays)
+		}
+		cfg.HourlyRollupRetentionDays = days
+	}
+
+	for _, pair := range strings.Split(os.Getenv("CHECK_RETENTION_TAGS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tag, rawDays, ok := strings.Cut(pair, "=")
+		days, err := strconv.Atoi(strings.TrimSpace(rawDays))
+		if !ok || strings.TrimSpace(tag) == "" || err != nil || days < 1 {
+			return cfg, fmt.Errorf("CHECK_RETENTION_TAGS entry %q must look like tag=days", pair)
+		}
+		cfg.TagRetentionDays[strings.TrimSpace(tag)] = days
+	}
+
+	return cfg, nil
+}
+
+// RunRetentionJob rolls up and prunes check data every RETENTION_JOB_INTERVAL until
+// ctx is cancelled. Every replica runs the loop; the advisory lock makes sure only one
+// of them does the work per tick.
+func (s *Service) RunRetentionJob(ctx context.Context) {
+	cfg, err := LoadRetentionConfig()
+	if err != nil {
+		log.Printf("Retention job disabled: %v", err)
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RunRetention(ctx, "scheduled", nil); err != nil && !errors.Is(err, ErrRetentionRunning) {
+				log.Printf("Retention run failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunRetention rolls raw checks up into hourly and daily rollups, then prunes raw
+// checks and hourly rollups past their retention. Failed runs are recorded too.
+func (s *Service) RunRetention(ctx context.Context, trigger string, triggeredBy *string) (*RetentionRun, error) {
+	cfg, err := LoadRetentionConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &RetentionRun{
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+
+	err = s.dbRepo.RunRetention(ctx, cfg, run)
+	if errors.Is(err, ErrRetentionRunning) {
+		return nil, err
+	}
+	if err != nil {
+		msg := err.Error()
+		run.Error = &msg
+		run.PrunedByEndpoint = nil
+		if recErr := s.dbRepo.RecordRetentionRun(ctx, run); recErr != nil {
+			log.Printf("Failed to record retention run: %v", recErr)
+		}
+		return run, err
+	}
+
+	log.Printf("Retention run finished: %d hourly and %d daily rollups written, %d checks and %d hourly rollups pruned",
+		run.HourlyRollups, run.DailyRollups, run.ChecksPruned, run.HourlyRollupsPruned)
+	return run, nil
+}
+
+// ListRetentionRuns returns the most recent retention runs, newest first
+func (s *Service) ListRetentionRuns(ctx context.Context, limit int) ([]RetentionRun, error) {
+	return s.dbRepo.ListRetentionRuns(ctx, limit)
+}
@@ -2,15 +2,99 @@ package monitor
 
 import (
     "context"
+    "errors"
     "log"
+    "math/rand"
     "os"
+    "sort"
     "strconv"
+    "sync"
     "time"
+
+    "github.com/badgerv/monitoring-api/internal/metrics"
+)
+
+var (
+    ErrSchedulerRunning    = errors.New("endpoints check already running")
+    ErrSchedulerNotRunning = errors.New("no endpoints check running")
 )
 
+// defaultResyncInterval is how often the scheduler diffs its working set against the
+// database, to catch endpoints changed outside this process.
+const defaultResyncInterval = 60 * time.Second
+
+// defaultCheckTimeout applies to endpoints without their own timeout_seconds.
+const defaultCheckTimeout = 5 * time.Second
+
+// defaultWorkers bounds how many checks run at the same time.
+const defaultWorkers = 10
+
+// dispatchInterval is the resolution at which due endpoints are picked up.
+const dispatchInterval = time.Second
+
+// envSeconds reads a positive number of seconds from the environment.
+func envSeconds(name string, fallback time.Duration) time.Duration {
+    if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+        return time.Duration(v) * time.Second
+    }
+    return fallback
+}
+
+// checkTimeout is the per-endpoint request timeout, falling back to CHECK_TIMEOUT.
+func (ep *Endpoint) checkTimeout() time.Duration {
+    if ep.TimeoutSeconds != nil && *ep.TimeoutSeconds > 0 {
+        return time.Duration(*ep.TimeoutSeconds) * time.Second
+    }
+    return envSeconds("CHECK_TIMEOUT", defaultCheckTimeout)
+}
+
+// checkInterval is the per-endpoint interval, falling back to the global CHECK_TIMER.
+func (ep *Endpoint) checkInterval(fallback time.Duration) time.Duration {
+    if ep.CheckIntervalSeconds != nil && *ep.CheckIntervalSeconds > 0 {
+        return time.Duration(*ep.CheckIntervalSeconds) * time.Second
+    }
+    return fallback
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+    if d <= 0 {
+        return 0
+    }
+    return time.Duration(rand.Int63n(int64(d)))
+}
+
+// scheduledEndpoint tracks when an endpoint was last checked and when it is due next.
+type scheduledEndpoint struct {
+    ep        Endpoint
+    interval  time.Duration
+    lastCheck time.Time
+    nextCheck time.Time
+    running   bool
+}
+
+// EndpointSchedule is the scheduler's view of one endpoint, returned by check-scheduler-status.
+type EndpointSchedule struct {
+    EndpointID      int        `json:"endpoint_id"`
+    ServiceName     string     `json:"service_name"`
+    URL             string     `json:"url"`
+    IntervalSeconds int        `json:"interval_seconds"`
+    TimeoutSeconds  int        `json:"timeout_seconds"`
+    LastCheckAt     *time.Time `json:"last_check_at"`
+    NextCheckAt     time.Time  `json:"next_check_at"`
+    Running         bool       `json:"running"`
+}
+
 type Scheduler struct {
     cancel context.CancelFunc
     done   chan struct{}
+
+    defaultInterval time.Duration
+    workers         int
+    elector         *LeaderElector
+
+    mu        sync.RWMutex
+    endpoints map[int]*scheduledEndpoint
 }
 
 func (s *Service) StartScheduler(ctx context.Context) (*Scheduler, error) {
@@ -19,44 +103,228 @@ func (s *Service) StartScheduler(ctx context.Context) (*Scheduler, error) {
         return nil, err
     }
 
+    resync := envSeconds("SCHEDULER_RESYNC_INTERVAL", defaultResyncInterval)
+
+    workers := defaultWorkers
+    if v, err := strconv.Atoi(os.Getenv("CHECK_WORKERS")); err == nil && v > 0 {
+        workers = v
+    }
+
     endpoints, err := s.LoadAndSyncEndpoints("endpoints.json")
     if err != nil {
         return nil, err
     }
 
     ctx, cancel := context.WithCancel(ctx)
-    ticker := time.NewTicker(time.Duration(timer) * time.Second)
     done := make(chan struct{})
 
+    sch := &Scheduler{
+        cancel:          cancel,
+        done:            done,
+        defaultInterval: time.Duration(timer) * time.Second,
+        workers:         workers,
+        elector:         NewLeaderElector(s.db.Pool),
+        endpoints:       make(map[int]*scheduledEndpoint),
+    }
+    // Spread the first round over each endpoint's interval so 70+ services don't fire at once
+    sch.replaceEndpoints(endpoints, true)
+
+    // Every replica keeps its working set in sync, but only the leader dispatches checks
+    electorDone := make(chan struct{})
+    go func() {
+        defer close(electorDone)
+        sch.elector.Run(ctx)
+    }()
+
+    jobs := make(chan Endpoint, workers)
+    var wg sync.WaitGroup
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for ep := range jobs {
+                s.runScheduledCheck(ctx, sch, ep)
+            }
+        }()
+    }
+
     go func() {
+        ticker := time.NewTicker(dispatchInterval)
+        resyncTicker := time.NewTicker(resync)
+
         defer close(done)
+        defer metrics.ForgetEndpoints()
+        defer func() { <-electorDone }()
+        defer wg.Wait()
+        defer close(jobs)
         defer ticker.Stop()
+        defer resyncTicker.Stop()
         defer log.Println("Scheduler stopped")
 
         for {
             select {
             case <-ctx.Done():
                 return
-            case <-ticker.C:
-                for _, ep := range endpoints {
-                    select {
-                    case <-ctx.Done():
-                        return
-                    default:
-                        go func(e Endpoint) {
-                            reqCtx, cancelReq := context.WithTimeout(ctx, 5*time.Second)
-                            defer cancelReq()
-                            if err := s.CheckEndpoint(reqCtx, e.ID, e.URL, e.APIMethod, e.ServerName, e.ServiceName); err != nil {
-                                log.Println("Error Checking", e.URL, ":", err)
-                            }
-                        }(ep)
-                    }
+            case <-s.changes:
+                s.reconcile(ctx, sch)
+            case <-resyncTicker.C:
+                s.reconcile(ctx, sch)
+            case now := <-ticker.C:
+                if sch.elector.IsLeader() {
+                    sch.dispatchDue(ctx, now, jobs)
                 }
             }
         }
     }()
 
-    return &Scheduler{cancel: cancel, done: done}, nil
+    return sch, nil
+}
+
+// runScheduledCheck performs one check, with room for its confirmation retries, and
+// records when it ran.
+func (s *Service) runScheduledCheck(ctx context.Context, sch *Scheduler, ep Endpoint) {
+    reqCtx, cancelReq := context.WithTimeout(ctx, ep.confirmBudget())
+    defer cancelReq()
+
+    if err := s.CheckEndpoint(reqCtx, ep); err != nil {
+        log.Println("Error Checking", ep.URL, ":", err)
+    }
+
+    sch.mu.Lock()
+    if se, ok := sch.endpoints[ep.ID]; ok {
+        se.lastCheck = time.Now()
+        se.running = false
+    }
+    sch.mu.Unlock()
+}
+
+// dispatchDue hands every endpoint whose next check time has passed to the worker pool.
+// When the pool is saturated the endpoint stays due and is retried on the next dispatch.
+func (sch *Scheduler) dispatchDue(ctx context.Context, now time.Time, jobs chan<- Endpoint) {
+    sch.mu.Lock()
+    defer sch.mu.Unlock()
+
+    for _, se := range sch.endpoints {
+        if se.running || now.Before(se.nextCheck) {
+            continue
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case jobs <- se.ep:
+            se.running = true
+            // Up to 10% jitter keeps endpoints with the same interval from re-aligning
+            se.nextCheck = now.Add(se.interval + jitter(se.interval/10))
+        default:
+            return
+        }
+    }
+}
+
+// reconcile reloads the endpoint set from the database so created, updated and
+// deleted endpoints are picked up on the next tick. On failure the previous set is kept.
+func (s *Service) reconcile(ctx context.Context, sch *Scheduler) {
+    dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+
+    endpoints, err := s.dbRepo.GetAllEndpoints(dbCtx)
+    if err != nil {
+        log.Println("Scheduler failed to reload endpoints:", err)
+        return
+    }
+
+    added, removed := sch.replaceEndpoints(endpoints, false)
+    if added > 0 || removed > 0 {
+        log.Printf("Scheduler reconciled endpoints: %d added, %d removed, %d total", added, removed, len(endpoints))
+    }
+}
+
+// replaceEndpoints swaps in a new working set and reports how many endpoints appeared and
+// disappeared. Known endpoints keep their timing; new ones are due immediately unless
+// spread is set, in which case their first check is jittered across their interval.
+func (sch *Scheduler) replaceEndpoints(endpoints []Endpoint, spread bool) (added, removed int) {
+    now := time.Now()
+    next := make(map[int]*scheduledEndpoint, len(endpoints))
+
+    sch.mu.Lock()
+    defer sch.mu.Unlock()
+
+    for _, ep := range endpoints {
+        interval := ep.checkInterval(sch.defaultInterval)
+
+        if se, ok := sch.endpoints[ep.ID]; ok {
+            if se.interval != interval && !se.lastCheck.IsZero() {
+                se.nextCheck = se.lastCheck.Add(interval)
+            }
+            se.ep = ep
+            se.interval = interval
+            next[ep.ID] = se
+            continue
+        }
+
+        added++
+        se := &scheduledEndpoint{ep: ep, interval: interval, nextCheck: now}
+        if spread {
+            se.nextCheck = now.Add(jitter(interval))
+        }
+        next[ep.ID] = se
+    }
+
+    for id := range sch.endpoints {
+        if _, ok := next[id]; !ok {
+            removed++
+            metrics.ForgetEndpoint(id)
+        }
+    }
+    sch.endpoints = next
+    return added, removed
+}
+
+// EndpointCount returns the number of endpoints currently being checked
+func (sch *Scheduler) EndpointCount() int {
+    sch.mu.RLock()
+    defer sch.mu.RUnlock()
+    return len(sch.endpoints)
+}
+
+// IsLeader reports whether this replica is the one running checks
+func (sch *Scheduler) IsLeader() bool {
+    return sch.elector.IsLeader()
+}
+
+// Workers returns the size of the check worker pool
+func (sch *Scheduler) Workers() int {
+    return sch.workers
+}
+
+// Schedule returns the last and next check time of every endpoint, ordered by next check
+func (sch *Scheduler) Schedule() []EndpointSchedule {
+    sch.mu.RLock()
+    defer sch.mu.RUnlock()
+
+    schedule := make([]EndpointSchedule, 0, len(sch.endpoints))
+    for _, se := range sch.endpoints {
+        item := EndpointSchedule{
+            EndpointID:      se.ep.ID,
+            ServiceName:     se.ep.ServiceName,
+            URL:             se.ep.URL,
+            IntervalSeconds: int(se.interval / time.Second),
+            TimeoutSeconds:  int(se.ep.checkTimeout() / time.Second),
+            NextCheckAt:     se.nextCheck,
+            Running:         se.running,
+        }
+        if !se.lastCheck.IsZero() {
+            last := se.lastCheck
+            item.LastCheckAt = &last
+        }
+        schedule = append(schedule, item)
+    }
+
+    sort.Slice(schedule, func(i, j int) bool {
+        return schedule[i].NextCheckAt.Before(schedule[j].NextCheckAt)
+    })
+    return schedule
 }
 
 func (sch *Scheduler) Stop() {
@@ -65,3 +333,138 @@ func (sch *Scheduler) Stop() {
         <-sch.done
     }
 }
+
+// StartChecks starts the scheduler and persists "running" as the desired state, so it
+// is restored after a restart.
+func (s *Service) StartChecks(ctx context.Context, actor Actor) error {
+    s.schedMu.Lock()
+    defer s.schedMu.Unlock()
+
+    if s.scheduler != nil {
+        return ErrSchedulerRunning
+    }
+
+    scheduler, err := s.StartScheduler(context.Background())
+    if err != nil {
+        return err
+    }
+
+    if err := s.dbRepo.SetSchedulerState(ctx, true, "start", actor); err != nil {
+        scheduler.Stop()
+        return err
+    }
+
+    s.scheduler = scheduler
+    return nil
+}
+
+// StopChecks stops the scheduler and persists "stopped" as the desired state.
+func (s *Service) StopChecks(ctx context.Context, actor Actor) error {
+    s.schedMu.Lock()
+    defer s.schedMu.Unlock()
+
+    if s.scheduler == nil {
+        return ErrSchedulerNotRunning
+    }
+
+    if err := s.dbRepo.SetSchedulerState(ctx, false, "stop", actor); err != nil {
+        return err
+    }
+
+    s.scheduler.Stop()
+    s.scheduler = nil
+    return nil
+}
+
+// RestoreScheduler starts the scheduler on boot when it was left running before the
+// restart, then keeps following the persisted state so a start or stop issued on
+// another replica is applied here too.
+func (s *Service) RestoreScheduler(ctx context.Context) error {
+    defer func() { go s.followSchedulerState(context.Background()) }()
+
+    state, err := s.dbRepo.GetSchedulerState(ctx)
+    if err != nil {
+        return err
+    }
+    if !state.Running {
+        log.Println("Scheduler was stopped before restart, not starting it")
+        return nil
+    }
+
+    s.schedMu.Lock()
+    defer s.schedMu.Unlock()
+
+    if s.scheduler != nil {
+        return nil
+    }
+
+    scheduler, err := s.StartScheduler(context.Background())
+    if err != nil {
+        return err
+    }
+    s.scheduler = scheduler
+
+    if err := s.dbRepo.RecordSchedulerAction(ctx, "restore", Actor{Username: "system"}); err != nil {
+        log.Println("Failed to record scheduler restore:", err)
+    }
+
+    log.Println("Scheduler restored from persisted state")
+    return nil
+}
+
+// followSchedulerState starts or stops the local scheduler whenever the persisted desired
+// state differs from what this replica is doing.
+func (s *Service) followSchedulerState(ctx context.Context) {
+    ticker := time.NewTicker(envSeconds("SCHEDULER_RESYNC_INTERVAL", defaultResyncInterval))
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+
+        dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+        state, err := s.dbRepo.GetSchedulerState(dbCtx)
+        cancel()
+        if err != nil {
+            log.Println("Failed to read scheduler state:", err)
+            continue
+        }
+
+        s.schedMu.Lock()
+        switch {
+        case state.Running && s.scheduler == nil:
+            scheduler, err := s.StartScheduler(context.Background())
+            if err != nil {
+                log.Println("Failed to start scheduler to match persisted state:", err)
+                break
+            }
+            s.scheduler = scheduler
+            log.Println("Scheduler started to match persisted state")
+        case !state.Running && s.scheduler != nil:
+            s.scheduler.Stop()
+            s.scheduler = nil
+            log.Println("Scheduler stopped to match persisted state")
+        }
+        s.schedMu.Unlock()
+    }
+}
+
+// CurrentScheduler returns the scheduler running in this process, or nil.
+func (s *Service) CurrentScheduler() *Scheduler {
+    s.schedMu.Lock()
+    defer s.schedMu.Unlock()
+    return s.scheduler
+}
+
+// GetSchedulerState returns the persisted desired scheduler state.
+func (s *Service) GetSchedulerState(ctx context.Context) (*SchedulerState, error) {
+    return s.dbRepo.GetSchedulerState(ctx)
+}
+
+// ListSchedulerActions returns recent scheduler start/stop actions.
+func (s *Service) ListSchedulerActions(ctx context.Context, limit int) ([]SchedulerAction, error) {
+    return s.dbRepo.ListSchedulerActions(ctx, limit)
+}
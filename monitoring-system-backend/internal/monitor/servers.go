@@ -0,0 +1,503 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/badgerv/monitoring-api/internal/notify"
+)
+
+// defaultServerAlertGroupWait is how long DOWN alerts of a server's endpoints are held
+// back to see whether the whole server goes down.
+const defaultServerAlertGroupWait = time.Minute
+
+var (
+	ErrServerNotFound = errors.New("server not found")
+	ErrServerExists   = errors.New("a server with this name already exists")
+	ErrServerInUse    = errors.New("server still has endpoints")
+)
+
+// Server is a host endpoints run on. Endpoints belong to the server named by their
+// server_name; a server_name nobody registered yet adds its server, so every endpoint
+// has one. Renaming a server renames it on its endpoints too.
+type Server struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	IPAddress   *string    `json:"ip_address"`
+	Environment string     `json:"environment"`
+	Owner       string     `json:"owner"`
+	Tags        []string   `json:"tags"`
+	Description string     `json:"description"`
+	IsDown      bool       `json:"is_down"`
+	DownSince   *time.Time `json:"down_since"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ServerHealth is a server with the state of its endpoints. An endpoint counts as up
+// until it is marked down; WorstLatencyMs is the slowest last check among them.
+type ServerHealth struct {
+	Server
+	Endpoints              int      `json:"endpoints"`
+	EndpointsUp            int      `json:"endpoints_up"`
+	UpPercentage           *float64 `json:"up_percentage"`
+	WorstLatencyMs         *int64   `json:"worst_latency_ms"`
+	WorstLatencyEndpointID *int     `json:"worst_latency_endpoint_id"`
+	OpenIncidents          int      `json:"open_incidents"`
+}
+
+// ServerEndpoint is an endpoint as listed on its server.
+type ServerEndpoint struct {
+	ID            int        `json:"id"`
+	ServiceName   string     `json:"service_name"`
+	URL           string     `json:"url"`
+	CheckType     string     `json:"check_type"`
+	IsDown        bool       `json:"is_down"`
+	DownSince     *time.Time `json:"down_since"`
+	LastLatencyMs *int64     `json:"last_latency_ms"`
+}
+
+// ServerDetail is a server's health with its endpoints.
+type ServerDetail struct {
+	ServerHealth
+	EndpointList []ServerEndpoint `json:"endpoint_list"`
+}
+
+// ServerFilter narrows the server list. Zero values mean no filter.
+type ServerFilter struct {
+	Environment string
+	Tag         string
+}
+
+// ValidateServer checks the name and IP address, and drops blank tags
+func ValidateServer(srv *Server) error {
+	srv.Name = strings.TrimSpace(srv.Name)
+	if srv.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if srv.IPAddress != nil {
+		ip := strings.TrimSpace(*srv.IPAddress)
+		if ip == "" {
+			srv.IPAddress = nil
+		} else if net.ParseIP(ip) == nil {
+			return fmt.Errorf("ip_address must be an IPv4 or IPv6 address")
+		} else {
+			srv.IPAddress = &ip
+		}
+	}
+
+	tags := []string{}
+	for _, tag := range srv.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	srv.Tags = tags
+	return nil
+}
+
+func (h *ServerHealth) computeUpPercentage() {
+	if h.Endpoints > 0 {
+		up := float64(h.EndpointsUp) / float64(h.Endpoints) * 100
+		h.UpPercentage = &up
+	}
+}
+
+// ListServers returns every server with its aggregated health
+func (s *Service) ListServers(ctx context.Context, filter ServerFilter) ([]ServerHealth, error) {
+	return s.dbRepo.ListServerHealth(ctx, filter)
+}
+
+// GetServer returns a server's aggregated health and its endpoints
+func (s *Service) GetServer(ctx context.Context, id int) (*ServerDetail, error) {
+	health, err := s.dbRepo.GetServerHealth(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := s.dbRepo.ListServerEndpoints(ctx, health.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerDetail{ServerHealth: *health, EndpointList: endpoints}, nil
+}
+
+func (s *Service) CreateServer(ctx context.Context, srv Server) (*Server, error) {
+	if err := ValidateServer(&srv); err != nil {
+		return nil, err
+	}
+	return s.dbRepo.CreateServer(ctx, &srv)
+}
+
+// UpdateServer replaces a server's inventory details. A new name carries over to its
+// endpoints and to maintenance windows, silences and status page components scoped to it.
+func (s *Service) UpdateServer(ctx context.Context, srv Server) (*Server, error) {
+	if err := ValidateServer(&srv); err != nil {
+		return nil, err
+	}
+	updated, renamed, err := s.dbRepo.UpdateServer(ctx, &srv)
+	if err != nil {
+		return nil, err
+	}
+	if renamed {
+		s.notifyEndpointsChanged()
+		s.invalidateStatusPage()
+	}
+	return updated, nil
+}
+
+// DeleteServer removes a server that no longer has endpoints
+func (s *Service) DeleteServer(ctx context.Context, id int) error {
+	return s.dbRepo.DeleteServer(ctx, id)
+}
+
+// ServerAlert is a whole server going down or coming back. Endpoints are all of the
+// server's endpoints at the time.
+type ServerAlert struct {
+	Kind       AlertKind
+	Server     Server
+	Endpoints  []ServerEndpoint
+	DownSince  time.Time
+	OccurredAt time.Time
+}
+
+// Duration is how long the server has been (or was) down.
+func (a ServerAlert) Duration() time.Duration {
+	return a.OccurredAt.Sub(a.DownSince).Round(time.Second)
+}
+
+// StillDown returns the endpoints that are down, for a recovery that isn't complete yet.
+func (a ServerAlert) StillDown() []ServerEndpoint {
+	down := []ServerEndpoint{}
+	for _, se := range a.Endpoints {
+		if se.IsDown {
+			down = append(down, se)
+		}
+	}
+	return down
+}
+
+// heldAlerts are the DOWN alerts of one server's endpoints waiting out the group wait.
+type heldAlerts struct {
+	alerts map[int]Alert // by endpoint ID
+	timer  *time.Timer
+}
+
+// serverAlertGroupWait reads SERVER_ALERT_GROUP_WAIT, how long a server's endpoint DOWN
+// alerts are held back before being sent one by one.
+func serverAlertGroupWait() time.Duration {
+	return envSeconds("SERVER_ALERT_GROUP_WAIT", defaultServerAlertGroupWait)
+}
+
+// sendDownAlert sends the DOWN alert of an endpoint that was just marked down. When that
+// was the last of its server's endpoints still up, a single server DOWN alert replaces
+// it and the alerts held back for the server. Otherwise the alert is held for the group
+// wait in case the rest of the server follows. Servers with one endpoint alert as before.
+func (s *Service) sendDownAlert(ctx context.Context, sup suppression, alert Alert) {
+	name := alert.Endpoint.ServerName
+	srv, serverDown, err := s.dbRepo.MarkServerDown(ctx, name)
+	if err != nil {
+		log.Printf("Failed to update server %s: %v", name, err)
+	}
+	if s.withholds(ctx, sup, alert) {
+		return
+	}
+
+	endpoints, err := s.dbRepo.ListServerEndpoints(ctx, name)
+	if err != nil {
+		log.Printf("Failed to load endpoints of server %s: %v", name, err)
+		s.deliverAlert(alert)
+		return
+	}
+	if len(endpoints) < 2 {
+		s.deliverAlert(alert)
+		return
+	}
+
+	if serverDown {
+		s.takeHeldAlerts(name)
+		s.deliverServerAlert(ServerAlert{
+			Kind:       AlertDown,
+			Server:     *srv,
+			Endpoints:  endpoints,
+			DownSince:  *srv.DownSince,
+			OccurredAt: alert.OccurredAt,
+		})
+		return
+	}
+	s.holdDownAlert(alert)
+}
+
+// sendRecoveredAlert sends the RECOVERED alert of an endpoint that was just marked up.
+// A DOWN alert still held back is withdrawn instead, and the first endpoint back on a
+// down server sends a server RECOVERED alert listing those still down. These get their
+// own RECOVERED alert once they are back.
+func (s *Service) sendRecoveredAlert(ctx context.Context, sup suppression, alert Alert) {
+	if s.cancelHeldDownAlert(alert.Endpoint) {
+		log.Printf("down alert for %s withdrawn: recovered within the server alert group wait", alert.Endpoint.ServiceName)
+		return
+	}
+
+	name := alert.Endpoint.ServerName
+	srv, serverRecovered, err := s.dbRepo.MarkServerRecovered(ctx, name)
+	if err != nil {
+		log.Printf("Failed to update server %s: %v", name, err)
+	}
+	if s.withholds(ctx, sup, alert) {
+		return
+	}
+
+	if serverRecovered && srv.DownSince != nil {
+		endpoints, err := s.dbRepo.ListServerEndpoints(ctx, name)
+		if err != nil {
+			log.Printf("Failed to load endpoints of server %s: %v", name, err)
+		} else if len(endpoints) > 1 {
+			s.deliverServerAlert(ServerAlert{
+				Kind:       AlertRecovered,
+				Server:     *srv,
+				Endpoints:  endpoints,
+				DownSince:  *srv.DownSince,
+				OccurredAt: alert.OccurredAt,
+			})
+			return
+		}
+	}
+	s.deliverAlert(alert)
+}
+
+// holdDownAlert holds an alert until the server's group wait runs out, starting it for
+// the first alert held
+func (s *Service) holdDownAlert(alert Alert) {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+
+	name := alert.Endpoint.ServerName
+	group, ok := s.held[name]
+	if !ok {
+		group = &heldAlerts{alerts: map[int]Alert{}}
+		group.timer = time.AfterFunc(serverAlertGroupWait(), func() { s.flushHeldAlerts(name, group) })
+		s.held[name] = group
+	}
+	group.alerts[alert.Endpoint.ID] = alert
+}
+
+// flushHeldAlerts sends the alerts held for a server whose other endpoints stayed up
+func (s *Service) flushHeldAlerts(name string, group *heldAlerts) {
+	s.heldMu.Lock()
+	if s.held[name] != group {
+		// Already replaced by a server alert
+		s.heldMu.Unlock()
+		return
+	}
+	delete(s.held, name)
+	s.heldMu.Unlock()
+
+	alerts := make([]Alert, 0, len(group.alerts))
+	for _, alert := range group.alerts {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].OccurredAt.Before(alerts[j].OccurredAt) })
+	for _, alert := range alerts {
+		s.deliverAlert(alert)
+	}
+}
+
+// takeHeldAlerts drops the alerts held for a server
+func (s *Service) takeHeldAlerts(name string) {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+
+	if group, ok := s.held[name]; ok {
+		group.timer.Stop()
+		delete(s.held, name)
+	}
+}
+
+// cancelHeldDownAlert drops the held DOWN alert of an endpoint, reporting whether there
+// was one
+func (s *Service) cancelHeldDownAlert(ep Endpoint) bool {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+
+	group, ok := s.held[ep.ServerName]
+	if !ok {
+		return false
+	}
+	if _, ok := group.alerts[ep.ID]; !ok {
+		return false
+	}
+	delete(group.alerts, ep.ID)
+	if len(group.alerts) == 0 {
+		group.timer.Stop()
+		delete(s.held, ep.ServerName)
+	}
+	return true
+}
+
+// deliverServerAlert renders a server alert and sends it to the channels routed to the
+// server or any of its endpoints and tags, and to the endpoints' subscribers.
+func (s *Service) deliverServerAlert(alert ServerAlert) {
+	msg, err := serverAlertMessage(alert)
+	if err != nil {
+		log.Printf("Failed to render alert for server %s: %v", alert.Server.Name, err)
+		return
+	}
+	if s.notifier == nil {
+		log.Printf("Alert for server %s (%s) not sent: notifications are not configured", alert.Server.Name, msg.Event)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		target := notify.Target{ServerName: alert.Server.Name}
+		seenTags := map[string]bool{}
+		seenRecipients := map[string]bool{}
+		recipients := []string{}
+		for _, se := range alert.Endpoints {
+			target.EndpointIDs = append(target.EndpointIDs, se.ID)
+
+			tags, err := s.dbRepo.GetEndpointTags(ctx, se.ID)
+			if err != nil {
+				log.Printf("Failed to load tags for endpoint %d: %v", se.ID, err)
+			}
+			for _, tag := range tags {
+				if !seenTags[tag] {
+					seenTags[tag] = true
+					target.Tags = append(target.Tags, tag)
+				}
+			}
+
+			emails, err := s.alertRecipients(ctx, se.ID)
+			if err != nil {
+				log.Printf("Failed to load alert recipients for endpoint %d: %v", se.ID, err)
+			}
+			for _, email := range emails {
+				if !seenRecipients[email] {
+					seenRecipients[email] = true
+					recipients = append(recipients, email)
+				}
+			}
+		}
+
+		var extra []notify.Channel
+		if len(recipients) > 0 {
+			extra = append(extra, s.notifier.EmailTo(recipients...))
+		}
+		if err := s.notifier.Notify(ctx, target, msg, extra...); err != nil {
+			log.Printf("Failed to deliver %s alert for server %s: %v", msg.Event, alert.Server.Name, err)
+		}
+	}()
+}
+
+var serverAlertEmailTemplate = template.Must(template.New("server").Parse(`
+	<html>
+	<head>
+		<style>
+			body { font-family: Arial, sans-serif; }
+			.container { border: 1px solid #ddd; padding: 16px; border-radius: 8px; }
+			.title { font-size: 20px; font-weight: bold; margin-bottom: 12px; }
+			.down { color: #c62828; }
+			.recovered { color: #2e7d32; }
+			.section { margin-bottom: 8px; }
+			.label { font-weight: bold; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			{{if eq .Kind "down"}}
+			<div class="title down">Server {{.Server.Name}} is DOWN</div>
+			{{else}}
+			<div class="title recovered">Server {{.Server.Name}} has RECOVERED</div>
+			{{end}}
+
+			{{if .Server.Environment}}<div class="section"><span class="label">Environment:</span> {{.Server.Environment}}</div>{{end}}
+			{{if .Server.IPAddress}}<div class="section"><span class="label">IP address:</span> {{.Server.IPAddress}}</div>{{end}}
+			{{if .Server.Owner}}<div class="section"><span class="label">Owner:</span> {{.Server.Owner}}</div>{{end}}
+			<div class="section"><span class="label">Down since:</span> {{.DownSince.Format "2006-01-02 15:04:05 MST"}}</div>
+			{{if eq .Kind "down"}}
+			<div class="section"><span class="label">Endpoints down:</span> all {{len .Endpoints}}</div>
+			<ul>{{range .Endpoints}}<li>{{.ServiceName}} ({{.URL}})</li>{{end}}</ul>
+			{{else}}
+			<div class="section"><span class="label">Outage duration:</span> {{.Duration}}</div>
+			{{with .StillDown}}
+			<div class="section"><span class="label">Still down:</span></div>
+			<ul>{{range .}}<li>{{.ServiceName}} ({{.URL}})</li>{{end}}</ul>
+			{{end}}
+			{{end}}
+		</div>
+	</body>
+	</html>`))
+
+// serverAlertMessage renders a server alert like alertMessage does an endpoint alert.
+func serverAlertMessage(alert ServerAlert) (notify.Message, error) {
+	srv := alert.Server
+	msg := notify.Message{
+		Fields: []notify.Field{
+			{Name: "Server", Value: srv.Name},
+			{Name: "Down since", Value: alert.DownSince.Format("2006-01-02 15:04:05 MST")},
+		},
+	}
+	if srv.Environment != "" {
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Environment", Value: srv.Environment})
+	}
+	if srv.Owner != "" {
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Owner", Value: srv.Owner})
+	}
+
+	names := func(endpoints []ServerEndpoint) (string, []int) {
+		list := make([]string, 0, len(endpoints))
+		ids := make([]int, 0, len(endpoints))
+		for _, se := range endpoints {
+			list = append(list, se.ServiceName)
+			ids = append(ids, se.ID)
+		}
+		return strings.Join(list, ", "), ids
+	}
+
+	data := map[string]interface{}{
+		"server_id":   srv.ID,
+		"server_name": srv.Name,
+		"environment": srv.Environment,
+		"ip_address":  srv.IPAddress,
+		"down_since":  alert.DownSince,
+	}
+
+	if alert.Kind == AlertDown {
+		list, ids := names(alert.Endpoints)
+		msg.Event = notify.EventServerDown
+		msg.Severity = notify.SeverityCritical
+		msg.Title = fmt.Sprintf("[DOWN] Server %s: all %d endpoints are down", srv.Name, len(alert.Endpoints))
+		msg.Text = fmt.Sprintf("Every endpoint on %s is failing its checks", srv.Name)
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Endpoints down", Value: list})
+		data["endpoint_ids"] = ids
+	} else {
+		stillDown := alert.StillDown()
+		msg.Event = notify.EventServerRecovered
+		msg.Severity = notify.SeverityResolved
+		msg.Title = fmt.Sprintf("[RECOVERED] Server %s after %s", srv.Name, alert.Duration())
+		msg.Text = fmt.Sprintf("%d of %d endpoints on %s are responding again", len(alert.Endpoints)-len(stillDown), len(alert.Endpoints), srv.Name)
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Outage duration", Value: alert.Duration().String()})
+		list, ids := names(stillDown)
+		if len(stillDown) > 0 {
+			msg.Fields = append(msg.Fields, notify.Field{Name: "Still down", Value: list})
+		}
+		data["duration_seconds"] = int64(alert.Duration().Seconds())
+		data["still_down_endpoint_ids"] = ids
+	}
+	msg.Data = data
+
+	builder := &strings.Builder{}
+	if err := serverAlertEmailTemplate.Execute(builder, alert); err != nil {
+		return msg, err
+	}
+	msg.HTML = builder.String()
+	return msg, nil
+}
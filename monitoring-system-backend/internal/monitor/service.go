@@ -2,10 +2,17 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
+	"sync"
 
+	"github.com/badgerv/monitoring-api/internal/auth"
+	"github.com/badgerv/monitoring-api/internal/metrics"
+	"github.com/badgerv/monitoring-api/internal/notify"
 	"github.com/badgerv/monitoring-api/internal/storage"
 
 	"encoding/json"
@@ -14,13 +21,68 @@ import (
 	"time"
 )
 
+var ErrEndpointNotFound = errors.New("endpoint not found")
+
+// EndpointValidationError is an endpoint the service refused to store because it
+// failed ValidateEndpoint.
+type EndpointValidationError struct {
+	Err error
+}
+
+func (e *EndpointValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EndpointValidationError) Unwrap() error {
+	return e.Err
+}
+
 type Service struct {
 	db     *storage.DB
 	dbRepo *PostgresRepository
+
+	// checkers run the checks of each check type
+	checkers map[string]Checker
+
+	// changes wakes a running scheduler so it reloads its endpoint set
+	changes chan struct{}
+
+	// schedMu guards the scheduler owned by this process
+	schedMu   sync.Mutex
+	scheduler *Scheduler
+
+	// Alert delivery to subscribers and routed notification channels
+	notifier *notify.Service
+	userRepo auth.UserRepository
+
+	// statusMu guards the cached public status page
+	statusMu sync.Mutex
+	status   *PublicStatus
+
+	// heldMu guards the DOWN alerts held back per server, see sendDownAlert
+	heldMu sync.Mutex
+	held   map[string]*heldAlerts
 }
 
-func NewService(db *storage.DB, dbRepo *PostgresRepository) *Service {
-	return &Service{db: db, dbRepo: dbRepo}
+func NewService(db *storage.DB, dbRepo *PostgresRepository, notifier *notify.Service, userRepo auth.UserRepository) *Service {
+	return &Service{
+		db:       db,
+		dbRepo:   dbRepo,
+		checkers: newCheckers(dbRepo),
+		changes:  make(chan struct{}, 1),
+		notifier: notifier,
+		userRepo: userRepo,
+		held:     map[string]*heldAlerts{},
+	}
+}
+
+// notifyEndpointsChanged tells a running scheduler that endpoints were created, updated
+// or deleted. It never blocks: one pending notification is enough to trigger a reload.
+func (s *Service) notifyEndpointsChanged() {
+	select {
+	case s.changes <- struct{}{}:
+	default:
+	}
 }
 
 func (s *Service) LoadAndSyncEndpoints(path string) ([]Endpoint, error) {
@@ -57,6 +119,9 @@ func (s *Service) LoadAndSyncEndpoints(path string) ([]Endpoint, error) {
 	// Merge JSON entries into DB
 	for _, jep := range jsonEndpoints {
 		jep.URL = normalizeURL(jep.URL)
+		if jep.CheckType == "" {
+			jep.CheckType = CheckHTTP
+		}
 
 		exists := false
 		for _, dep := range dbEndpoints {
@@ -70,14 +135,22 @@ func (s *Service) LoadAndSyncEndpoints(path string) ([]Endpoint, error) {
 		}
 
 		if !exists {
+			if err := registerServer(ctx, s.db.Pool, jep.ServerName); err != nil {
+				log.Printf("Error inserting endpoint %s: %v", jep.URL, err)
+				continue
+			}
+
 			var newID int
 			err = s.db.Pool.QueryRow(ctx, `
-				INSERT INTO endpoints (service_name, url, server_name, api_method, expected_status_code)
-				VALUES ($1, $2, $3, $4, $5)
+				INSERT INTO endpoints (service_name, url, server_name, api_method, expected_status_code, headers, query_params, body_template, assertions,
+					check_interval_seconds, timeout_seconds, check_type, check_config)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 				ON CONFLICT (url) DO NOTHING
 				RETURNING id
 			`,
-				jep.ServiceName, jep.URL, jep.ServerName, jep.APIMethod, jep.ExpectedCode,
+				jep.ServiceName, jep.URL, jep.ServerName, normalizeMethod(jep.APIMethod), jep.ExpectedCode,
+				nonNilMap(jep.Headers), nonNilMap(jep.QueryParams), jep.BodyTemplate, nonNilAssertions(jep.Assertions),
+				jep.CheckIntervalSeconds, jep.TimeoutSeconds, jep.CheckType, jep.CheckConfig,
 			).Scan(&newID)
 
 			if err != nil {
@@ -97,178 +170,202 @@ func (s *Service) LoadAndSyncEndpoints(path string) ([]Endpoint, error) {
 	return dbEndpoints, nil
 }
 
-// func (s *Service) CheckEndpoint(ctx context.Context, endpointID int, url, method, serverName, serviceName string) error {
-// 	start := time.Now()
-// 	log.Println("Calling the API endpoint for", url)
-
-// 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-// 	client := &http.Client{Timeout: 5 * time.Second}
-
-// 	resp, err := client.Do(req)
-
-// 	log.Println(resp.StatusCode)
-
-// 	if err != nil {
-// 		log.Println("An error occured while trying to call endpoint %v", err)
-// 	}
-// 	latency := time.Since(start).Milliseconds()
-
-// 	var errMsg string
-// 	var statusCode int
-// 	var success int64 // will be 1 if success, else 0
-// 	var lastrun bool
-
-// 	expectedStatusCode, err := s.dbRepo.GetEndpointExpectedCode(ctx, endpointID)
-
-// 	if err != nil {
-// 		errMsg = err.Error()
-// 	} else {
-// 		statusCode = resp.StatusCode
-// 		if statusCode == expectedStatusCode {
-// 			success = 1
-// 			lastrun = true
-// 		}
-// 		resp.Body.Close()
-// 	}
-
-// 	// Insert into checks log table
-// 	_, insertErr := s.db.Pool.Exec(ctx,
-// 		`INSERT INTO checks (endpoint_id, status_code, latency_ms, error)
-//          VALUES ($1, $2, $3, $4)`,
-// 		endpointID, statusCode, latency, errMsg,
-// 	)
-
-// 	if insertErr != nil {
-// 		return insertErr
-// 	}
-
-// 	// Update stats table
-// 	_, statsErr := s.db.Pool.Exec(ctx,
-// 		`INSERT INTO endpoint_stats (endpoint_id, total_checks, total_latency, successful_checks, last_run)
-// 	 VALUES ($1, 1, $2, $3, $4)
-// 	 ON CONFLICT (endpoint_id) DO UPDATE
-// 	 SET total_checks = endpoint_stats.total_checks + 1,
-// 	     total_latency = endpoint_stats.total_latency + EXCLUDED.total_latency,
-// 	     successful_checks = endpoint_stats.successful_checks + EXCLUDED.successful_checks,
-// 	     last_run = EXCLUDED.last_run`,
-// 		endpointID, latency, success, lastrun,
-// 	)
-
-// 	if statsErr != nil {
-// 		return statsErr
-// 	}
-
-// 	return nil
-// }
+// executeCheck runs the checker of the endpoint's check type. It is shared by the
+// scheduled and on-demand check paths.
+func (s *Service) executeCheck(ctx context.Context, ep *Endpoint) *CheckResult {
+	checker, err := s.checkerFor(ep)
+	if err != nil {
+		return &CheckResult{EndpointID: ep.ID, Error: err.Error()}
+	}
+	return checker.Check(ctx, ep)
+}
 
+// checkHTTP sends the configured request for an endpoint and evaluates the response.
+// The body is always read, up to the assertion limit, so the transfer phase is timed.
+func checkHTTP(ctx context.Context, ep *Endpoint) *CheckResult {
+	result := &CheckResult{EndpointID: ep.ID}
 
-func (s *Service) CheckEndpoint(ctx context.Context, endpointID int, url, method, serverName, serviceName string) error {
-	start := time.Now()
-	log.Println("Calling the API endpoint for", url)
+	req, err := buildCheckRequest(ctx, ep)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
 
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	client := &http.Client{Timeout: 5 * time.Second}
+	timer := &phaseTimer{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timer.trace()))
+	client := &http.Client{Timeout: ep.checkTimeout(), Transport: checkTransport}
 
+	start := time.Now()
 	resp, err := client.Do(req)
-
-	var statusCode int
-	var errMsg string
-	var success int64 // will be 1 if success, else 0
-	var failure int64 // will be 1 if failure, else 0
-	var lastrun bool
+	result.Latency = time.Since(start)
 
 	if err != nil {
+		result.Timing = timer.timing()
 		log.Printf("An error occurred while trying to call endpoint: %v", err)
-		errMsg = err.Error()
-	} else {
-		log.Println(resp.StatusCode)
-		statusCode = resp.StatusCode
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, bodyReadLimit(ep.Assertions)))
+	timer.finishBody()
+	result.Timing = timer.timing()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode != ep.ExpectedCode {
+		result.Error = fmt.Sprintf("unexpected status code: got %d, expected %d", resp.StatusCode, ep.ExpectedCode)
+		return result
+	}
+
+	if len(ep.Assertions) > 0 {
+		if err != nil && needsBody(ep.Assertions) {
+			result.Error = fmt.Sprintf("failed to read response body: %v", err)
+			return result
+		}
+
+		if failed := evaluateAssertions(ep.Assertions, resp.Header, body, result.Latency); failed != nil {
+			result.FailedAssertion = failed
+			result.Error = failed.Error()
+			return result
+		}
 	}
 
-	expectedStatusCode, err := s.dbRepo.GetEndpointExpectedCode(ctx, endpointID)
+	result.Success = true
+	return result
+}
+
+func (s *Service) CheckEndpoint(ctx context.Context, ep Endpoint) error {
+	log.Println("Calling the API endpoint for", ep.URL)
+
+	result := s.executeConfirmedCheck(ctx, &ep)
 
+	// A lookup failure shouldn't drop the check; it just isn't suppressed
+	sup, err := s.activeSuppression(ctx, ep)
 	if err != nil {
-		errMsg = err.Error()
-		failure = 1
-	} else if statusCode == expectedStatusCode && err == nil {
+		log.Printf("Failed to load maintenance windows and silences for endpoint %d: %v", ep.ID, err)
+	}
+	inMaintenance := sup.Maintenance != nil
+
+	var success int64 // will be 1 if success, else 0
+	var failure int64 // will be 1 if failure, else 0
+	if result.Success {
 		success = 1
-		lastrun = true
-		failure = 0
 	} else {
 		failure = 1
 	}
 
-	if resp != nil {
-		resp.Body.Close()
-	}
-
-	latency := time.Since(start).Milliseconds()
+	latency := result.Latency.Milliseconds()
 
 	// Insert into checks log table
-	_, insertErr := s.db.Pool.Exec(ctx,
-		`INSERT INTO checks (endpoint_id, status_code, latency_ms, error)
-         VALUES ($1, $2, $3, $4)`,
-		endpointID, statusCode, latency, errMsg,
-	)
+	insertErr := s.db.Pool.QueryRow(ctx,
+		`INSERT INTO checks (endpoint_id, status_code, latency_ms, error, success, in_maintenance, attempts,
+             dns_ms, connect_ms, tls_ms, ttfb_ms, transfer_ms)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+         RETURNING id`,
+		ep.ID, result.StatusCode, latency, result.Error, result.Success, inMaintenance, result.Attempts,
+		result.Timing.DNSMs, result.Timing.ConnectMs, result.Timing.TLSMs, result.Timing.TTFBMs, result.Timing.TransferMs,
+	).Scan(&result.CheckID)
 
 	if insertErr != nil {
 		return insertErr
 	}
 
+	// Checks during maintenance are kept for history but left out of uptime and latency
+	// stats. The failure streak still counts, so an endpoint that is still broken when
+	// the window closes alerts straight away.
+	var counted int64 = 1
+	if inMaintenance {
+		counted, success, latency = 0, 0, 0
+	} else if err := s.foldIntoRollup(ctx, ep.ID, success, latency); err != nil {
+		return err
+	}
+
 	// Update stats table
-	_, statsErr := s.db.Pool.Exec(ctx,
+	var failureCount int
+	var isDown bool
+	statsErr := s.db.Pool.QueryRow(ctx,
 		`INSERT INTO endpoint_stats (endpoint_id, total_checks, total_latency, successful_checks, failure_count, last_run)
-	 VALUES ($1, 1, $2, $3, $4, $5)
+	 VALUES ($1, $6, $2, $3, $4, $5)
 	 ON CONFLICT (endpoint_id) DO UPDATE
-	 SET total_checks = endpoint_stats.total_checks + 1,
+	 SET total_checks = endpoint_stats.total_checks + EXCLUDED.total_checks,
 	     total_latency = endpoint_stats.total_latency + EXCLUDED.total_latency,
 	     successful_checks = endpoint_stats.successful_checks + EXCLUDED.successful_checks,
-	     failure_count = CASE WHEN EXCLUDED.successful_checks = 1 THEN 0 ELSE endpoint_stats.failure_count + 1 END,
-	     last_run = EXCLUDED.last_run`,
-		endpointID, latency, success, failure, lastrun,
-	)
+	     failure_count = CASE WHEN EXCLUDED.last_run THEN 0 ELSE endpoint_stats.failure_count + 1 END,
+	     last_run = EXCLUDED.last_run
+	 RETURNING failure_count, is_down`,
+		ep.ID, latency, success, failure, result.Success, counted,
+	).Scan(&failureCount, &isDown)
 
 	if statsErr != nil {
 		return statsErr
 	}
 
-	return nil
+	s.recordCheckMetrics(ctx, ep, result, failureCount)
+
+	sup.Flapping, sup.FlapOwed = s.updateFlapState(ctx, ep, isDown)
+
+	return s.evaluateAlertState(ctx, ep, result, failureCount, isDown, sup)
+}
+
+// metricsEndpoint labels an endpoint's Prometheus series. Tags live in endpoint_info,
+// which the scheduler's working set doesn't load.
+func (s *Service) metricsEndpoint(ctx context.Context, ep Endpoint) metrics.Endpoint {
+	tags, err := s.dbRepo.GetEndpointTags(ctx, ep.ID)
+	if err != nil {
+		log.Printf("Failed to load tags for endpoint %d metrics: %v", ep.ID, err)
+	}
+	return metrics.Endpoint{ID: ep.ID, ServiceName: ep.ServiceName, ServerName: ep.ServerName, Tags: tags}
 }
 
-func (s *Service) CheckEndpointStatus(ctx context.Context, url, method string, expectedStatus int) (time.Duration, error) {
-    // Create the HTTP request with context
-    req, err := http.NewRequestWithContext(ctx, method, url, nil)
-    if err != nil {
-        return 0, err
-    }
-
-    // HTTP client with timeout
-    client := &http.Client{Timeout: 5 * time.Second}
-
-    // Measure start time
-    start := time.Now()
-
-    // Send the request
-    resp, err := client.Do(req)
-    latency := time.Since(start) // calculate latency
-    if err != nil {
-        return latency, err
-    }
-    defer resp.Body.Close() // ensure body is closed
-
-    // Check if status code matches expected
-    if resp.StatusCode != expectedStatus {
-        return latency, fmt.Errorf("unexpected status code: got %d, expected %d", resp.StatusCode, expectedStatus)
-    }
-
-    // Success
-    return latency, nil
+// recordCheckMetrics exports the outcome of a check to Prometheus
+func (s *Service) recordCheckMetrics(ctx context.Context, ep Endpoint, result *CheckResult, failureCount int) {
+	metrics.RecordCheck(s.metricsEndpoint(ctx, ep), result.Success, result.Latency, failureCount)
 }
 
+// foldIntoRollup adds a check to this hour's rollup, which backs the rolling uptime windows
+func (s *Service) foldIntoRollup(ctx context.Context, endpointID int, success, latency int64) error {
+	histogram := make([]int64, len(latencyBucketBounds)+1)
+	bucket := latencyBucket(latency)
+	histogram[bucket] = 1
+
+	_, rollupErr := s.db.Pool.Exec(ctx,
+		`INSERT INTO check_rollups_hourly (endpoint_id, bucket_start, total_checks, successful_checks, latency_sum, latency_min, latency_max, latency_histogram)
+	 VALUES ($1, date_trunc('hour', now()), 1, $2, $3, $3, $3, $4)
+	 ON CONFLICT (endpoint_id, bucket_start) DO UPDATE
+	 SET total_checks = check_rollups_hourly.total_checks + 1,
+	     successful_checks = check_rollups_hourly.successful_checks + EXCLUDED.successful_checks,
+	     latency_sum = check_rollups_hourly.latency_sum + EXCLUDED.latency_sum,
+	     latency_min = LEAST(check_rollups_hourly.latency_min, EXCLUDED.latency_min),
+	     latency_max = GREATEST(check_rollups_hourly.latency_max, EXCLUDED.latency_max),
+	     latency_histogram[$5] = COALESCE(check_rollups_hourly.latency_histogram[$5], 0) + 1`,
+		endpointID, success, latency, histogram, bucket+1,
+	)
 
+	return rollupErr
+}
 
+func (s *Service) CheckEndpointStatus(ctx context.Context, ep *Endpoint) (time.Duration, error) {
+	result := s.executeCheck(ctx, ep)
+	if result.FailedAssertion != nil {
+		return result.Latency, result.FailedAssertion
+	}
+	if !result.Success {
+		return result.Latency, errors.New(result.Error)
+	}
+
+	// Success
+	return result.Latency, nil
+}
+
+// GetEndpointByID returns an endpoint with its stats. Header values are masked, as they
+// often hold credentials; UpdateEndpoint keeps the stored value of a header sent back masked.
 func (s *Service) GetEndpointByID(ctx context.Context, endpointID int) (*EndpointDetail, error) {
-	return s.dbRepo.GetEndPointByID(ctx, endpointID)
+	detail, err := s.dbRepo.GetEndPointByID(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	detail.Headers = maskHeaders(detail.Headers)
+	return detail, nil
 }
 
 func (s *Service) GetAllEndpointEssentials(ctx context.Context) (*[]EndpointBasicsDTO, error) {
@@ -279,9 +376,195 @@ func (s *Service) GetAggregateStats(ctx context.Context) (*AggregateDTO, error)
 	return s.dbRepo.GetAggregateStats(ctx)
 }
 
+// GetEndpoint returns the endpoint with the configuration the check engine needs
+func (s *Service) GetEndpoint(ctx context.Context, endpointID int) (*Endpoint, error) {
+	return s.dbRepo.GetEndpoint(ctx, endpointID)
+}
+
 // Exposes repo function to the handler
 func (s *Service) CreateEndpoint(ctx context.Context, ep *Endpoint) (*Endpoint, error) {
-	return s.dbRepo.CreateEndpoint(ctx, ep)
+	if err := assignHeartbeatURL(ep, nil); err != nil {
+		return nil, err
+	}
+	if err := ValidateEndpoint(ep); err != nil {
+		return nil, &EndpointValidationError{Err: err}
+	}
+	ep.APIMethod = normalizeMethod(ep.APIMethod)
+
+	created, err := s.dbRepo.CreateEndpoint(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyEndpointsChanged()
+	return created, nil
+}
+
+// ValidateEndpoint checks the required fields and every piece of check configuration
+func ValidateEndpoint(ep *Endpoint) error {
+	if strings.TrimSpace(ep.ServiceName) == "" {
+		return fmt.Errorf("service_name is required")
+	}
+	if strings.TrimSpace(ep.URL) == "" {
+		return fmt.Errorf("url is required")
+	}
+	if strings.TrimSpace(ep.ServerName) == "" {
+		return fmt.Errorf("server_name is required")
+	}
+	if ep.CheckType == "" {
+		ep.CheckType = CheckHTTP
+	}
+	if ep.CheckType == CheckHTTP {
+		if ep.ExpectedCode < 100 || ep.ExpectedCode > 599 {
+			return fmt.Errorf("expected_status_code must be a valid HTTP status code")
+		}
+		if err := ValidateRequestConfig(RequestConfig{
+			APIMethod:    ep.APIMethod,
+			Headers:      ep.Headers,
+			QueryParams:  ep.QueryParams,
+			BodyTemplate: ep.BodyTemplate,
+		}); err != nil {
+			return err
+		}
+		if err := ValidateAssertions(ep.Assertions); err != nil {
+			return err
+		}
+	} else if err := ValidateCheckConfig(ep.CheckType, ep.URL, ep.CheckConfig); err != nil {
+		return err
+	}
+	return ValidateScheduleConfig(ScheduleConfig{
+		CheckIntervalSeconds: ep.CheckIntervalSeconds,
+		TimeoutSeconds:       ep.TimeoutSeconds,
+	})
+}
+
+// UpdateEndpoint replaces every field of an endpoint, recording the actor as the last person to change it.
+// A heartbeat keeps its ping URL; the endpoint is validated once it has it. Headers sent
+// back masked keep their stored values.
+func (s *Service) UpdateEndpoint(ctx context.Context, endpointID int, ep *Endpoint, actor Actor) (*Endpoint, error) {
+	current, err := s.dbRepo.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	headers, missing := unmaskHeaders(ep.Headers, current)
+	if len(missing) > 0 {
+		return nil, &EndpointValidationError{Err: fmt.Errorf("headers %s are masked and have no stored value", strings.Join(missing, ", "))}
+	}
+	ep.Headers = headers
+	if ep.CheckType == CheckHeartbeat {
+		if err := assignHeartbeatURL(ep, current); err != nil {
+			return nil, err
+		}
+	}
+	if err := ValidateEndpoint(ep); err != nil {
+		return nil, &EndpointValidationError{Err: err}
+	}
+
+	ep.ID = endpointID
+	ep.APIMethod = normalizeMethod(ep.APIMethod)
+	changedBy := actor.Username
+	ep.LastChangedBy = &changedBy
+
+	if err := s.dbRepo.UpdateEndpoint(ctx, ep); err != nil {
+		return nil, err
+	}
+	s.notifyEndpointsChanged()
+
+	return s.dbRepo.GetEndpointWithInfo(ctx, endpointID)
+}
+
+// PatchEndpoint updates only the fields set on the patch
+func (s *Service) PatchEndpoint(ctx context.Context, endpointID int, patch EndpointPatch, actor Actor) (*Endpoint, error) {
+	current, err := s.dbRepo.GetEndpointWithInfo(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	patch.Apply(current)
+	return s.UpdateEndpoint(ctx, endpointID, current, actor)
+}
+
+// DeleteEndpoint removes an endpoint and its check history, and stops it being checked
+func (s *Service) DeleteEndpoint(ctx context.Context, endpointID int, actor Actor) error {
+	if err := s.dbRepo.DeleteEndpoint(ctx, int64(endpointID), actor.Username); err != nil {
+		return err
+	}
+	log.Printf("Endpoint %d deleted by %s", endpointID, actor.Username)
+
+	s.notifyEndpointsChanged()
+	return nil
+}
+
+// ListEndpointChanges returns who changed an endpoint and how
+func (s *Service) ListEndpointChanges(ctx context.Context, endpointID int) ([]EndpointChange, error) {
+	return s.dbRepo.ListEndpointChanges(ctx, endpointID)
+}
+
+// UpdateEndpointAssertions replaces the response assertions evaluated on every check
+func (s *Service) UpdateEndpointAssertions(ctx context.Context, endpointID int, assertions []Assertion) (*Endpoint, error) {
+	if err := ValidateAssertions(assertions); err != nil {
+		return nil, err
+	}
+	updated, err := s.dbRepo.UpdateEndpointAssertions(ctx, endpointID, assertions)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyEndpointsChanged()
+	return updated, nil
+}
+
+// ValidateScheduleConfig rejects intervals and timeouts that would never let a check finish
+func ValidateScheduleConfig(cfg ScheduleConfig) error {
+	if cfg.CheckIntervalSeconds != nil && *cfg.CheckIntervalSeconds <= 0 {
+		return fmt.Errorf("check_interval_seconds must be positive")
+	}
+	if cfg.TimeoutSeconds != nil && *cfg.TimeoutSeconds <= 0 {
+		return fmt.Errorf("timeout_seconds must be positive")
+	}
+	if cfg.CheckIntervalSeconds != nil && cfg.TimeoutSeconds != nil && *cfg.TimeoutSeconds > *cfg.CheckIntervalSeconds {
+		return fmt.Errorf("timeout_seconds cannot be longer than check_interval_seconds")
+	}
+	return nil
+}
+
+// UpdateEndpointSchedule changes how often an endpoint is checked and its request timeout.
+// A value left out keeps the stored one, and the resulting pair is validated.
+func (s *Service) UpdateEndpointSchedule(ctx context.Context, endpointID int, cfg ScheduleConfig) (*Endpoint, error) {
+	current, err := s.dbRepo.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CheckIntervalSeconds == nil {
+		cfg.CheckIntervalSeconds = current.CheckIntervalSeconds
+	}
+	if cfg.TimeoutSeconds == nil {
+		cfg.TimeoutSeconds = current.TimeoutSeconds
+	}
+	if err := ValidateScheduleConfig(cfg); err != nil {
+		return nil, &EndpointValidationError{Err: err}
+	}
+
+	updated, err := s.dbRepo.UpdateEndpointSchedule(ctx, endpointID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyEndpointsChanged()
+	return updated, nil
+}
+
+// UpdateEndpointRequestConfig changes the method, headers, query params and body
+// template sent when the endpoint is checked
+func (s *Service) UpdateEndpointRequestConfig(ctx context.Context, endpointID int, cfg RequestConfig) (*Endpoint, error) {
+	if err := ValidateRequestConfig(cfg); err != nil {
+		return nil, err
+	}
+	cfg.APIMethod = normalizeMethod(cfg.APIMethod)
+
+	updated, err := s.dbRepo.UpdateEndpointRequestConfig(ctx, endpointID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyEndpointsChanged()
+	return updated, nil
 }
 
 // func (s *Service) GetEndpointEssentials(ctx context.Context) ([]EndpointBasicsDTO, error) {
@@ -0,0 +1,499 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badgerv/monitoring-api/internal/notify"
+	"github.com/google/uuid"
+)
+
+// SLO kinds. An availability SLO counts successful checks; a latency SLO counts the
+// successful checks that were at most LatencyThresholdMs, so "p95 under 300ms" is a
+// latency SLO with a 95% target and a 300ms threshold.
+const (
+	SLOAvailability = "availability"
+	SLOLatency      = "latency"
+)
+
+// Burn alert levels, from the multi-window burn rate alerts of the Google SRE workbook
+const (
+	BurnFast = "fast" // 2% of the budget spent within an hour
+	BurnSlow = "slow" // 5% of the budget spent within six hours
+)
+
+const (
+	defaultSLOWindowDays = 30
+	maxSLOWindowDays     = 90
+
+	// defaultSLOEvaluationInterval is how often burn rates are checked for alerts.
+	defaultSLOEvaluationInterval = time.Minute
+)
+
+var ErrSLONotFound = errors.New("SLO not found")
+
+// burnWindow pairs a long and a short lookback. An alert fires only when both burn
+// faster than the threshold: the long window proves it matters, the short one that
+// it is still happening.
+type burnWindow struct {
+	Level       string
+	Long, Short time.Duration
+	BudgetSpent float64 // fraction of the budget the long window may spend at the threshold
+}
+
+var burnWindows = []burnWindow{
+	{Level: BurnFast, Long: time.Hour, Short: 5 * time.Minute, BudgetSpent: 0.02},
+	{Level: BurnSlow, Long: 6 * time.Hour, Short: 30 * time.Minute, BudgetSpent: 0.05},
+}
+
+// threshold is the burn rate at which the long window spends its share of the budget;
+// 14.4 and 6 for a 30 day SLO.
+func (b burnWindow) threshold(window time.Duration) float64 {
+	return b.BudgetSpent * float64(window) / float64(b.Long)
+}
+
+// SLO is a target for the checks of an endpoint or of every endpoint with a tag,
+// measured over the trailing WindowDays. Checks during maintenance don't count. Latency
+// SLOs need the latency of every check, so their window can't outlast the raw checks
+// kept for CHECK_RETENTION_DAYS; availability SLOs also count the daily rollups.
+type SLO struct {
+	ID                 int        `json:"id"`
+	Name               string     `json:"name"`
+	ScopeType          string     `json:"scope_type"`
+	ScopeValue         string     `json:"scope_value"`
+	Kind               string     `json:"kind"`
+	Target             float64    `json:"target"` // percent, e.g. 99.9
+	LatencyThresholdMs *int       `json:"latency_threshold_ms"`
+	WindowDays         int        `json:"window_days"`
+	AlertLevel         *string    `json:"alert_level"`
+	AlertChangedAt     *time.Time `json:"alert_changed_at"`
+	CreatedByID        *uuid.UUID `json:"created_by_id"`
+	CreatedBy          string     `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// SLOWindowCounts is how many checks counted towards an SLO over a lookback, and how
+// many of them were good.
+type SLOWindowCounts struct {
+	Total int64
+	Good  int64
+}
+
+// BurnRate is how fast a window spends the error budget; 1 spends exactly the budget
+// over the SLO window. Nil without checks.
+type BurnRate struct {
+	Window    string   `json:"window"`
+	Rate      *float64 `json:"rate"`
+	Threshold float64  `json:"threshold"`
+	Level     string   `json:"level"`
+}
+
+// SLOStatus is an SLO with its current attainment and error budget. ErrorBudgetRemaining
+// is the fraction of the budget left, negative once it is overspent.
+type SLOStatus struct {
+	SLO
+	TotalChecks          int64      `json:"total_checks"`
+	GoodChecks           int64      `json:"good_checks"`
+	Attainment           *float64   `json:"attainment"` // percent of good checks
+	Met                  bool       `json:"met"`
+	AllowedBadChecks     float64    `json:"allowed_bad_checks"`
+	ErrorBudgetRemaining *float64   `json:"error_budget_remaining"`
+	BurnRates            []BurnRate `json:"burn_rates"`
+}
+
+func (slo *SLO) window() time.Duration {
+	return time.Duration(slo.WindowDays) * 24 * time.Hour
+}
+
+// errorBudget is the fraction of checks allowed to be bad.
+func (slo *SLO) errorBudget() float64 {
+	return 1 - slo.Target/100
+}
+
+// ValidateSLO checks the scope, kind, target and window, defaulting the window to 30 days
+func ValidateSLO(slo *SLO) error {
+	if strings.TrimSpace(slo.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch slo.ScopeType {
+	case ScopeEndpoint, ScopeTag:
+		if err := validateScope(slo.ScopeType, slo.ScopeValue); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("scope_type must be endpoint or tag")
+	}
+
+	switch slo.Kind {
+	case SLOAvailability:
+		if slo.LatencyThresholdMs != nil {
+			return fmt.Errorf("latency_threshold_ms only applies to latency SLOs")
+		}
+	case SLOLatency:
+		if slo.LatencyThresholdMs == nil || *slo.LatencyThresholdMs <= 0 {
+			return fmt.Errorf("latency SLOs need a positive latency_threshold_ms")
+		}
+	default:
+		return fmt.Errorf("kind must be availability or latency")
+	}
+
+	if slo.Target <= 0 || slo.Target >= 100 {
+		return fmt.Errorf("target must be a percentage between 0 and 100, exclusive")
+	}
+
+	if slo.WindowDays == 0 {
+		slo.WindowDays = defaultSLOWindowDays
+	}
+	if slo.WindowDays < 1 || slo.WindowDays > maxSLOWindowDays {
+		return fmt.Errorf("window_days must be between 1 and %d", maxSLOWindowDays)
+	}
+	if slo.Kind == SLOLatency {
+		retention, err := LoadRetentionConfig()
+		if err != nil {
+			return err
+		}
+		if slo.WindowDays > retention.CheckRetentionDays {
+			return fmt.Errorf("latency SLOs are computed from raw checks, so window_days can't exceed CHECK_RETENTION_DAYS (%d)", retention.CheckRetentionDays)
+		}
+	}
+	return nil
+}
+
+// lookbacks lists the SLO window followed by every burn window, in the order
+// GetSLOCounts returns them.
+func (slo *SLO) lookbacks() []time.Duration {
+	lookbacks := []time.Duration{slo.window()}
+	for _, b := range burnWindows {
+		lookbacks = append(lookbacks, b.Long, b.Short)
+	}
+	return lookbacks
+}
+
+// burnRate is the bad fraction of a lookback divided by the error budget.
+func (slo *SLO) burnRate(c SLOWindowCounts) *float64 {
+	if c.Total == 0 {
+		return nil
+	}
+	rate := float64(c.Total-c.Good) / float64(c.Total) / slo.errorBudget()
+	return &rate
+}
+
+// status computes attainment, budget and burn rates from counts in lookbacks() order.
+// alertLevel is the most severe burn pair whose long and short windows both exceed
+// the threshold.
+func (slo *SLO) status(counts []SLOWindowCounts) (*SLOStatus, string) {
+	st := &SLOStatus{SLO: *slo, BurnRates: []BurnRate{}}
+
+	window := counts[0]
+	st.TotalChecks = window.Total
+	st.GoodChecks = window.Good
+	st.AllowedBadChecks = slo.errorBudget() * float64(window.Total)
+	if window.Total > 0 {
+		attainment := float64(window.Good) / float64(window.Total) * 100
+		st.Attainment = &attainment
+		st.Met = attainment >= slo.Target
+
+		remaining := 1 - float64(window.Total-window.Good)/st.AllowedBadChecks
+		st.ErrorBudgetRemaining = &remaining
+	}
+
+	alertLevel := ""
+	for i, b := range burnWindows {
+		threshold := b.threshold(slo.window())
+		long := slo.burnRate(counts[1+2*i])
+		short := slo.burnRate(counts[2+2*i])
+		st.BurnRates = append(st.BurnRates,
+			BurnRate{Window: formatLookback(b.Long), Rate: long, Threshold: threshold, Level: b.Level},
+			BurnRate{Window: formatLookback(b.Short), Rate: short, Threshold: threshold, Level: b.Level},
+		)
+		if alertLevel == "" && long != nil && short != nil && *long > threshold && *short > threshold {
+			alertLevel = b.Level
+		}
+	}
+	return st, alertLevel
+}
+
+// formatLookback renders 5m, 30m, 1h or 6h rather than Go's 5m0s.
+func formatLookback(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	}
+	return strconv.Itoa(int(d/time.Minute)) + "m"
+}
+
+func (s *Service) sloStatus(ctx context.Context, slo *SLO) (*SLOStatus, string, error) {
+	counts, err := s.dbRepo.GetSLOCounts(ctx, slo)
+	if err != nil {
+		return nil, "", err
+	}
+	st, level := slo.status(counts)
+	return st, level, nil
+}
+
+func (s *Service) CreateSLO(ctx context.Context, slo SLO, actor Actor) (*SLO, error) {
+	if err := ValidateSLO(&slo); err != nil {
+		return nil, err
+	}
+	slo.CreatedBy = actor.Username
+	slo.CreatedByID = actor.UserID
+	return s.dbRepo.CreateSLO(ctx, &slo)
+}
+
+// UpdateSLO replaces an SLO's definition; its alert state carries over
+func (s *Service) UpdateSLO(ctx context.Context, slo SLO) (*SLO, error) {
+	if err := ValidateSLO(&slo); err != nil {
+		return nil, err
+	}
+	return s.dbRepo.UpdateSLO(ctx, &slo)
+}
+
+func (s *Service) DeleteSLO(ctx context.Context, id int) error {
+	return s.dbRepo.DeleteSLO(ctx, id)
+}
+
+// GetSLOStatus returns an SLO with its attainment, error budget and burn rates
+func (s *Service) GetSLOStatus(ctx context.Context, id int) (*SLOStatus, error) {
+	slo, err := s.dbRepo.GetSLO(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	st, _, err := s.sloStatus(ctx, slo)
+	return st, err
+}
+
+// ListSLOStatuses returns every SLO with its attainment, error budget and burn rates
+func (s *Service) ListSLOStatuses(ctx context.Context) ([]SLOStatus, error) {
+	slos, err := s.dbRepo.ListSLOs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]SLOStatus, 0, len(slos))
+	for i := range slos {
+		st, _, err := s.sloStatus(ctx, &slos[i])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *st)
+	}
+	return statuses, nil
+}
+
+// RunSLOJob evaluates burn rate alerts every SLO_EVALUATION_INTERVAL. Only the replica
+// running checks evaluates, so each alert is sent once.
+func (s *Service) RunSLOJob(ctx context.Context) {
+	ticker := time.NewTicker(envSeconds("SLO_EVALUATION_INTERVAL", defaultSLOEvaluationInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.runsChecks() {
+				continue
+			}
+			if err := s.EvaluateSLOs(ctx); err != nil {
+				log.Printf("SLO evaluation failed: %v", err)
+			}
+		}
+	}
+}
+
+// runsChecks reports whether this replica's scheduler is running and leading.
+func (s *Service) runsChecks() bool {
+	s.schedMu.Lock()
+	defer s.schedMu.Unlock()
+	return s.scheduler != nil && s.scheduler.IsLeader()
+}
+
+// EvaluateSLOs alerts when an SLO starts burning its budget too fast, escalates from
+// slow to fast burn, or stops burning.
+func (s *Service) EvaluateSLOs(ctx context.Context) error {
+	slos, err := s.dbRepo.ListSLOs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range slos {
+		slo := &slos[i]
+		st, level, err := s.sloStatus(ctx, slo)
+		if err != nil {
+			log.Printf("Failed to evaluate SLO %d: %v", slo.ID, err)
+			continue
+		}
+
+		previous := ""
+		if slo.AlertLevel != nil {
+			previous = *slo.AlertLevel
+		}
+		if level == previous {
+			continue
+		}
+
+		if s.sloAlertSuppressed(ctx, slo) {
+			// Not recorded, so the alert goes out once the suppression ends
+			continue
+		}
+
+		changed, err := s.dbRepo.SetSLOAlertLevel(ctx, slo.ID, previous, level)
+		if err != nil {
+			log.Printf("Failed to record alert level of SLO %d: %v", slo.ID, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		msg, err := sloMessage(st, previous, level)
+		if err != nil {
+			log.Printf("Failed to render SLO alert for SLO %d: %v", slo.ID, err)
+			continue
+		}
+		s.deliverSLOMessage(slo, msg)
+	}
+	return nil
+}
+
+// sloAlertSuppressed applies the maintenance windows and silences of an endpoint SLO's
+// endpoint. Tag SLOs span endpoints and are never suppressed as a whole.
+func (s *Service) sloAlertSuppressed(ctx context.Context, slo *SLO) bool {
+	if slo.ScopeType != ScopeEndpoint {
+		return false
+	}
+	endpointID, _ := strconv.Atoi(slo.ScopeValue)
+	ep, err := s.dbRepo.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		log.Printf("Failed to load endpoint of SLO %d: %v", slo.ID, err)
+		return false
+	}
+	sup, err := s.activeSuppression(ctx, *ep)
+	if err != nil {
+		log.Printf("Failed to load maintenance windows and silences for endpoint %d: %v", ep.ID, err)
+	}
+	return sup.suppresses("slo", *ep)
+}
+
+// deliverSLOMessage sends an SLO alert to the channels routed to its endpoint or tag,
+// and to the endpoint's subscribers.
+func (s *Service) deliverSLOMessage(slo *SLO, msg notify.Message) {
+	if s.notifier == nil {
+		log.Printf("Alert for SLO %s not sent: notifications are not configured", slo.Name)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var target notify.Target
+		var extra []notify.Channel
+		if slo.ScopeType == ScopeEndpoint {
+			endpointID, _ := strconv.Atoi(slo.ScopeValue)
+			target.EndpointID = endpointID
+
+			tags, err := s.dbRepo.GetEndpointTags(ctx, endpointID)
+			if err != nil {
+				log.Printf("Failed to load tags for endpoint %d: %v", endpointID, err)
+			}
+			target.Tags = tags
+
+			recipients, err := s.alertRecipients(ctx, endpointID)
+			if err != nil {
+				log.Printf("Failed to load alert recipients for endpoint %d: %v", endpointID, err)
+			} else if len(recipients) > 0 {
+				extra = append(extra, s.notifier.EmailTo(recipients...))
+			}
+		} else {
+			target.Tags = []string{slo.ScopeValue}
+		}
+
+		if err := s.notifier.Notify(ctx, target, msg, extra...); err != nil {
+			log.Printf("Failed to deliver alert for SLO %d: %v", slo.ID, err)
+		}
+	}()
+}
+
+var sloEmailTemplate = template.Must(template.New("slo").Parse(`
+	<html>
+	<head>
+		<style>
+			body { font-family: Arial, sans-serif; }
+			.container { border: 1px solid #ddd; padding: 16px; border-radius: 8px; }
+			.title { font-size: 20px; font-weight: bold; margin-bottom: 12px; }
+			.burning { color: #d32f2f; }
+			.resolved { color: #2e7d32; }
+			.section { margin-bottom: 8px; }
+			.label { font-weight: bold; }
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			{{if .Level}}
+			<div class="title burning">{{.SLO.Name}} is burning its error budget ({{.Level}} burn)</div>
+			{{else}}
+			<div class="title resolved">{{.SLO.Name}} is no longer burning its error budget</div>
+			{{end}}
+
+			<div class="section"><span class="label">Scope:</span> {{.SLO.ScopeType}} {{.SLO.ScopeValue}}</div>
+			<div class="section"><span class="label">Target:</span> {{.SLO.Target}}% {{.SLO.Kind}} over {{.SLO.WindowDays}} days</div>
+			{{range .Fields}}<div class="section"><span class="label">{{.Name}}:</span> {{.Value}}</div>{{end}}
+		</div>
+	</body>
+	</html>`))
+
+// sloMessage renders a burn rate alert, or its resolution when level is empty.
+func sloMessage(st *SLOStatus, previous, level string) (notify.Message, error) {
+	scope := fmt.Sprintf("%s %s", st.ScopeType, st.ScopeValue)
+	msg := notify.Message{
+		Event: notify.EventSLOBurn,
+		Fields: []notify.Field{
+			{Name: "Scope", Value: scope},
+			{Name: "Target", Value: fmt.Sprintf("%g%% %s over %d days", st.Target, st.Kind, st.WindowDays)},
+		},
+		Data: map[string]interface{}{
+			"slo":            st,
+			"alert_level":    level,
+			"previous_level": previous,
+		},
+	}
+	if st.Attainment != nil {
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Attainment", Value: fmt.Sprintf("%.3f%%", *st.Attainment)})
+	}
+	if st.ErrorBudgetRemaining != nil {
+		msg.Fields = append(msg.Fields, notify.Field{Name: "Error budget left", Value: fmt.Sprintf("%.1f%%", *st.ErrorBudgetRemaining*100)})
+	}
+	for _, b := range st.BurnRates {
+		if b.Rate != nil && (level == "" || b.Level == level) {
+			msg.Fields = append(msg.Fields, notify.Field{Name: "Burn rate " + b.Window, Value: fmt.Sprintf("%.1fx (alert at %.1fx)", *b.Rate, b.Threshold)})
+		}
+	}
+
+	switch level {
+	case BurnFast:
+		msg.Severity = notify.SeverityCritical
+		msg.Title = fmt.Sprintf("[SLO] %s is burning its error budget fast", st.Name)
+		msg.Text = fmt.Sprintf("At this rate %s spends 2%% of its %d day error budget every hour", scope, st.WindowDays)
+	case BurnSlow:
+		msg.Severity = notify.SeverityWarning
+		msg.Title = fmt.Sprintf("[SLO] %s is burning its error budget", st.Name)
+		msg.Text = fmt.Sprintf("At this rate %s spends 5%% of its %d day error budget every 6 hours", scope, st.WindowDays)
+	default:
+		msg.Severity = notify.SeverityResolved
+		msg.Title = fmt.Sprintf("[SLO] %s is no longer burning its error budget", st.Name)
+		msg.Text = fmt.Sprintf("The %s burn of %s has stopped", previous, scope)
+	}
+
+	builder := &strings.Builder{}
+	err := sloEmailTemplate.Execute(builder, map[string]interface{}{"SLO": st, "Level": level, "Fields": msg.Fields[2:]})
+	if err != nil {
+		return msg, err
+	}
+	msg.HTML = builder.String()
+	return msg, nil
+}
@@ -0,0 +1,273 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Component statuses shown on the public status page, from best to worst
+const (
+	ComponentUnknown     = "unknown" // nothing in scope has been checked yet
+	ComponentOperational = "operational"
+	ComponentMaintenance = "maintenance"
+	ComponentDegraded    = "degraded" // some of the component's endpoints are down
+	ComponentOutage      = "outage"   // all of them are down
+)
+
+const (
+	// statusPageDays is how many daily uptime bars each component shows.
+	statusPageDays = 90
+
+	// statusPageIncidents caps the incidents listed on the status page.
+	statusPageIncidents = 50
+
+	// statusPageCacheTTL is how long the public status page is served from memory, so
+	// unauthenticated traffic can't put load on the database.
+	statusPageCacheTTL = 30 * time.Second
+)
+
+var ErrStatusComponentNotFound = errors.New("status page component not found")
+
+// StatusComponent is an endpoint, tag or server published on the status page under a
+// display name. Nothing about the endpoints behind it (URLs, errors, servers) is shown.
+type StatusComponent struct {
+	ID          int        `json:"id"`
+	DisplayName string     `json:"display_name"`
+	Description string     `json:"description"`
+	ScopeType   string     `json:"scope_type"`
+	ScopeValue  string     `json:"scope_value"`
+	Position    int        `json:"position"`
+	CreatedByID *uuid.UUID `json:"created_by_id"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// componentHealth is how many of a component's endpoints there are, are down, and were
+// last checked during maintenance.
+type componentHealth struct {
+	Endpoints     int
+	Down          int
+	InMaintenance int
+}
+
+func (h componentHealth) status() string {
+	switch {
+	case h.Endpoints == 0:
+		return ComponentUnknown
+	case h.Down == h.Endpoints:
+		return ComponentOutage
+	case h.Down > 0:
+		return ComponentDegraded
+	case h.InMaintenance > 0:
+		return ComponentMaintenance
+	default:
+		return ComponentOperational
+	}
+}
+
+// statusRank orders statuses so the page shows its worst component's.
+var statusRank = map[string]int{
+	ComponentUnknown:     0,
+	ComponentOperational: 1,
+	ComponentMaintenance: 2,
+	ComponentDegraded:    3,
+	ComponentOutage:      4,
+}
+
+// UptimeDay is one daily bar. Uptime is a percentage, nil for a day without checks.
+type UptimeDay struct {
+	Date        string   `json:"date"`
+	TotalChecks int64    `json:"-"`
+	GoodChecks  int64    `json:"-"`
+	Uptime      *float64 `json:"uptime"`
+}
+
+// PublicComponent is a component as shown on the status page.
+type PublicComponent struct {
+	ID          int         `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Status      string      `json:"status"`
+	Uptime      *float64    `json:"uptime"` // over all the days shown
+	Days        []UptimeDay `json:"days"`
+}
+
+// PublicIncident is an incident of one or more components, without its error.
+type PublicIncident struct {
+	ID              int        `json:"id"`
+	Components      []string   `json:"components"`
+	Status          string     `json:"status"` // ongoing or resolved
+	StartedAt       time.Time  `json:"started_at"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+	DurationSeconds *int64     `json:"duration_seconds"`
+}
+
+// PublicStatus is the unauthenticated status page: overall status, each component with
+// its daily uptime, and ongoing and recent incidents.
+type PublicStatus struct {
+	Status     string            `json:"status"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Components []PublicComponent `json:"components"`
+	Incidents  []PublicIncident  `json:"incidents"`
+}
+
+// publicIncidentRow is an incident with the IDs of the components it affects.
+type publicIncidentRow struct {
+	PublicIncident
+	ComponentIDs []int
+}
+
+// ValidateStatusComponent checks the display name and scope
+func ValidateStatusComponent(sc *StatusComponent) error {
+	sc.DisplayName = strings.TrimSpace(sc.DisplayName)
+	if sc.DisplayName == "" {
+		return fmt.Errorf("display_name is required")
+	}
+	return validateScope(sc.ScopeType, sc.ScopeValue)
+}
+
+func (s *Service) ListStatusComponents(ctx context.Context) ([]StatusComponent, error) {
+	return s.dbRepo.ListStatusComponents(ctx)
+}
+
+func (s *Service) CreateStatusComponent(ctx context.Context, sc StatusComponent, actor Actor) (*StatusComponent, error) {
+	if err := ValidateStatusComponent(&sc); err != nil {
+		return nil, err
+	}
+	sc.CreatedBy = actor.Username
+	sc.CreatedByID = actor.UserID
+
+	created, err := s.dbRepo.CreateStatusComponent(ctx, &sc)
+	if err == nil {
+		s.invalidateStatusPage()
+	}
+	return created, err
+}
+
+func (s *Service) UpdateStatusComponent(ctx context.Context, sc StatusComponent) (*StatusComponent, error) {
+	if err := ValidateStatusComponent(&sc); err != nil {
+		return nil, err
+	}
+	updated, err := s.dbRepo.UpdateStatusComponent(ctx, &sc)
+	if err == nil {
+		s.invalidateStatusPage()
+	}
+	return updated, err
+}
+
+func (s *Service) DeleteStatusComponent(ctx context.Context, id int) error {
+	err := s.dbRepo.DeleteStatusComponent(ctx, id)
+	if err == nil {
+		s.invalidateStatusPage()
+	}
+	return err
+}
+
+func (s *Service) invalidateStatusPage() {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.status = nil
+}
+
+// GetPublicStatus returns the status page, rebuilt at most every statusPageCacheTTL
+func (s *Service) GetPublicStatus(ctx context.Context) (*PublicStatus, error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	if s.status != nil && time.Since(s.status.UpdatedAt) < statusPageCacheTTL {
+		return s.status, nil
+	}
+
+	status, err := s.buildPublicStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.status = status
+	return status, nil
+}
+
+func (s *Service) buildPublicStatus(ctx context.Context) (*PublicStatus, error) {
+	components, err := s.dbRepo.ListStatusComponents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	health, err := s.dbRepo.GetStatusComponentHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.dbRepo.GetStatusComponentDays(ctx, statusPageDays)
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := s.dbRepo.ListStatusPageIncidents(ctx, statusPageDays, statusPageIncidents)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &PublicStatus{
+		Status:     ComponentUnknown,
+		UpdatedAt:  time.Now(),
+		Components: make([]PublicComponent, 0, len(components)),
+		Incidents:  make([]PublicIncident, 0, len(incidents)),
+	}
+
+	names := map[int]string{}
+	for _, sc := range components {
+		names[sc.ID] = sc.DisplayName
+
+		pc := PublicComponent{
+			ID:          sc.ID,
+			Name:        sc.DisplayName,
+			Description: sc.Description,
+			Status:      health[sc.ID].status(),
+			Days:        days[sc.ID],
+		}
+		if pc.Days == nil {
+			pc.Days = []UptimeDay{}
+		}
+
+		var total, good int64
+		for _, d := range pc.Days {
+			total += d.TotalChecks
+			good += d.GoodChecks
+		}
+		if total > 0 {
+			uptime := float64(good) / float64(total) * 100
+			pc.Uptime = &uptime
+		}
+
+		if statusRank[pc.Status] > statusRank[page.Status] {
+			page.Status = pc.Status
+		}
+		page.Components = append(page.Components, pc)
+	}
+
+	for _, inc := range incidents {
+		inc.Components = []string{}
+		for _, id := range inc.ComponentIDs {
+			inc.Components = append(inc.Components, names[id])
+		}
+		page.Incidents = append(page.Incidents, inc.PublicIncident)
+	}
+	return page, nil
+}
+
+// GetComponentStatus returns a published component's display name and current status,
+// for its badge
+func (s *Service) GetComponentStatus(ctx context.Context, id int) (name string, status string, err error) {
+	page, err := s.GetPublicStatus(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	for _, pc := range page.Components {
+		if pc.ID == id {
+			return pc.Name, pc.Status, nil
+		}
+	}
+	return "", "", ErrStatusComponentNotFound
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// checkTransport is used for every HTTP check. Keep-alives are off so each check
+// opens its own connection and the DNS, connect and TLS phases are always measured
+// rather than hidden by a pooled connection.
+var checkTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DisableKeepAlives = true
+	return t
+}()
+
+// CheckTiming breaks an HTTP check down into phases, in milliseconds. TTFB is the
+// wait between sending the request and the first response byte, so the phases add
+// up to the whole request. A phase that didn't happen, like TLS on plain HTTP or all
+// of them for non-HTTP checks, is nil.
+type CheckTiming struct {
+	DNSMs      *float64 `json:"dns_ms"`
+	ConnectMs  *float64 `json:"connect_ms"`
+	TLSMs      *float64 `json:"tls_ms"`
+	TTFBMs     *float64 `json:"ttfb_ms"`
+	TransferMs *float64 `json:"transfer_ms"`
+}
+
+// phaseTimer collects the httptrace events of one request. Connection attempts can
+// race (happy eyeballs), so events are recorded under a lock and the first of each
+// kind wins.
+type phaseTimer struct {
+	mu                  sync.Mutex
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	wroteRequest        time.Time
+	firstByte           time.Time
+	bodyDone            time.Time
+}
+
+func (p *phaseTimer) mark(t *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// trace returns the hooks that feed the timer.
+func (p *phaseTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { p.mark(&p.dnsStart) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { p.mark(&p.dnsDone) },
+		ConnectStart: func(string, string) { p.mark(&p.connStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				p.mark(&p.connDone)
+			}
+		},
+		TLSHandshakeStart: func() { p.mark(&p.tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				p.mark(&p.tlsDone)
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { p.mark(&p.wroteRequest) },
+		GotFirstResponseByte: func() { p.mark(&p.firstByte) },
+	}
+}
+
+// finishBody records when the response body was fully read.
+func (p *phaseTimer) finishBody() {
+	p.mark(&p.bodyDone)
+}
+
+func phaseMs(start, end time.Time) *float64 {
+	if start.IsZero() || end.IsZero() {
+		return nil
+	}
+	ms := float64(end.Sub(start).Microseconds()) / 1000
+	return &ms
+}
+
+// timing turns the recorded events into phase durations.
+func (p *phaseTimer) timing() CheckTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return CheckTiming{
+		DNSMs:      phaseMs(p.dnsStart, p.dnsDone),
+		ConnectMs:  phaseMs(p.connStart, p.connDone),
+		TLSMs:      phaseMs(p.tlsStart, p.tlsDone),
+		TTFBMs:     phaseMs(p.wroteRequest, p.firstByte),
+		TransferMs: phaseMs(p.firstByte, p.bodyDone),
+	}
+}
@@ -0,0 +1,678 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Endpoint import and export formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatCSV  = "csv"
+)
+
+// Import modes
+const (
+	ImportUpsert = "upsert" // create new endpoints and update the ones that match
+	ImportSync   = "sync"   // upsert, then delete endpoints missing from the file
+)
+
+// Import actions
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportDelete    = "delete"
+	ImportUnchanged = "unchanged"
+)
+
+// maxImportEndpoints caps how many endpoints one import may contain.
+const maxImportEndpoints = 5000
+
+// redactedValue replaces header values in an export without secrets. Importing it keeps
+// the value the endpoint already has.
+const redactedValue = "********"
+
+var ErrImportNotApplied = errors.New("import failed, nothing was applied")
+
+// EndpointSpec is the portable form of an endpoint, with its endpoint_info metadata and
+// without anything the database assigns. A JSON export is a valid endpoints.json.
+type EndpointSpec struct {
+	ServiceName          string            `json:"service_name"`
+	URL                  string            `json:"url"`
+	ServerName           string            `json:"server_name"`
+	CheckType            string            `json:"check_type"`
+	APIMethod            string            `json:"api_method"`
+	ExpectedCode         int               `json:"expected_status_code"`
+	CheckIntervalSeconds *int              `json:"check_interval_seconds,omitempty"`
+	TimeoutSeconds       *int              `json:"timeout_seconds,omitempty"`
+	Tags                 []string          `json:"tags,omitempty"`
+	GitlabURL            *string           `json:"gitlab_url,omitempty"`
+	DockerContainerName  *string           `json:"docker_container_name,omitempty"`
+	KubernetesPodName    *string           `json:"kubernetes_pod_name,omitempty"`
+	Description          *string           `json:"description,omitempty"`
+	Headers              map[string]string `json:"headers,omitempty"`
+	QueryParams          map[string]string `json:"query_params,omitempty"`
+	BodyTemplate         *string           `json:"body_template,omitempty"`
+	Assertions           []Assertion       `json:"assertions,omitempty"`
+	CheckConfig          *CheckConfig      `json:"check_config,omitempty"`
+}
+
+// ImportOptions controls how an import is applied.
+type ImportOptions struct {
+	Mode   string
+	DryRun bool
+}
+
+// ImportChange is what an import does, or would do, to one endpoint. Fields lists the
+// fields an update changes.
+type ImportChange struct {
+	Action      string   `json:"action"`
+	EndpointID  *int     `json:"endpoint_id,omitempty"`
+	ServiceName string   `json:"service_name"`
+	URL         string   `json:"url"`
+	APIMethod   string   `json:"api_method"`
+	Fields      []string `json:"fields,omitempty"`
+	Error       string   `json:"error,omitempty"`
+
+	endpoint Endpoint
+}
+
+// ImportResult is the diff of an import. The changes are applied in one transaction:
+// Applied is false on a dry run, and when a change failed, which then is the one
+// change with an Error and counted in Failed.
+type ImportResult struct {
+	Mode      string         `json:"mode"`
+	DryRun    bool           `json:"dry_run"`
+	Applied   bool           `json:"applied"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Deleted   int            `json:"deleted"`
+	Unchanged int            `json:"unchanged"`
+	Failed    int            `json:"failed"`
+	Changes   []ImportChange `json:"changes"`
+}
+
+// ImportError lists every problem found in an import file. Nothing is applied while
+// there are any.
+type ImportError struct {
+	Problems []string
+}
+
+func (e *ImportError) Error() string {
+	return "invalid import: " + strings.Join(e.Problems, "; ")
+}
+
+// ParseFormat accepts a format name or a file extension
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), ".")) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatYAML, "yml":
+		return FormatYAML, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("format must be json, yaml or csv")
+	}
+}
+
+// specFromEndpoint is the spec an endpoint would be exported as.
+func specFromEndpoint(ep Endpoint) EndpointSpec {
+	cfg := ep.CheckConfig
+	spec := EndpointSpec{
+		ServiceName:          ep.ServiceName,
+		URL:                  ep.URL,
+		ServerName:           ep.ServerName,
+		CheckType:            ep.CheckType,
+		APIMethod:            ep.APIMethod,
+		ExpectedCode:         ep.ExpectedCode,
+		CheckIntervalSeconds: ep.CheckIntervalSeconds,
+		TimeoutSeconds:       ep.TimeoutSeconds,
+		Tags:                 ep.Tags,
+		GitlabURL:            ep.GitlabURL,
+		DockerContainerName:  ep.DockerContainerName,
+		KubernetesPodName:    ep.KubernetesPodName,
+		Description:          ep.Description,
+		Headers:              ep.Headers,
+		QueryParams:          ep.QueryParams,
+		BodyTemplate:         ep.BodyTemplate,
+		Assertions:           ep.Assertions,
+		CheckConfig:          &cfg,
+	}
+	spec.normalize()
+	return spec
+}
+
+// endpoint is the endpoint a spec describes.
+func (spec EndpointSpec) endpoint() Endpoint {
+	ep := Endpoint{
+		ServiceName:          spec.ServiceName,
+		URL:                  spec.URL,
+		ServerName:           spec.ServerName,
+		CheckType:            spec.CheckType,
+		APIMethod:            spec.APIMethod,
+		ExpectedCode:         spec.ExpectedCode,
+		CheckIntervalSeconds: spec.CheckIntervalSeconds,
+		TimeoutSeconds:       spec.TimeoutSeconds,
+		Tags:                 spec.Tags,
+		GitlabURL:            spec.GitlabURL,
+		DockerContainerName:  spec.DockerContainerName,
+		KubernetesPodName:    spec.KubernetesPodName,
+		Description:          spec.Description,
+		Headers:              spec.Headers,
+		QueryParams:          spec.QueryParams,
+		BodyTemplate:         spec.BodyTemplate,
+		Assertions:           spec.Assertions,
+	}
+	if spec.CheckConfig != nil {
+		ep.CheckConfig = *spec.CheckConfig
+	}
+	return ep
+}
+
+// normalize applies the defaults and URL rules endpoints are stored with, and drops
+// empty values, so a spec compares equal to the export of the endpoint it created.
+func (spec *EndpointSpec) normalize() {
+	spec.ServiceName = strings.TrimSpace(spec.ServiceName)
+	spec.URL = normalizeURL(spec.URL)
+	spec.ServerName = strings.TrimSpace(spec.ServerName)
+	spec.APIMethod = normalizeMethod(spec.APIMethod)
+	if spec.CheckType == "" {
+		spec.CheckType = CheckHTTP
+	}
+
+	if len(spec.Tags) == 0 {
+		spec.Tags = nil
+	}
+	if len(spec.Headers) == 0 {
+		spec.Headers = nil
+	}
+	if len(spec.QueryParams) == 0 {
+		spec.QueryParams = nil
+	}
+	if len(spec.Assertions) == 0 {
+		spec.Assertions = nil
+	}
+	if spec.CheckConfig != nil && reflect.DeepEqual(*spec.CheckConfig, CheckConfig{}) {
+		spec.CheckConfig = nil
+	}
+	for _, s := range []**string{&spec.GitlabURL, &spec.DockerContainerName, &spec.KubernetesPodName, &spec.Description, &spec.BodyTemplate} {
+		if *s != nil && **s == "" {
+			*s = nil
+		}
+	}
+}
+
+// importKey identifies the endpoint a spec updates.
+func (spec EndpointSpec) importKey() string {
+	return spec.APIMethod + " " + spec.URL
+}
+
+// changedFields names the fields, by their JSON name, that differ between two specs.
+func changedFields(from, to EndpointSpec) []string {
+	fields := []string{}
+	a, b := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < a.NumField(); i++ {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// ParseEndpointSpecs decodes an import file. JSON and YAML hold a list of endpoints;
+// CSV has a header row naming the columns, see csvColumns.
+func ParseEndpointSpecs(format string, data []byte) ([]EndpointSpec, error) {
+	var err error
+	switch format {
+	case FormatYAML:
+		data, err = yamlToJSON(data)
+	case FormatCSV:
+		data, err = csvToJSON(data)
+	}
+	if err != nil {
+		return nil, &ImportError{Problems: []string{err.Error()}}
+	}
+
+	var specs []EndpointSpec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&specs); err != nil {
+		return nil, &ImportError{Problems: []string{fmt.Sprintf("failed to parse %s: %v", format, err)}}
+	}
+	if len(specs) > maxImportEndpoints {
+		return nil, &ImportError{Problems: []string{fmt.Sprintf("at most %d endpoints can be imported at once", maxImportEndpoints)}}
+	}
+	return specs, nil
+}
+
+// EncodeEndpointSpecs renders specs in the given format, readable by ParseEndpointSpecs
+func EncodeEndpointSpecs(format string, specs []EndpointSpec) ([]byte, error) {
+	data, err := json.MarshalIndent(specs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatYAML:
+		return jsonToYAML(data)
+	case FormatCSV:
+		return jsonToCSV(data)
+	default:
+		return append(data, '\n'), nil
+	}
+}
+
+// yamlToJSON converts YAML to JSON, so YAML is decoded with the same json tags.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse yaml: %w", err)
+	}
+	if v == nil {
+		v = []interface{}{}
+	}
+	return json.Marshal(v)
+}
+
+// jsonToYAML re-encodes JSON as block-style YAML, keeping the key order.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var plain func(n *yaml.Node)
+	plain = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			plain(c)
+		}
+	}
+	plain(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// csvColumns are the columns of a CSV export. Tags are separated by ";"; headers,
+// query_params, assertions and check_config hold JSON. An empty cell is unset.
+var csvColumns = []string{"service_name", "url", "server_name", "check_type", "api_method", "expected_status_code",
+	"check_interval_seconds", "timeout_seconds", "tags", "gitlab_url", "docker_container_name", "kubernetes_pod_name",
+	"description", "headers", "query_params", "body_template", "assertions", "check_config"}
+
+var (
+	csvNumberColumns = map[string]bool{"expected_status_code": true, "check_interval_seconds": true, "timeout_seconds": true}
+	csvJSONColumns   = map[string]bool{"headers": true, "query_params": true, "assertions": true, "check_config": true}
+)
+
+const csvTagSeparator = ";"
+
+// csvToJSON turns CSV rows into a JSON list of endpoints.
+func csvToJSON(data []byte) ([]byte, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse csv: %w", err)
+	}
+	if len(rows) == 0 {
+		return []byte("[]"), nil
+	}
+
+	header := rows[0]
+	known := map[string]bool{}
+	for _, col := range csvColumns {
+		known[col] = true
+	}
+	for i, col := range header {
+		header[i] = strings.TrimSpace(col)
+		if !known[header[i]] {
+			return nil, fmt.Errorf("unknown csv column %q", col)
+		}
+	}
+
+	endpoints := make([]map[string]interface{}, 0, len(rows)-1)
+	for n, row := range rows[1:] {
+		ep := map[string]interface{}{}
+		for i, cell := range row {
+			col := header[i]
+			if strings.TrimSpace(cell) == "" {
+				continue
+			}
+			switch {
+			case col == "tags":
+				tags := []string{}
+				for _, tag := range strings.Split(cell, csvTagSeparator) {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						tags = append(tags, tag)
+					}
+				}
+				ep[col] = tags
+			case csvNumberColumns[col]:
+				v, err := strconv.Atoi(strings.TrimSpace(cell))
+				if err != nil {
+					return nil, fmt.Errorf("csv row %d: %s must be a number", n+2, col)
+				}
+				ep[col] = v
+			case csvJSONColumns[col]:
+				if !json.Valid([]byte(cell)) {
+					return nil, fmt.Errorf("csv row %d: %s must be JSON", n+2, col)
+				}
+				ep[col] = json.RawMessage(cell)
+			default:
+				ep[col] = cell
+			}
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return json.Marshal(endpoints)
+}
+
+// jsonToCSV writes a JSON list of endpoints as CSV rows under csvColumns.
+func jsonToCSV(data []byte) ([]byte, error) {
+	var endpoints []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvColumns); err != nil {
+		return nil, err
+	}
+	for _, ep := range endpoints {
+		row := make([]string, len(csvColumns))
+		for i, col := range csvColumns {
+			raw, ok := ep[col]
+			if !ok {
+				continue
+			}
+			switch {
+			case col == "tags":
+				var tags []string
+				if err := json.Unmarshal(raw, &tags); err != nil {
+					return nil, err
+				}
+				row[i] = strings.Join(tags, csvTagSeparator)
+			case csvNumberColumns[col]:
+				row[i] = string(raw)
+			case csvJSONColumns[col]:
+				var compact bytes.Buffer
+				if err := json.Compact(&compact, raw); err != nil {
+					return nil, err
+				}
+				row[i] = compact.String()
+			default:
+				if err := json.Unmarshal(raw, &row[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// maskHeaders returns the header names with every value masked, nil for no headers.
+// Header values often hold credentials such as Authorization tokens.
+func maskHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(headers))
+	for name := range headers {
+		masked[name] = redactedValue
+	}
+	return masked
+}
+
+// redactHeaders masks the spec's header values.
+func (spec *EndpointSpec) redactHeaders() {
+	spec.Headers = maskHeaders(spec.Headers)
+}
+
+// restoreHeaders puts back the values of the headers a redacted export masked, from
+// the endpoint the spec updates. It returns the masked headers it has no value for.
+func (spec *EndpointSpec) restoreHeaders(existing *Endpoint) []string {
+	headers, missing := unmaskHeaders(spec.Headers, existing)
+	spec.Headers = headers
+	return missing
+}
+
+// unmaskHeaders returns a copy of headers with every masked value replaced by the one
+// stored on existing, and the sorted names of the masked headers existing lacks.
+func unmaskHeaders(headers map[string]string, existing *Endpoint) (map[string]string, []string) {
+	missing := []string{}
+	if headers == nil {
+		return nil, missing
+	}
+	unmasked := make(map[string]string, len(headers))
+	for name, value := range headers {
+		unmasked[name] = value
+		if value != redactedValue {
+			continue
+		}
+		if stored, ok := existing.headerValue(name); ok {
+			unmasked[name] = stored
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return unmasked, missing
+}
+
+func (ep *Endpoint) headerValue(name string) (string, bool) {
+	if ep == nil {
+		return "", false
+	}
+	value, ok := ep.Headers[name]
+	return value, ok
+}
+
+// ExportEndpoints renders every endpoint in the given format, ordered by service name
+// and URL so exports diff cleanly in git. Header values are masked unless
+// includeSecrets is set.
+func (s *Service) ExportEndpoints(ctx context.Context, format string, includeSecrets bool) ([]byte, error) {
+	endpoints, err := s.dbRepo.ListEndpointsWithInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make([]EndpointSpec, 0, len(endpoints))
+	for _, ep := range endpoints {
+		spec := specFromEndpoint(ep)
+		if !includeSecrets {
+			spec.redactHeaders()
+		}
+		specs = append(specs, spec)
+	}
+	sort.SliceStable(specs, func(i, j int) bool {
+		if specs[i].ServiceName != specs[j].ServiceName {
+			return specs[i].ServiceName < specs[j].ServiceName
+		}
+		return specs[i].importKey() < specs[j].importKey()
+	})
+	return EncodeEndpointSpecs(format, specs)
+}
+
+// ImportEndpoints creates and updates endpoints from an import file, matching existing
+// endpoints by URL and method; in sync mode it also deletes those missing from the
+// file. Every endpoint is validated before anything is applied, and the changes are
+// applied all together or not at all. A heartbeat without a URL gets a new one;
+// masked header values keep the value of the endpoint they update.
+func (s *Service) ImportEndpoints(ctx context.Context, format string, data []byte, opts ImportOptions, actor Actor) (*ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = ImportUpsert
+	}
+	if opts.Mode != ImportUpsert && opts.Mode != ImportSync {
+		return nil, &ImportError{Problems: []string{"mode must be upsert or sync"}}
+	}
+
+	specs, err := ParseEndpointSpecs(format, data)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.dbRepo.ListEndpointsWithInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := planImport(specs, current, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if err := s.applyImport(ctx, result, actor); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// planImport diffs the specs against the current endpoints.
+func planImport(specs []EndpointSpec, current []Endpoint, opts ImportOptions) (*ImportResult, error) {
+	byKey := map[string]*Endpoint{}
+	byURL := map[string]*Endpoint{}
+	for i := range current {
+		spec := specFromEndpoint(current[i])
+		byKey[spec.importKey()] = &current[i]
+		byURL[spec.URL] = &current[i]
+	}
+
+	result := &ImportResult{Mode: opts.Mode, DryRun: opts.DryRun, Changes: []ImportChange{}}
+	problems := []string{}
+	seen := map[string]int{}
+	seenURL := map[string]int{}
+	matched := map[int]bool{}
+
+	for i, spec := range specs {
+		spec.normalize()
+		key := spec.importKey()
+		label := fmt.Sprintf("endpoint %d (%s %s)", i+1, spec.APIMethod, spec.URL)
+		if missing := spec.restoreHeaders(byKey[key]); len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: headers %s are redacted and there is no endpoint to keep their values from; export with include_secrets=true", label, strings.Join(missing, ", ")))
+			continue
+		}
+
+		ep := spec.endpoint()
+		if ep.CheckType == CheckHeartbeat && ep.URL == "" {
+			if err := assignHeartbeatURL(&ep, nil); err != nil {
+				return nil, err
+			}
+			spec.URL = ep.URL
+			key = spec.importKey()
+			label = fmt.Sprintf("endpoint %d (%s %s)", i+1, spec.APIMethod, spec.URL)
+		}
+
+		if err := ValidateEndpoint(&ep); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: same url and api_method as endpoint %d", label, first))
+			continue
+		}
+		if first, ok := seenURL[spec.URL]; ok {
+			problems = append(problems, fmt.Sprintf("%s: same url as endpoint %d; urls must be unique", label, first))
+			continue
+		}
+		seen[key] = i + 1
+		seenURL[spec.URL] = i + 1
+
+		change := ImportChange{ServiceName: spec.ServiceName, URL: spec.URL, APIMethod: spec.APIMethod, endpoint: ep}
+		existing := byKey[key]
+		switch {
+		case existing != nil:
+			id := existing.ID
+			change.EndpointID = &id
+			matched[id] = true
+			change.Fields = changedFields(specFromEndpoint(*existing), spec)
+			if len(change.Fields) == 0 {
+				change.Action = ImportUnchanged
+				change.Fields = nil
+				result.Unchanged++
+			} else {
+				change.Action = ImportUpdate
+				result.Updated++
+			}
+		case byURL[spec.URL] != nil:
+			other := byURL[spec.URL]
+			problems = append(problems, fmt.Sprintf("%s: url is already used by endpoint %d with api_method %s", label, other.ID, other.APIMethod))
+			continue
+		default:
+			change.Action = ImportCreate
+			result.Created++
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	if len(problems) > 0 {
+		return nil, &ImportError{Problems: problems}
+	}
+
+	if opts.Mode == ImportSync {
+		for _, ep := range current {
+			if matched[ep.ID] {
+				continue
+			}
+			id := ep.ID
+			result.Changes = append(result.Changes, ImportChange{
+				Action: ImportDelete, EndpointID: &id, ServiceName: ep.ServiceName, URL: ep.URL, APIMethod: ep.APIMethod,
+			})
+			result.Deleted++
+		}
+	}
+	return result, nil
+}
+
+// applyImport applies a planned import in one transaction. When a change fails, the
+// result records which and nothing is applied.
+func (s *Service) applyImport(ctx context.Context, result *ImportResult, actor Actor) error {
+	failed, err := s.dbRepo.ApplyEndpointImport(ctx, result.Changes, actor.Username)
+	if err != nil {
+		for i := range result.Changes {
+			if result.Changes[i].Action == ImportCreate {
+				result.Changes[i].EndpointID = nil
+			}
+		}
+		if failed >= 0 {
+			result.Changes[failed].Error = err.Error()
+			result.Failed = 1
+		}
+		log.Printf("Endpoint import by %s (%s) rolled back: %v", actor.Username, result.Mode, err)
+		return fmt.Errorf("%w: %v", ErrImportNotApplied, err)
+	}
+	result.Applied = true
+
+	log.Printf("Endpoint import by %s (%s): %d created, %d updated, %d deleted",
+		actor.Username, result.Mode, result.Created, result.Updated, result.Deleted)
+	if result.Created+result.Updated+result.Deleted > 0 {
+		s.notifyEndpointsChanged()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaskHeadersRoundTrip(t *testing.T) {
+	stored := &Endpoint{Headers: map[string]string{"Authorization": "Bearer secret", "Accept": "application/json"}}
+
+	masked := maskHeaders(stored.Headers)
+	for name, value := range masked {
+		if value != redactedValue {
+			t.Errorf("maskHeaders()[%q] = %q, want %q", name, value, redactedValue)
+		}
+	}
+
+	sent := map[string]string{"Authorization": masked["Authorization"], "Accept": "text/plain", "X-Api-Key": redactedValue}
+	got, missing := unmaskHeaders(sent, stored)
+	want := map[string]string{"Authorization": "Bearer secret", "Accept": "text/plain", "X-Api-Key": redactedValue}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unmaskHeaders() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(missing, []string{"X-Api-Key"}) {
+		t.Errorf("unmaskHeaders() missing = %v, want [X-Api-Key]", missing)
+	}
+	if sent["Authorization"] != redactedValue {
+		t.Errorf("unmaskHeaders() modified its input")
+	}
+}
+
+func TestMaskHeadersNil(t *testing.T) {
+	if got := maskHeaders(nil); got != nil {
+		t.Errorf("maskHeaders(nil) = %v, want nil", got)
+	}
+	if got, missing := unmaskHeaders(nil, nil); got != nil || len(missing) != 0 {
+		t.Errorf("unmaskHeaders(nil, nil) = %v, %v, want nil and no missing headers", got, missing)
+	}
+}
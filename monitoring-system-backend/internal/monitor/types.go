@@ -1,12 +1,17 @@
 package monitor
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Endpoint struct {
     ID                  int       `json:"id,omitempty"`
     ServiceName         string    `json:"service_name"`
     URL                 string    `json:"url"`
     ServerName          string    `json:"server_name"`
+    CheckType           string    `json:"check_type"`
     APIMethod           string    `json:"api_method"`
     ExpectedCode        int       `json:"expected_status_code"`
     GitlabURL           *string   `json:"gitlab_url,omitempty"`
@@ -15,6 +20,56 @@ type Endpoint struct {
     Tags                []string  `json:"tags,omitempty"`
     Description         *string   `json:"description,omitempty"`
     LastChangedBy       *string   `json:"last_changed_by,omitempty"`
+
+    // Outbound request configuration used by the check engine
+    Headers             map[string]string `json:"headers,omitempty"`
+    QueryParams         map[string]string `json:"query_params,omitempty"`
+    BodyTemplate        *string           `json:"body_template,omitempty"`
+
+    // Response assertions that must all pass for the endpoint to count as up
+    Assertions          []Assertion       `json:"assertions,omitempty"`
+
+    // Scheduling; nil falls back to CHECK_TIMER and CHECK_TIMEOUT
+    CheckIntervalSeconds *int             `json:"check_interval_seconds,omitempty"`
+    TimeoutSeconds       *int             `json:"timeout_seconds,omitempty"`
+
+    // Settings of the tcp, dns and grpc check types
+    CheckConfig          CheckConfig      `json:"check_config"`
+}
+
+// ScheduleConfig holds how often an endpoint is checked and how long a check may take.
+type ScheduleConfig struct {
+    CheckIntervalSeconds *int `json:"check_interval_seconds"`
+    TimeoutSeconds       *int `json:"timeout_seconds"`
+}
+
+// RequestConfig holds the editable parts of the request sent when checking an endpoint.
+type RequestConfig struct {
+    APIMethod    string            `json:"api_method"`
+    Headers      map[string]string `json:"headers"`
+    QueryParams  map[string]string `json:"query_params"`
+    BodyTemplate *string           `json:"body_template"`
+}
+
+// CheckResult is the outcome of a single request against an endpoint.
+type CheckResult struct {
+    EndpointID int
+    StatusCode int
+    Latency    time.Duration
+    Success    bool
+    Error      string
+
+    // Set when the status code matched but a response assertion did not
+    FailedAssertion *AssertionError
+
+    // ID of the checks row once a scheduled result has been stored
+    CheckID int64
+
+    // Requests made, including confirmation retries
+    Attempts int
+
+    // Per-phase breakdown of the last request; empty for non-HTTP checks
+    Timing CheckTiming
 }
 
 
@@ -27,6 +82,20 @@ type EndpointDetail struct {
 	APIMethod    string `db:"api_method" json:"api_method"`
 	ExpectedCode int    `db:"expected_status_code" json:"expected_code"`
 
+	// Check type and its settings (endpoints table)
+	CheckType   string      `db:"check_type" json:"check_type"`
+	CheckConfig CheckConfig `db:"check_config" json:"check_config"`
+
+	// Request configuration (endpoints table)
+	Headers      map[string]string `db:"headers" json:"headers"`
+	QueryParams  map[string]string `db:"query_params" json:"query_params"`
+	BodyTemplate *string           `db:"body_template" json:"body_template"`
+	Assertions   []Assertion       `db:"assertions" json:"assertions"`
+
+	// Scheduling (endpoints table)
+	CheckIntervalSeconds *int `db:"check_interval_seconds" json:"check_interval_seconds"`
+	TimeoutSeconds       *int `db:"timeout_seconds" json:"timeout_seconds"`
+
 	// Extra info (endpoint_info table)
 	Description         string   `db:"description" json:"description"`
 	GitlabURL           string   `db:"gitlab_url" json:"gitlab_url"`
@@ -45,6 +114,24 @@ type EndpointDetail struct {
 	LastRunSucceeded string  `db:"last_run" json:"last_run_succeeded"` // true = succeeded, false = failed
 	FailureCount     int     `db:"failure_count" json:"failure_count"`
 
+	// Alert state; set once failure_count reaches ALERT_FAILURE_THRESHOLD
+	IsDown    bool       `db:"is_down" json:"is_down"`
+	DownSince *time.Time `db:"down_since" json:"down_since"`
+
+	// Flap state; alerts are suppressed while the endpoint is flapping
+	IsFlapping    bool       `db:"is_flapping" json:"is_flapping"`
+	FlappingSince *time.Time `db:"flapping_since" json:"flapping_since"`
+
+	// Rolling uptime and latency percentiles keyed by window (1h, 24h, 7d, 30d)
+	Windows map[string]*WindowStats `json:"windows"`
+
+	// Average of each request phase over the last 24 hours
+	AvgTiming24h CheckTiming `json:"avg_timing_24h"`
+
+	// Last TLS certificate inspection; nil for plain HTTP or not yet inspected
+	Certificate             *CertificateInfo `json:"certificate"`
+	CertificateDaysToExpiry *int             `json:"certificate_days_to_expiry"`
+
 	// Timestamps
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
@@ -77,13 +164,210 @@ type EndpointBasicsDTO struct {
 	AverageLatency		float64		`json:"avg_latency"`
 	LastRun				bool		`json:"last_run"`
 	FailureCount		int			`json:"failure_count"`
+	Windows				map[string]*WindowStats	`json:"windows"`
 }
 
 type AggregateDTO struct {
-	TotalEndpoints     int     `json:"total_endpoints"`
-	TotalChecks        int     `json:"total_checks"`
-	SuccessfulChecks   int     `json:"successful_checks"`
-	DownTimeCount      int     `json:"down_time_count"`
-	OverallUptime      float64 `json:"overall_uptime"`
-	AverageLatency     float64 `json:"average_latency"`
-}
\ No newline at end of file
+	TotalEndpoints   int                     `json:"total_endpoints"`
+	TotalChecks      int                     `json:"total_checks"`
+	SuccessfulChecks int                     `json:"successful_checks"`
+	DownTimeCount    int                     `json:"down_time_count"`
+	OverallUptime    float64                 `json:"overall_uptime"`
+	AverageLatency   float64                 `json:"average_latency"`
+	Windows          map[string]*WindowStats `json:"windows"`
+}
+
+// WindowStats is uptime and latency over one rolling window. Percentiles are estimated
+// from the fixed latency histogram kept in check_rollups_hourly.
+type WindowStats struct {
+	TotalChecks      int      `json:"total_checks"`
+	SuccessfulChecks int      `json:"successful_checks"`
+	Uptime           *float64 `json:"uptime"` // nil when no checks ran in the window
+	AvgLatency       float64  `json:"avg_latency"`
+	P50Latency       float64  `json:"p50_latency"`
+	P95Latency       float64  `json:"p95_latency"`
+	P99Latency       float64  `json:"p99_latency"`
+}
+
+// SchedulerState is the desired scheduler state persisted so it survives restarts.
+type SchedulerState struct {
+	Running     bool       `json:"running"`
+	ChangedByID *uuid.UUID `json:"changed_by_id"`
+	ChangedBy   *string    `json:"changed_by"`
+	ChangedAt   *time.Time `json:"changed_at"`
+}
+
+// SchedulerAction records who started or stopped the scheduler, and when.
+type SchedulerAction struct {
+	ID        int        `json:"id"`
+	Action    string     `json:"action"` // start, stop or restore
+	ActorID   *uuid.UUID `json:"actor_id"`
+	ActorName string     `json:"actor_name"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Actor identifies the user behind a change, taken from the auth context.
+type Actor struct {
+	UserID   *uuid.UUID
+	Username string
+}
+
+// EndpointPatch is a partial endpoint update; nil fields are left unchanged.
+type EndpointPatch struct {
+	ServiceName          *string            `json:"service_name"`
+	URL                  *string            `json:"url"`
+	ServerName           *string            `json:"server_name"`
+	CheckType            *string            `json:"check_type"`
+	CheckConfig          *CheckConfig       `json:"check_config"`
+	APIMethod            *string            `json:"api_method"`
+	ExpectedCode         *int               `json:"expected_status_code"`
+	GitlabURL            *string            `json:"gitlab_url"`
+	DockerContainerName  *string            `json:"docker_container_name"`
+	KubernetesPodName    *string            `json:"kubernetes_pod_name"`
+	Tags                 *[]string          `json:"tags"`
+	Description          *string            `json:"description"`
+	Headers              *map[string]string `json:"headers"`
+	QueryParams          *map[string]string `json:"query_params"`
+	BodyTemplate         *string            `json:"body_template"`
+	Assertions           *[]Assertion       `json:"assertions"`
+	CheckIntervalSeconds *int               `json:"check_interval_seconds"`
+	TimeoutSeconds       *int               `json:"timeout_seconds"`
+}
+
+// Apply copies the fields set on the patch onto ep.
+func (p EndpointPatch) Apply(ep *Endpoint) {
+	if p.ServiceName != nil {
+		ep.ServiceName = *p.ServiceName
+	}
+	if p.URL != nil {
+		ep.URL = *p.URL
+	}
+	if p.ServerName != nil {
+		ep.ServerName = *p.ServerName
+	}
+	if p.CheckType != nil {
+		ep.CheckType = *p.CheckType
+	}
+	if p.CheckConfig != nil {
+		ep.CheckConfig = *p.CheckConfig
+	}
+	if p.APIMethod != nil {
+		ep.APIMethod = *p.APIMethod
+	}
+	if p.ExpectedCode != nil {
+		ep.ExpectedCode = *p.ExpectedCode
+	}
+	if p.GitlabURL != nil {
+		ep.GitlabURL = p.GitlabURL
+	}
+	if p.DockerContainerName != nil {
+		ep.DockerContainerName = p.DockerContainerName
+	}
+	if p.KubernetesPodName != nil {
+		ep.KubernetesPodName = p.KubernetesPodName
+	}
+	if p.Tags != nil {
+		ep.Tags = *p.Tags
+	}
+	if p.Description != nil {
+		ep.Description = p.Description
+	}
+	if p.Headers != nil {
+		ep.Headers = *p.Headers
+	}
+	if p.QueryParams != nil {
+		ep.QueryParams = *p.QueryParams
+	}
+	if p.BodyTemplate != nil {
+		ep.BodyTemplate = p.BodyTemplate
+	}
+	if p.Assertions != nil {
+		ep.Assertions = *p.Assertions
+	}
+	if p.CheckIntervalSeconds != nil {
+		ep.CheckIntervalSeconds = p.CheckIntervalSeconds
+	}
+	if p.TimeoutSeconds != nil {
+		ep.TimeoutSeconds = p.TimeoutSeconds
+	}
+}
+
+// EndpointChange is one entry in an endpoint's change log. It outlives the endpoint
+// so deletions stay auditable.
+type EndpointChange struct {
+	ID         int       `json:"id"`
+	EndpointID int       `json:"endpoint_id"`
+	Action     string    `json:"action"` // create, update or delete
+	ChangedBy  string    `json:"changed_by"`
+	Before     *Endpoint `json:"before,omitempty"`
+	After      *Endpoint `json:"after,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CheckRecord is one row of the checks table.
+type CheckRecord struct {
+	ID            int64       `json:"id"`
+	EndpointID    int         `json:"endpoint_id"`
+	StatusCode    int         `json:"status_code"`
+	LatencyMs     int         `json:"latency_ms"`
+	Success       bool        `json:"success"`
+	Error         string      `json:"error"`
+	Attempts      int         `json:"attempts"`
+	InMaintenance bool        `json:"in_maintenance"`
+	Timing        CheckTiming `json:"timing"`
+	CheckedAt     time.Time   `json:"checked_at"`
+}
+
+// CheckHistoryQuery filters an endpoint's check history. Cursor is the opaque
+// next_cursor of the previous page.
+type CheckHistoryQuery struct {
+	From          *time.Time
+	To            *time.Time
+	Status        string // "up", "down" or empty for both
+	ErrorContains string
+	Cursor        string
+	Limit         int
+}
+
+// CheckHistoryPage is one page of check history, newest first.
+type CheckHistoryPage struct {
+	Checks     []CheckRecord `json:"checks"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+// CheckBucket summarises the checks of one endpoint within a minute or hour.
+type CheckBucket struct {
+	BucketStart      time.Time `json:"bucket_start"`
+	TotalChecks      int       `json:"total_checks"`
+	SuccessfulChecks int       `json:"successful_checks"`
+	Availability     float64   `json:"availability"`
+	MinLatency       float64   `json:"min_latency"`
+	AvgLatency       float64   `json:"avg_latency"`
+	MaxLatency       float64   `json:"max_latency"`
+
+	// Average of each request phase over the bucket's HTTP checks
+	AvgTiming CheckTiming `json:"avg_timing"`
+}
+
+// RetentionRun reports what one retention run rolled up and pruned.
+type RetentionRun struct {
+	ID                  int           `json:"id"`
+	Trigger             string        `json:"trigger"` // scheduled or manual
+	TriggeredBy         *string       `json:"triggered_by"`
+	StartedAt           time.Time     `json:"started_at"`
+	FinishedAt          *time.Time    `json:"finished_at"`
+	HourlyRollups       int64         `json:"hourly_rollups"`
+	DailyRollups        int64         `json:"daily_rollups"`
+	ChecksPruned        int64         `json:"checks_pruned"`
+	HourlyRollupsPruned int64         `json:"hourly_rollups_pruned"`
+	PrunedByEndpoint    map[int]int64 `json:"pruned_by_endpoint"`
+	Error               *string       `json:"error"`
+}
+
+// EndpointSubscription is a user who receives an endpoint's DOWN and RECOVERED alerts.
+type EndpointSubscription struct {
+	EndpointID int       `json:"endpoint_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Username   string    `json:"username"`
+	CreatedAt  time.Time `json:"created_at"`
+}
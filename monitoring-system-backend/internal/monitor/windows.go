@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// latencyBucketBounds are the upper-exclusive edges (ms) of the latency histogram kept
+// per hourly rollup. Bucket i holds latencies in [bounds[i-1], bounds[i]); the last
+// bucket holds everything from the final bound up. The rollup backfill in
+// migrations_to_be_done uses the same edges.
+var latencyBucketBounds = []int64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// uptimeWindows are the rolling windows reported next to the lifetime counters.
+// The 1h window is read from raw checks, the others from hourly rollups.
+var uptimeWindows = []struct {
+	Name string
+	Span time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// latencyBucket returns the zero-based histogram bucket for a latency.
+func latencyBucket(latencyMs int64) int {
+	return sort.Search(len(latencyBucketBounds), func(i int) bool {
+		return latencyBucketBounds[i] > latencyMs
+	})
+}
+
+// windowAccumulator gathers rollup rows for one endpoint (or all of them) and window.
+type windowAccumulator struct {
+	total      int
+	successful int
+	latencySum int64
+	latencyMax int64
+	histogram  []int64
+}
+
+func newWindowAccumulator() *windowAccumulator {
+	return &windowAccumulator{histogram: make([]int64, len(latencyBucketBounds)+1)}
+}
+
+func (a *windowAccumulator) merge(o *windowAccumulator) {
+	a.total += o.total
+	a.successful += o.successful
+	a.latencySum += o.latencySum
+	if o.latencyMax > a.latencyMax {
+		a.latencyMax = o.latencyMax
+	}
+	for i, n := range o.histogram {
+		a.histogram[i] += n
+	}
+}
+
+// percentile estimates the p-th latency percentile by interpolating inside the
+// histogram bucket that holds it. The open-ended last bucket is capped at the
+// largest latency seen.
+func (a *windowAccumulator) percentile(p float64) float64 {
+	var count int64
+	for _, n := range a.histogram {
+		count += n
+	}
+	if count == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(count)
+	var seen int64
+	for i, n := range a.histogram {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+
+		var lower, upper float64
+		if i > 0 {
+			lower = float64(latencyBucketBounds[i-1])
+		}
+		if i < len(latencyBucketBounds) {
+			upper = float64(latencyBucketBounds[i])
+		} else {
+			upper = float64(a.latencyMax)
+		}
+		if upper > float64(a.latencyMax) && a.latencyMax > 0 {
+			upper = float64(a.latencyMax)
+		}
+		if upper < lower {
+			upper = lower
+		}
+		return lower + (upper-lower)*(rank-float64(seen))/float64(n)
+	}
+	return float64(a.latencyMax)
+}
+
+func (a *windowAccumulator) stats() *WindowStats {
+	ws := &WindowStats{
+		TotalChecks:      a.total,
+		SuccessfulChecks: a.successful,
+	}
+	if a.total > 0 {
+		uptime := float64(a.successful) / float64(a.total) * 100
+		ws.Uptime = &uptime
+		ws.AvgLatency = float64(a.latencySum) / float64(a.total)
+		ws.P50Latency = a.percentile(50)
+		ws.P95Latency = a.percentile(95)
+		ws.P99Latency = a.percentile(99)
+	}
+	return ws
+}
+
+// windowSet holds the accumulators of every rolling window for one endpoint.
+type windowSet map[string]*windowAccumulator
+
+func newWindowSet() windowSet {
+	ws := windowSet{}
+	for _, w := range uptimeWindows {
+		ws[w.Name] = newWindowAccumulator()
+	}
+	return ws
+}
+
+func (ws windowSet) stats() map[string]*WindowStats {
+	out := make(map[string]*WindowStats, len(ws))
+	for name, acc := range ws {
+		out[name] = acc.stats()
+	}
+	return out
+}
+
+// mergeWindowSets combines per-endpoint windows into fleet-wide ones.
+func mergeWindowSets(sets map[int]windowSet) windowSet {
+	all := newWindowSet()
+	for _, set := range sets {
+		for name, acc := range set {
+			all[name].merge(acc)
+		}
+	}
+	return all
+}
@@ -0,0 +1,271 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set on every generic webhook request. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	HeaderEvent     = "X-Devoptic-Event"
+	HeaderTimestamp = "X-Devoptic-Timestamp"
+	HeaderSignature = "X-Devoptic-Signature"
+)
+
+// EmailSender is the part of emailservice.EmailService the email channel needs.
+type EmailSender interface {
+	Send(subject, body string, to []string) error
+	SendHTML(subject, htmlBody string, to []string) error
+}
+
+// EmailChannel sends messages to a fixed list of addresses.
+type EmailChannel struct {
+	sender     EmailSender
+	recipients []string
+}
+
+func NewEmailChannel(sender EmailSender, recipients []string) *EmailChannel {
+	return &EmailChannel{sender: sender, recipients: recipients}
+}
+
+func (c *EmailChannel) Type() string { return ChannelEmail }
+
+func (c *EmailChannel) Send(ctx context.Context, msg Message) error {
+	if c.sender == nil {
+		return fmt.Errorf("email is not configured")
+	}
+	if len(c.recipients) == 0 {
+		return nil
+	}
+	if msg.HTML != "" {
+		return c.sender.SendHTML(msg.Title, msg.HTML, c.recipients)
+	}
+	return c.sender.Send(msg.Title, plainText(msg), c.recipients)
+}
+
+// SlackChannel posts to a Slack incoming webhook.
+type SlackChannel struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func NewSlackChannel(webhookURL string, client *http.Client) *SlackChannel {
+	return &SlackChannel{webhookURL: webhookURL, client: client}
+}
+
+func (c *SlackChannel) Type() string { return ChannelSlack }
+
+func (c *SlackChannel) Send(ctx context.Context, msg Message) error {
+	fields := make([]map[string]interface{}, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		fields = append(fields, map[string]interface{}{"title": f.Name, "value": f.Value, "short": true})
+	}
+
+	payload := map[string]interface{}{
+		"text": msg.Title,
+		"attachments": []map[string]interface{}{{
+			"color":  severityColor(msg.Severity),
+			"title":  msg.Title,
+			"text":   msg.Text,
+			"fields": fields,
+			"footer": msg.Event,
+			"ts":     time.Now().Unix(),
+		}},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, c.client, c.webhookURL, body, nil)
+}
+
+// TeamsChannel posts a MessageCard to an MS Teams incoming webhook.
+type TeamsChannel struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func NewTeamsChannel(webhookURL string, client *http.Client) *TeamsChannel {
+	return &TeamsChannel{webhookURL: webhookURL, client: client}
+}
+
+func (c *TeamsChannel) Type() string { return ChannelTeams }
+
+func (c *TeamsChannel) Send(ctx context.Context, msg Message) error {
+	facts := make([]map[string]string, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		facts = append(facts, map[string]string{"name": f.Name, "value": f.Value})
+	}
+
+	payload := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    msg.Title,
+		"themeColor": strings.TrimPrefix(severityColor(msg.Severity), "#"),
+		"title":      msg.Title,
+		"text":       msg.Text,
+		"sections":   []map[string]interface{}{{"facts": facts}},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, c.client, c.webhookURL, body, nil)
+}
+
+// WebhookChannel posts the full message as JSON, signed with a shared secret so the
+// receiver can verify it came from us.
+type WebhookChannel struct {
+	url     string
+	secret  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhookChannel(url, secret string, headers map[string]string, client *http.Client) *WebhookChannel {
+	return &WebhookChannel{url: url, secret: secret, headers: headers, client: client}
+}
+
+func (c *WebhookChannel) Type() string { return ChannelWebhook }
+
+func (c *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := map[string]string{
+		HeaderEvent:     msg.Event,
+		HeaderTimestamp: timestamp,
+	}
+	for k, v := range c.headers {
+		headers[k] = v
+	}
+	if c.secret != "" {
+		headers[HeaderSignature] = Sign(c.secret, timestamp, body)
+	}
+
+	return postJSON(ctx, c.client, c.url, body, headers)
+}
+
+// Sign computes the webhook signature header value for a timestamp and body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewChannel builds a channel from its stored configuration.
+func NewChannel(cfg ChannelConfig, email EmailSender, client *http.Client) (Channel, error) {
+	var settings struct {
+		WebhookURL string            `json:"webhook_url"`
+		URL        string            `json:"url"`
+		Secret     string            `json:"secret"`
+		Headers    map[string]string `json:"headers"`
+		Recipients []string          `json:"recipients"`
+	}
+	if len(cfg.Config) > 0 {
+		if err := json.Unmarshal(cfg.Config, &settings); err != nil {
+			return nil, fmt.Errorf("invalid %s channel config: %w", cfg.Type, err)
+		}
+	}
+
+	switch cfg.Type {
+	case ChannelEmail:
+		if len(settings.Recipients) == 0 {
+			return nil, fmt.Errorf("email channel needs at least one recipient")
+		}
+		return NewEmailChannel(email, settings.Recipients), nil
+	case ChannelSlack:
+		if err := validateURL(settings.WebhookURL); err != nil {
+			return nil, fmt.Errorf("slack channel: %w", err)
+		}
+		return NewSlackChannel(settings.WebhookURL, client), nil
+	case ChannelTeams:
+		if err := validateURL(settings.WebhookURL); err != nil {
+			return nil, fmt.Errorf("teams channel: %w", err)
+		}
+		return NewTeamsChannel(settings.WebhookURL, client), nil
+	case ChannelWebhook:
+		if err := validateURL(settings.URL); err != nil {
+			return nil, fmt.Errorf("webhook channel: %w", err)
+		}
+		return NewWebhookChannel(settings.URL, settings.Secret, settings.Headers, client), nil
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", cfg.Type)
+	}
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("a valid http(s) url is required")
+	}
+	return nil
+}
+
+// postJSON sends body and treats any non-2xx response as a failed delivery.
+func postJSON(ctx context.Context, client *http.Client, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+func severityColor(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "#c62828"
+	case SeverityWarning:
+		return "#f9a825"
+	case SeverityResolved:
+		return "#2e7d32"
+	default:
+		return "#1565c0"
+	}
+}
+
+func plainText(msg Message) string {
+	var b strings.Builder
+	b.WriteString(msg.Text)
+	for _, f := range msg.Fields {
+		fmt.Fprintf(&b, "\n%s: %s", f.Name, f.Value)
+	}
+	return b.String()
+}
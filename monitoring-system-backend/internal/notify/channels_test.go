@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// received is one request captured by the stand-in server.
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// standIn starts a local HTTP server answering every request with status and sending
+// what it received on the returned channel.
+func standIn(t *testing.T, status int) (*httptest.Server, <-chan received) {
+	t.Helper()
+	reqs := make(chan received, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+		io.WriteString(w, "stand-in says hi")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs
+}
+
+// routedRepo routes every event to a fixed set of channels.
+type routedRepo struct {
+	Repository
+	channels []ChannelConfig
+}
+
+func (r routedRepo) MatchChannels(ctx context.Context, target Target, event string) ([]ChannelConfig, error) {
+	return r.channels, nil
+}
+
+// notifyThrough sends msg through a Service whose only route is a channel of the given
+// type and config, delivering with the stand-in server's client.
+func notifyThrough(t *testing.T, srv *httptest.Server, channelType string, config map[string]interface{}, msg Message) error {
+	t.Helper()
+	raw, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewService(routedRepo{channels: []ChannelConfig{{ID: 1, Name: channelType, Type: channelType, Config: raw, Enabled: true}}}, nil)
+	svc.SetHTTPClient(srv.Client())
+	return svc.Notify(context.Background(), Target{EndpointID: 7}, msg)
+}
+
+var testMessage = Message{
+	Event:    EventEndpointDown,
+	Severity: SeverityCritical,
+	Title:    "[DOWN] orders (staging-1)",
+	Text:     "orders failed 3 consecutive checks",
+	Fields:   []Field{{Name: "Server", Value: "staging-1"}, {Name: "Consecutive failures", Value: "3"}},
+	Data:     map[string]interface{}{"endpoint_id": 7},
+}
+
+func TestSlackPayload(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	if err := notifyThrough(t, srv, ChannelSlack, map[string]interface{}{"webhook_url": srv.URL}, testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-reqs
+	var payload struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color  string `json:"color"`
+			Title  string `json:"title"`
+			Text   string `json:"text"`
+			Footer string `json:"footer"`
+			Fields []struct {
+				Title string `json:"title"`
+				Value string `json:"value"`
+				Short bool   `json:"short"`
+			} `json:"fields"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if payload.Text != testMessage.Title || len(payload.Attachments) != 1 {
+		t.Fatalf("unexpected payload %s", req.body)
+	}
+	att := payload.Attachments[0]
+	if att.Color != severityColor(SeverityCritical) || att.Text != testMessage.Text || att.Footer != EventEndpointDown {
+		t.Errorf("unexpected attachment %+v", att)
+	}
+	if len(att.Fields) != 2 || att.Fields[1].Title != "Consecutive failures" || att.Fields[1].Value != "3" || !att.Fields[1].Short {
+		t.Errorf("unexpected fields %+v", att.Fields)
+	}
+}
+
+func TestTeamsPayload(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	if err := notifyThrough(t, srv, ChannelTeams, map[string]interface{}{"webhook_url": srv.URL}, testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-reqs
+	var payload struct {
+		Type       string `json:"@type"`
+		Summary    string `json:"summary"`
+		ThemeColor string `json:"themeColor"`
+		Title      string `json:"title"`
+		Text       string `json:"text"`
+		Sections   []struct {
+			Facts []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"facts"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.Type != "MessageCard" || payload.Title != testMessage.Title || payload.Text != testMessage.Text {
+		t.Errorf("unexpected card %s", req.body)
+	}
+	if strings.HasPrefix(payload.ThemeColor, "#") {
+		t.Errorf("themeColor %q must not start with #", payload.ThemeColor)
+	}
+	if len(payload.Sections) != 1 || len(payload.Sections[0].Facts) != 2 || payload.Sections[0].Facts[0].Value != "staging-1" {
+		t.Errorf("unexpected sections %+v", payload.Sections)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusAccepted)
+	config := map[string]interface{}{
+		"url":     srv.URL,
+		"secret":  "s3cret",
+		"headers": map[string]string{"Authorization": "Bearer abc"},
+	}
+	if err := notifyThrough(t, srv, ChannelWebhook, config, testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-reqs
+	timestamp := req.header.Get(HeaderTimestamp)
+	if timestamp == "" {
+		t.Fatal("missing timestamp header")
+	}
+	if got, want := req.header.Get(HeaderSignature), Sign("s3cret", timestamp, req.body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := req.header.Get(HeaderEvent); got != EventEndpointDown {
+		t.Errorf("event header = %q", got)
+	}
+	if got := req.header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("custom header = %q", got)
+	}
+
+	var body struct {
+		Event string                 `json:"event"`
+		Data  map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil || body.Event != EventEndpointDown || body.Data["endpoint_id"] != float64(7) {
+		t.Errorf("unexpected body %s (%v)", req.body, err)
+	}
+}
+
+func TestNon2xxIsAnError(t *testing.T) {
+	srv, _ := standIn(t, http.StatusInternalServerError)
+
+	for _, tt := range []struct {
+		channelType string
+		config      map[string]interface{}
+	}{
+		{ChannelSlack, map[string]interface{}{"webhook_url": srv.URL}},
+		{ChannelTeams, map[string]interface{}{"webhook_url": srv.URL}},
+		{ChannelWebhook, map[string]interface{}{"url": srv.URL}},
+	} {
+		t.Run(tt.channelType, func(t *testing.T) {
+			err := notifyThrough(t, srv, tt.channelType, tt.config, testMessage)
+			if err == nil || !strings.Contains(err.Error(), "returned 500: stand-in says hi") {
+				t.Errorf("err = %v, want the 500 response", err)
+			}
+		})
+	}
+}
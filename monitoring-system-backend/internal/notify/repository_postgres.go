@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/badgerv/monitoring-api/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+type PostgresRepository struct {
+	db *storage.DB
+}
+
+func NewPostgresRepository(db *storage.DB) Repository {
+	return &PostgresRepository{db: db}
+}
+
+const channelColumns = "id, name, type, config, enabled, created_by, created_at, updated_at"
+
+func scanChannel(row pgx.Row, ch *ChannelConfig) error {
+	return row.Scan(&ch.ID, &ch.Name, &ch.Type, &ch.Config, &ch.Enabled, &ch.CreatedBy, &ch.CreatedAt, &ch.UpdatedAt)
+}
+
+func (r *PostgresRepository) CreateChannel(ctx context.Context, ch *ChannelConfig) (*ChannelConfig, error) {
+	var created ChannelConfig
+	err := scanChannel(r.db.Pool.QueryRow(ctx, `
+		INSERT INTO notification_channels (name, type, config, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+channelColumns,
+		ch.Name, ch.Type, ch.Config, ch.Enabled, ch.CreatedBy), &created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *PostgresRepository) UpdateChannel(ctx context.Context, ch *ChannelConfig) (*ChannelConfig, error) {
+	var updated ChannelConfig
+	err := scanChannel(r.db.Pool.QueryRow(ctx, `
+		UPDATE notification_channels
+		SET name = $2, type = $3, config = $4, enabled = $5, updated_at = now()
+		WHERE id = $1
+		RETURNING `+channelColumns,
+		ch.ID, ch.Name, ch.Type, ch.Config, ch.Enabled), &updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update channel: %w", err)
+	}
+	return &updated, nil
+}
+
+// DeleteChannel removes a channel; its routes go with it
+func (r *PostgresRepository) DeleteChannel(ctx context.Context, id int) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM notification_channels WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete channel: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetChannel(ctx context.Context, id int) (*ChannelConfig, error) {
+	var ch ChannelConfig
+	err := scanChannel(r.db.Pool.QueryRow(ctx, `SELECT `+channelColumns+` FROM notification_channels WHERE id = $1`, id), &ch)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel %v: %w", id, err)
+	}
+	return &ch, nil
+}
+
+func (r *PostgresRepository) ListChannels(ctx context.Context) ([]ChannelConfig, error) {
+	return r.queryChannels(ctx, `SELECT `+channelColumns+` FROM notification_channels ORDER BY name`)
+}
+
+func (r *PostgresRepository) CreateRoute(ctx context.Context, route *Route) (*Route, error) {
+	var created Route
+	err := r.db.Pool.QueryRow(ctx, `
+		INSERT INTO notification_routes (channel_id, scope_type, scope_value, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, channel_id, scope_type, scope_value, events, created_at`,
+		route.ChannelID, route.ScopeType, route.ScopeValue, route.Events,
+	).Scan(&created.ID, &created.ChannelID, &created.ScopeType, &created.ScopeValue, &created.Events, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create route: %w", err)
+	}
+	return &created, nil
+}
+
+func (r *PostgresRepository) DeleteRoute(ctx context.Context, id int) error {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM notification_routes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete route: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("route %v not found", id)
+	}
+	return nil
+}
+
+// ListRoutes returns the routes of one channel, or of all channels when channelID is 0
+func (r *PostgresRepository) ListRoutes(ctx context.Context, channelID int) ([]Route, error) {
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT id, channel_id, scope_type, scope_value, events, created_at
+		FROM notification_routes
+		WHERE $1 = 0 OR channel_id = $1
+		ORDER BY id`, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []Route{}
+	for rows.Next() {
+		var rt Route
+		if err := rows.Scan(&rt.ID, &rt.ChannelID, &rt.ScopeType, &rt.ScopeValue, &rt.Events, &rt.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		routes = append(routes, rt)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return routes, nil
+}
+
+// MatchChannels returns each enabled channel at most once, however many of its routes match
+func (r *PostgresRepository) MatchChannels(ctx context.Context, target Target, event string) ([]ChannelConfig, error) {
+	endpointIDs := []string{}
+	for _, id := range append([]int{target.EndpointID}, target.EndpointIDs...) {
+		if id != 0 {
+			endpointIDs = append(endpointIDs, strconv.Itoa(id))
+		}
+	}
+	tags := target.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return r.queryChannels(ctx, `
+		SELECT `+channelColumns+`
+		FROM notification_channels c
+		WHERE c.enabled AND EXISTS (
+			SELECT 1 FROM notification_routes rt
+			WHERE rt.channel_id = c.id
+				AND (cardinality(rt.events) = 0 OR $1 = ANY(rt.events))
+				AND (
+					(rt.scope_type = 'endpoint' AND rt.scope_value = ANY($2::text[]))
+					OR (rt.scope_type = 'tag' AND rt.scope_value = ANY($3::text[]))
+					OR (rt.scope_type = 'server' AND $6 <> '' AND rt.scope_value = $6)
+					OR (rt.scope_type = 'pipeline' AND $4 AND (rt.scope_value = '' OR rt.scope_value = $5))
+				)
+		)
+		ORDER BY c.id`,
+		event, endpointIDs, tags, target.Pipeline, target.MacroService, target.ServerName)
+}
+
+func (r *PostgresRepository) queryChannels(ctx context.Context, query string, args ...interface{}) ([]ChannelConfig, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []ChannelConfig{}
+	for rows.Next() {
+		var ch ChannelConfig
+		if err := scanChannel(rows, &ch); err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		channels = append(channels, ch)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", rows.Err())
+	}
+	return channels, nil
+}
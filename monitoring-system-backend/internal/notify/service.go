@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultSendTimeout bounds each delivery so one dead webhook can't stall the rest.
+const defaultSendTimeout = 10 * time.Second
+
+// secretMask replaces webhook secrets, webhook URLs and header values in API responses.
+// Saving a config with the mask keeps the stored value.
+const secretMask = "********"
+
+// Service resolves which channels an event is routed to and delivers it to each.
+type Service struct {
+	repo   Repository
+	email  EmailSender
+	client *http.Client
+}
+
+func NewService(repo Repository, email EmailSender) *Service {
+	return &Service{
+		repo:   repo,
+		email:  email,
+		client: &http.Client{Timeout: defaultSendTimeout},
+	}
+}
+
+// SetHTTPClient replaces the client used for Slack, Teams and webhook deliveries.
+func (s *Service) SetHTTPClient(client *http.Client) {
+	s.client = client
+}
+
+// EmailTo returns an ad-hoc email channel, for recipients that come from the event
+// itself (subscribers, the pipeline requester) rather than from a stored route.
+func (s *Service) EmailTo(recipients ...string) Channel {
+	return NewEmailChannel(s.email, recipients)
+}
+
+// Notify sends msg to every channel routed to the target plus any extra channels.
+// Every channel is attempted; failures are logged and returned together.
+func (s *Service) Notify(ctx context.Context, target Target, msg Message, extra ...Channel) error {
+	channels := append([]Channel{}, extra...)
+
+	if s.repo != nil {
+		configs, err := s.repo.MatchChannels(ctx, target, msg.Event)
+		if err != nil {
+			log.Printf("Failed to load notification routes for %s: %v", msg.Event, err)
+		}
+		for _, cfg := range configs {
+			ch, err := NewChannel(cfg, s.email, s.client)
+			if err != nil {
+				log.Printf("Skipping notification channel %d (%s): %v", cfg.ID, cfg.Name, err)
+				continue
+			}
+			channels = append(channels, ch)
+		}
+	}
+
+	var errs []error
+	for _, ch := range channels {
+		sendCtx, cancel := context.WithTimeout(ctx, defaultSendTimeout)
+		err := ch.Send(sendCtx, msg)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to send %s notification via %s: %v", msg.Event, ch.Type(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Type(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateChannel checks the name and that the config builds a working channel
+func (s *Service) ValidateChannel(cfg ChannelConfig) error {
+	if strings.TrimSpace(cfg.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	_, err := NewChannel(cfg, s.email, s.client)
+	return err
+}
+
+func (s *Service) CreateChannel(ctx context.Context, cfg ChannelConfig) (*ChannelConfig, error) {
+	if err := s.ValidateChannel(cfg); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateChannel(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	return redact(created), nil
+}
+
+func (s *Service) UpdateChannel(ctx context.Context, cfg ChannelConfig) (*ChannelConfig, error) {
+	existing, err := s.repo.GetChannel(ctx, cfg.ID)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Config = keepSecrets(cfg.Config, existing.Config)
+
+	if err := s.ValidateChannel(cfg); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateChannel(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	return redact(updated), nil
+}
+
+func (s *Service) DeleteChannel(ctx context.Context, id int) error {
+	return s.repo.DeleteChannel(ctx, id)
+}
+
+func (s *Service) GetChannel(ctx context.Context, id int) (*ChannelConfig, error) {
+	ch, err := s.repo.GetChannel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return redact(ch), nil
+}
+
+func (s *Service) ListChannels(ctx context.Context) ([]ChannelConfig, error) {
+	channels, err := s.repo.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range channels {
+		channels[i] = *redact(&channels[i])
+	}
+	return channels, nil
+}
+
+// TestChannel sends a test message through a stored channel, enabled or not
+func (s *Service) TestChannel(ctx context.Context, id int) error {
+	cfg, err := s.repo.GetChannel(ctx, id)
+	if err != nil {
+		return err
+	}
+	ch, err := NewChannel(*cfg, s.email, s.client)
+	if err != nil {
+		return err
+	}
+	return ch.Send(ctx, Message{
+		Event:    EventTest,
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("Test notification from DevOptic (%s)", cfg.Name),
+		Text:     "If you can read this, the channel is configured correctly.",
+	})
+}
+
+// ValidateRoute checks the scope of a route
+func ValidateRoute(route Route) error {
+	switch route.ScopeType {
+	case ScopeEndpoint, ScopeTag, ScopeServer:
+		if strings.TrimSpace(route.ScopeValue) == "" {
+			return fmt.Errorf("scope_value is required for %s routes", route.ScopeType)
+		}
+	case ScopePipeline:
+	default:
+		return fmt.Errorf("scope_type must be endpoint, tag, server or pipeline")
+	}
+	return nil
+}
+
+// CreateRoute attaches a channel to an endpoint, tag, server or pipeline scope
+func (s *Service) CreateRoute(ctx context.Context, route Route) (*Route, error) {
+	if err := ValidateRoute(route); err != nil {
+		return nil, err
+	}
+	if route.Events == nil {
+		route.Events = []string{}
+	}
+	if _, err := s.repo.GetChannel(ctx, route.ChannelID); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateRoute(ctx, &route)
+}
+
+func (s *Service) DeleteRoute(ctx context.Context, id int) error {
+	return s.repo.DeleteRoute(ctx, id)
+}
+
+func (s *Service) ListRoutes(ctx context.Context, channelID int) ([]Route, error) {
+	return s.repo.ListRoutes(ctx, channelID)
+}
+
+// redact hides what grants access to a channel before its config leaves the service:
+// the webhook secret and custom header values, and the Slack or Teams webhook URL,
+// which is a credential in itself. Only the URL's scheme and host are kept.
+func redact(ch *ChannelConfig) *ChannelConfig {
+	var settings map[string]interface{}
+	if err := json.Unmarshal(ch.Config, &settings); err != nil {
+		return ch
+	}
+	if secret, ok := settings["secret"].(string); ok && secret != "" {
+		settings["secret"] = secretMask
+	}
+	if webhookURL, ok := settings["webhook_url"].(string); ok && webhookURL != "" {
+		settings["webhook_url"] = maskURL(webhookURL)
+	}
+	if headers, ok := settings["headers"].(map[string]interface{}); ok {
+		for name := range headers {
+			headers[name] = secretMask
+		}
+	}
+	if raw, err := json.Marshal(settings); err == nil {
+		ch.Config = raw
+	}
+	return ch
+}
+
+// maskURL replaces everything after the host with the mask
+func maskURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return secretMask
+	}
+	return u.Scheme + "://" + u.Host + "/" + secretMask
+}
+
+// keepSecrets restores the stored secret, webhook URL and header values an update sends
+// back masked
+func keepSecrets(updated, stored json.RawMessage) json.RawMessage {
+	var next, prev map[string]interface{}
+	if json.Unmarshal(updated, &next) != nil || json.Unmarshal(stored, &prev) != nil {
+		return updated
+	}
+	if next["secret"] == secretMask {
+		next["secret"] = prev["secret"]
+	}
+	if webhookURL, ok := prev["webhook_url"].(string); ok && next["webhook_url"] == maskURL(webhookURL) {
+		next["webhook_url"] = webhookURL
+	}
+	if headers, ok := next["headers"].(map[string]interface{}); ok {
+		prevHeaders, _ := prev["headers"].(map[string]interface{})
+		for name, value := range headers {
+			if prevValue, ok := prevHeaders[name]; ok && value == secretMask {
+				headers[name] = prevValue
+			}
+		}
+	}
+	raw, err := json.Marshal(next)
+	if err != nil {
+		return updated
+	}
+	return raw
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRedactAndKeepSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   string
+		redacted string
+	}{
+		{
+			name:     "slack",
+			stored:   `{"webhook_url":"https://hooks.slack.com/services/T0/B0/xyz"}`,
+			redacted: `{"webhook_url":"https://hooks.slack.com/********"}`,
+		},
+		{
+			name:     "webhook",
+			stored:   `{"url":"https://example.com/hook","secret":"s3cret","headers":{"Authorization":"Bearer abc"}}`,
+			redacted: `{"url":"https://example.com/hook","secret":"********","headers":{"Authorization":"********"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := redact(&ChannelConfig{Config: json.RawMessage(tt.stored)})
+			assertSameJSON(t, "redact", ch.Config, tt.redacted)
+
+			// Sending the redacted config back keeps every stored value
+			assertSameJSON(t, "keepSecrets", keepSecrets(ch.Config, json.RawMessage(tt.stored)), tt.stored)
+		})
+	}
+}
+
+func TestKeepSecretsTakesNewValues(t *testing.T) {
+	stored := `{"url":"https://example.com/hook","secret":"s3cret","headers":{"Authorization":"Bearer abc"}}`
+	updated := `{"url":"https://example.com/hook","secret":"n3w","headers":{"Authorization":"********","X-Team":"********"}}`
+
+	// A mask for a header that wasn't stored before has nothing to restore
+	want := `{"url":"https://example.com/hook","secret":"n3w","headers":{"Authorization":"Bearer abc","X-Team":"********"}}`
+	assertSameJSON(t, "keepSecrets", keepSecrets(json.RawMessage(updated), json.RawMessage(stored)), want)
+}
+
+func assertSameJSON(t *testing.T, what string, got json.RawMessage, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("%s returned invalid JSON %s: %v", what, got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s = %s, want %s", what, got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Channel types
+const (
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+	ChannelWebhook = "webhook"
+)
+
+// Route scopes. A route sends events for one endpoint, every endpoint with a tag, every
+// endpoint on a server, or the pipelines of one macro service (an empty scope_value
+// matches all pipelines).
+const (
+	ScopeEndpoint = "endpoint"
+	ScopeTag      = "tag"
+	ScopeServer   = "server"
+	ScopePipeline = "pipeline"
+)
+
+// Events published through channels
+const (
+	EventEndpointDown      = "monitor.down"
+	EventEndpointRecovered = "monitor.recovered"
+	EventCertificateExpiry = "monitor.certificate"
+	EventSLOBurn           = "monitor.slo"
+	EventServerDown        = "monitor.server_down"
+	EventServerRecovered   = "monitor.server_recovered"
+	EventPipelineTriggered = "pipeline.triggered"
+	EventPipelineApproved  = "pipeline.approved"
+	EventPipelineRejected  = "pipeline.rejected"
+	EventPipelineCompleted = "pipeline.completed"
+	EventPipelineFailed    = "pipeline.failed"
+	EventTest              = "notify.test"
+)
+
+// Severities, used for colouring chat messages
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+	SeverityResolved = "resolved"
+)
+
+var ErrChannelNotFound = errors.New("notification channel not found")
+
+// Field is a labelled value shown alongside the message text.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Message is a channel-agnostic notification. Email uses HTML when set and falls back
+// to Text; chat channels use Title, Text and Fields; the webhook sends all of it plus Data.
+type Message struct {
+	Event    string      `json:"event"`
+	Severity string      `json:"severity"`
+	Title    string      `json:"title"`
+	Text     string      `json:"text"`
+	HTML     string      `json:"-"`
+	Fields   []Field     `json:"fields,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// Channel delivers a message to one destination.
+type Channel interface {
+	Type() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// ChannelConfig is the stored configuration of a channel. Config holds the
+// type-specific settings, e.g. {"webhook_url": "..."} for Slack and Teams,
+// {"url": "...", "secret": "...", "headers": {...}} for webhooks and
+// {"recipients": ["..."]} for email.
+type ChannelConfig struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Config    json.RawMessage `json:"config"`
+	Enabled   bool            `json:"enabled"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Route attaches a channel to a scope. Events limits which events are sent; empty
+// means all of them.
+type Route struct {
+	ID         int       `json:"id"`
+	ChannelID  int       `json:"channel_id"`
+	ScopeType  string    `json:"scope_type"`
+	ScopeValue string    `json:"scope_value"`
+	Events     []string  `json:"events"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Target describes what an event is about, so routes can be matched against it.
+type Target struct {
+	EndpointID   int
+	EndpointIDs  []int // further endpoints an event covers, e.g. every endpoint of a down server
+	Tags         []string
+	ServerName   string
+	MacroService string
+	Pipeline     bool
+}
+
+// Repository stores channels and their routes.
+type Repository interface {
+	CreateChannel(ctx context.Context, ch *ChannelConfig) (*ChannelConfig, error)
+	UpdateChannel(ctx context.Context, ch *ChannelConfig) (*ChannelConfig, error)
+	DeleteChannel(ctx context.Context, id int) error
+	GetChannel(ctx context.Context, id int) (*ChannelConfig, error)
+	ListChannels(ctx context.Context) ([]ChannelConfig, error)
+
+	CreateRoute(ctx context.Context, route *Route) (*Route, error)
+	DeleteRoute(ctx context.Context, id int) error
+	ListRoutes(ctx context.Context, channelID int) ([]Route, error)
+
+	// MatchChannels returns the enabled channels routed to the target for an event
+	MatchChannels(ctx context.Context, target Target, event string) ([]ChannelConfig, error)
+}
@@ -173,4 +173,15 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, entityID s
 func (h *Hub) Broadcast(message Message) {
 	fmt.Printf("[BROADCAST] Queuing message for broadcast - entity_id: %s, type: %s\n", message.ID, message.Type)
 	h.broadcast <- message
-}
\ No newline at end of file
+}
+// ClientCount returns the number of connected clients across all entity IDs.
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	count := 0
+	for _, clients := range h.clients {
+		count += len(clients)
+	}
+	return count
+}